@@ -0,0 +1,291 @@
+package main
+
+import (
+	"backend/internal/app"
+	"backend/internal/contract"
+	"backend/internal/entity"
+	"backend/internal/indexer"
+	"backend/internal/reconciler"
+	"backend/internal/relayer"
+	"backend/internal/repo/mongodb"
+	redisrepo "backend/internal/repo/redis"
+	"backend/internal/usecase"
+	"backend/internal/usecase/service"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/redis/go-redis/v9"
+)
+
+// leaderKey ключ блокировки в Consul, которую удерживает реплика-лидер
+const leaderKey = "donly/indexer/leader"
+
+func main() {
+	backfill := flag.Bool("backfill", false, "заново загрузить состояние желаний и историю из блокчейна начиная с блока деплоя контракта и завершиться")
+	flag.Parse()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	log.Println("🚀 Запуск Donly Indexer...")
+
+	// Инициализация Consul
+	consulClient, err := app.InitConsul()
+	if err != nil {
+		log.Fatalf("❌ Ошибка подключения к Consul: %v", err)
+	}
+	log.Println("✅ Consul подключен")
+
+	// Инициализация Vault
+	vaultClient, err := app.InitVault()
+	if err != nil {
+		log.Fatalf("❌ Ошибка подключения к Vault: %v", err)
+	}
+	log.Println("✅ Vault подключен")
+
+	// Загрузка конфигурации из Vault
+	config, err := app.LoadConfig(vaultClient)
+	if err != nil {
+		log.Fatalf("❌ Ошибка загрузки конфигурации: %v", err)
+	}
+	log.Println("✅ Конфигурация загружена из Vault")
+
+	// Подключение к MongoDB
+	mongoClient, err := app.InitMongoDB(ctx, config.MongoURL)
+	if err != nil {
+		log.Fatalf("❌ Ошибка подключения к MongoDB: %v", err)
+	}
+	defer func() {
+		if err := mongoClient.Disconnect(context.Background()); err != nil {
+			log.Printf("⚠️ Ошибка отключения от MongoDB: %v", err)
+		}
+	}()
+	log.Println("✅ MongoDB подключена")
+
+	// Подключение к блокчейну
+	polygonClient, registry, err := app.InitPolygon(config)
+	if err != nil {
+		log.Fatalf("❌ Ошибка подключения к Polygon: %v", err)
+	}
+	log.Printf("✅ Polygon подключен к сети Chain UUID: %d", config.ChainID)
+	log.Printf("📋 Действующий контракт: %s, деплоев в реестре: %d", registry.Current().Address.Hex(), len(registry.Deployments()))
+
+	// Инициализация репозиториев
+	db := mongoClient.Database(config.MongoDatabase)
+	if err := mongodb.Migrate(ctx, db); err != nil {
+		log.Fatalf("❌ Ошибка миграции MongoDB: %v", err)
+	}
+
+	userRepo := mongodb.NewUserRepository(db)
+	wishRepo := mongodb.NewWishRepository(db)
+	historyRepo := mongodb.NewHistoryRepository(db)
+	blockchainRepo := mongodb.NewBlockchainRepository(db)
+	relayerRepo := mongodb.NewRelayerRepository(db)
+	reconciliationRepo := mongodb.NewReconciliationRepository(db)
+
+	// --- Redis для Donation Events ---
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     config.RedisAddr,
+		Password: config.RedisPassword,
+		DB:       0,
+	})
+	donationEventRepo := redisrepo.NewDonationEventRepo(redisClient, "donation_events")
+
+	log.Println("✅ Репозитории инициализированы")
+
+	// Инициализация индексатора и регистрация обработчиков событий
+	ix := indexer.New(polygonClient, registry.Deployments(), blockchainRepo, indexer.Config{
+		PollInterval:  config.PollInterval,
+		Confirmations: config.ConfirmationDepth,
+		// По WebSocket получаем логи по подписке, по HTTP остаётся только опрос
+		Subscribe: hasWebSocketURL(config.PolygonRPCURLs),
+	})
+	users := indexer.NewUserResolver(userRepo, blockchainRepo)
+	ix.OnStart(users.IndexUUIDHashes)
+	// Очередь релеера, в которую индексатор ставит вызовы от имени стримера
+	var relayerService *service.RelayerService
+	if config.RelayerEnabled {
+		relayerService = service.NewRelayerService(relayerRepo, wishRepo, registry.Current().Address.Hex(), true, config.RelayerMaxWishes)
+	}
+
+	wishHandler := indexer.NewWishHandler(wishRepo, users)
+	if config.RelayerUUID != "" {
+		wishHandler.TrustRelayer(config.RelayerUUID)
+	}
+	// Желание, удалённое стримером, пока его addWish был в пути, удаляется из контракта релеером.
+	// Без релеера событие остаётся только в аномалиях
+	if relayerService != nil {
+		wishHandler.OnDeletedAdded(func(ctx context.Context, wish *entity.Wish) error {
+			err := relayerService.RemoveDeletedWish(ctx, wish)
+			switch {
+			case err == nil:
+				log.Printf("📨 Удаление из контракта удалённого желания %s поставлено в очередь релеера", wish.UUID)
+			case errors.Is(err, usecase.ErrRelayAlreadyQueued), errors.Is(err, usecase.ErrWishOnRetiredContract):
+				log.Printf("⚠️ Удаление из контракта желания %s пропущено: %v", wish.UUID, err)
+			default:
+				return err
+			}
+			return nil
+		})
+	}
+	wishHandler.Register(ix)
+	paymentHandler := indexer.NewPaymentHandler(wishRepo, historyRepo, donationEventRepo, users)
+	// Набравшее цель желание завершается в контракте вызовом релеера, как если бы стример завершил его сам
+	if config.AutoCompleteFunded && relayerService != nil {
+		paymentHandler.OnFunded(func(ctx context.Context, wish *entity.Wish) error {
+			_, err := relayerService.RelayWish(ctx, entity.RelayWishRequest{
+				WishUUID: wish.UUID,
+				UserUUID: wish.StreamerUUID,
+				Action:   entity.RelayActionComplete,
+			})
+			switch {
+			case err == nil:
+				log.Printf("📨 Завершение желания %s поставлено в очередь релеера", wish.UUID)
+			case errors.Is(err, usecase.ErrRelayAlreadyQueued), errors.Is(err, usecase.ErrWishStatusForAction),
+				errors.Is(err, usecase.ErrWishOnRetiredContract):
+				log.Printf("⚠️ Автозавершение желания %s пропущено: %v", wish.UUID, err)
+			default:
+				return err
+			}
+			return nil
+		})
+		log.Println("✅ Автозавершение набравших цель желаний включено")
+	} else if config.AutoCompleteFunded {
+		log.Println("⚠️ Автозавершение набравших цель желаний требует включённого релеера и отключено")
+	}
+	paymentHandler.Register(ix)
+	// Регистрацию стримера подтверждаем чтением его записи по адресу кошелька в контракте, выпустившем событие
+	registrationHandler := indexer.NewRegistrationHandler(userRepo, users, func(ctx context.Context, contractAddr, addr common.Address) (*contract.OnchainUser, error) {
+		deployment, ok := registry.Get(contractAddr)
+		if !ok {
+			return nil, fmt.Errorf("контракт %s отсутствует в реестре", contractAddr.Hex())
+		}
+		return deployment.Donates().ReadUser(ctx, polygonClient, addr, nil)
+	})
+	if config.RelayerUUID != "" {
+		registrationHandler.TrustRelayer(config.RelayerUUID)
+	}
+	registrationHandler.Register(ix)
+
+	// Релеер и сверка работают только с действующим контрактом
+	donates := registry.Current().Donates()
+
+	// Релеер подписывает вызовы контракта ключом бэкенда, если он включён в конфигурации
+	var rl *relayer.Relayer
+	if config.RelayerEnabled {
+		rl, err = relayer.New(polygonClient, config.PrivateKey, donates, relayerRepo, wishRepo, userRepo, relayer.Config{
+			UUID: config.RelayerUUID,
+		})
+		if err != nil {
+			log.Fatalf("❌ Ошибка инициализации релеера: %v", err)
+		}
+		log.Printf("✅ Релеер включён, адрес %s", rl.Address().Hex())
+	}
+
+	// Сверка с контрактом находит расхождения, оставленные пропущенными логами
+	var rc *reconciler.Reconciler
+	if config.ReconcileInterval > 0 {
+		rc = reconciler.New(polygonClient, donates, userRepo, wishRepo, historyRepo, blockchainRepo, reconciliationRepo, reconciler.Config{
+			Interval:   config.ReconcileInterval,
+			AutoRepair: config.ReconcileAutoRepair,
+			// Итоги истории включают платежи выведенных из работы деплоев
+			SkipBalance: len(registry.Deployments()) > 1,
+		})
+		log.Printf("✅ Сверка с контрактом включена, интервал %s", config.ReconcileInterval)
+	}
+
+	// Опрашивать логи может только одна реплика, остальные ждут блокировку в Consul
+	leader := indexer.NewLeader(consulClient, leaderKey)
+
+	log.Println("✅ Индексатор инициализирован")
+
+	// Историческая загрузка выполняется под той же блокировкой, поэтому мониторинг на других репликах в это время не работает
+	if *backfill {
+		log.Printf("⏳ Историческая загрузка %d деплоев контракта", len(registry.Deployments()))
+		err := leader.Do(ctx, func(ctx context.Context) error {
+			return ix.Backfill(ctx)
+		})
+		if err != nil {
+			log.Fatalf("❌ Ошибка исторической загрузки: %v", err)
+		}
+		log.Println("✅ Историческая загрузка завершена")
+		return
+	}
+
+	// Запуск мониторинга блокчейна на реплике-лидере
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := leader.Run(ctx, func(ctx context.Context) error {
+			return runLeader(ctx, ix, rl, rc)
+		}); err != nil {
+			log.Printf("⚠️ Ошибка выбора лидера: %v", err)
+		}
+	}()
+	log.Println("🔍 Выбор лидера для мониторинга блокчейна запущен")
+
+	// Graceful shutdown
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+
+	log.Println("🛑 Остановка индексатора...")
+
+	cancel()
+	<-done
+
+	polygonClient.Close()
+
+	log.Println("👋 Индексатор остановлен")
+}
+
+// hasWebSocketURL сообщает, есть ли среди RPC эндпоинтов поддерживающие подписки
+func hasWebSocketURL(urls []string) bool {
+	for _, url := range urls {
+		if strings.HasPrefix(url, "ws://") || strings.HasPrefix(url, "wss://") {
+			return true
+		}
+	}
+	return false
+}
+
+// runLeader работает на реплике-лидере: индексатор и, если они включены, релеер и сверка с контрактом
+func runLeader(ctx context.Context, ix *indexer.Indexer, rl *relayer.Relayer, rc *reconciler.Reconciler) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	if rl != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := rl.Run(ctx); err != nil {
+				log.Printf("⚠️ Ошибка релеера: %v", err)
+			}
+		}()
+	}
+
+	if rc != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := rc.Run(ctx); err != nil {
+				log.Printf("⚠️ Ошибка сверки с контрактом: %v", err)
+			}
+		}()
+	}
+
+	err := ix.Run(ctx)
+	cancel()
+	wg.Wait()
+	return err
+}
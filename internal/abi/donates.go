@@ -8,3 +8,18 @@ import (
 //
 //go:embed Donates.abi
 var DonatesABI string
+
+// DonatesBin байткод деплоя контракта той же версии, что и DonatesABI (используется в тестах на локальной цепочке)
+//
+//go:embed Donates.bin
+var DonatesBin string
+
+// LatestVersion версия ABI, с которой деплоится контракт сейчас
+const LatestVersion = "v1"
+
+// Versions ABI задеплоенных версий контракта по версии из реестра деплоев.
+// При изменении интерфейса контракта новое ABI добавляется сюда под новой версией, старые остаются
+// для чтения логов прежних деплоев
+var Versions = map[string]string{
+	"v1": DonatesABI,
+}
@@ -0,0 +1,160 @@
+package app
+
+import (
+	"backend/internal/contract"
+	"backend/pkg/ethrpc"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// InitConsul инициализирует подключение к Consul
+func InitConsul() (*consulapi.Client, error) {
+	config := consulapi.DefaultConfig()
+
+	// Для Docker Compose
+	if consulAddr := os.Getenv("CONSUL_ADDR"); consulAddr != "" {
+		config.Address = consulAddr
+	} else {
+		config.Address = "localhost:8500" // по умолчанию
+	}
+
+	return consulapi.NewClient(config)
+}
+
+// InitVault инициализирует подключение к Vault
+func InitVault() (*vaultapi.Client, error) {
+	config := vaultapi.DefaultConfig()
+
+	// Для Docker Compose
+	if vaultAddr := os.Getenv("VAULT_ADDR"); vaultAddr != "" {
+		config.Address = vaultAddr
+	} else {
+		config.Address = "http://localhost:8200" // по умолчанию
+	}
+
+	client, err := vaultapi.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Токен для разработки
+	if token := os.Getenv("VAULT_TOKEN"); token != "" {
+		client.SetToken(token)
+	} else {
+		client.SetToken("myroot") // токен из docker-compose
+	}
+
+	return client, nil
+}
+
+// InitMongoDB инициализирует подключение к MongoDB
+func InitMongoDB(ctx context.Context, mongoURL string) (*mongo.Client, error) {
+	clientOptions := options.Client().ApplyURI(mongoURL)
+
+	client, err := mongo.Connect(ctx, clientOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	// Проверка подключения
+	if err := client.Ping(ctx, nil); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// InitMinIO инициализирует подключение к MinIO
+func InitMinIO(config *Config) (*minio.Client, error) {
+	client, err := minio.New(config.MinIOEndpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(config.MinIOAccessKey, config.MinIOSecretKey, ""),
+		Secure: config.MinIOUseSSL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Создаем bucket если не существует
+	ctx := context.Background()
+	exists, err := client.BucketExists(ctx, config.MinIOBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		err = client.MakeBucket(ctx, config.MinIOBucket, minio.MakeBucketOptions{})
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("✅ Bucket '%s' создан", config.MinIOBucket)
+	}
+
+	return client, nil
+}
+
+// InitPolygon инициализирует подключение к Polygon блокчейну
+func InitPolygon(config *Config) (ethrpc.Client, *contract.Registry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Подключение к Polygon RPC: запросы идут в первый здоровый эндпоинт из списка
+	client, err := ethrpc.NewPool(ctx, config.PolygonRPCURLs, ethrpc.Options{
+		RequestsPerSecond: config.RPCRateLimit,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка подключения к Polygon RPC: %w", err)
+	}
+
+	// Проверка подключения
+
+	_, err = client.ChainID(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка получения Chain UUID: %w", err)
+	}
+
+	/*
+		if chainID.Int64() != config.ChainID {
+			return nil, nil, fmt.Errorf("неожиданный Chain UUID: получен %d, ожидался %d", chainID.Int64(), config.ChainID)
+		}
+	*/
+
+	// Реестр деплоев контракта с ABI их версий
+	registry, err := contract.NewRegistry(config.ContractDeployments)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка загрузки реестра контрактов: %w", err)
+	}
+
+	if len(registry.Deployments()) == 0 {
+		log.Println("⚠️ Адрес контракта не указан. Мониторинг блокчейна будет недоступен")
+		return client, registry, nil
+	}
+
+	// Проверка существования контрактов
+	for _, deployment := range registry.Deployments() {
+		code, err := client.CodeAt(ctx, deployment.Address, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("ошибка проверки контракта: %w", err)
+		}
+		switch {
+		case len(code) == 0:
+			log.Printf("⚠️ Контракт по адресу %s не найден или не задеплоен", deployment.Address.Hex())
+		case deployment.Retired():
+			log.Printf("✅ Контракт найден по адресу %s (ABI %s, выведен из работы, блоки %d-%d)",
+				deployment.Address.Hex(), deployment.ABIVersion, deployment.StartBlock, deployment.EndBlock)
+		default:
+			log.Printf("✅ Контракт найден по адресу %s (ABI %s, с блока %d)", deployment.Address.Hex(), deployment.ABIVersion, deployment.StartBlock)
+		}
+	}
+
+	return client, registry, nil
+}
@@ -0,0 +1,273 @@
+package contract
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Donates кодирует вызовы смарт-контракта Donates по загруженному ABI.
+// Структуры-аргументы собираются по именам полей из ABI, поэтому кодирование работает
+// и с ABI, где желание идентифицируется числовым id, и с ABI, где используется wishUUID
+type Donates struct {
+	abi     abi.ABI
+	address common.Address
+}
+
+func NewDonates(contractABI abi.ABI, address common.Address) *Donates {
+	return &Donates{
+		abi:     contractABI,
+		address: address,
+	}
+}
+
+// Address возвращает адрес контракта
+func (d *Donates) Address() common.Address {
+	return d.address
+}
+
+// WishArgs данные желания для вызова addWish
+type WishArgs struct {
+	UserUUID    string
+	WishUUID    string
+	OnchainID   uint64
+	Price       *big.Int
+	Name        string
+	Link        string
+	Description string
+}
+
+// PackAddWish кодирует вызов addWish
+func (d *Donates) PackAddWish(args WishArgs) ([]byte, error) {
+	method, ok := d.abi.Methods["addWish"]
+	if !ok || len(method.Inputs) != 1 {
+		return nil, fmt.Errorf("метод addWish не найден в ABI")
+	}
+	wish, err := buildTuple(method.Inputs[0].Type, map[string]interface{}{
+		"userUUID":       args.UserUUID,
+		"wishUUID":       args.WishUUID,
+		"id":             new(big.Int).SetUint64(args.OnchainID),
+		"currentBalance": big.NewInt(0),
+		"price":          args.Price,
+		"name":           args.Name,
+		"link":           args.Link,
+		"description":    args.Description,
+		"completed":      false,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сборки аргументов addWish: %w", err)
+	}
+	return d.abi.Pack("addWish", wish)
+}
+
+// PackCompleteOrRemoveWish кодирует вызов completeOrRemoveWish для желания пользователя userAddr
+func (d *Donates) PackCompleteOrRemoveWish(userAddr common.Address, wishUUID string, onchainID uint64, remove bool) ([]byte, error) {
+	method, ok := d.abi.Methods["completeOrRemoveWish"]
+	if !ok || len(method.Inputs) != 3 {
+		return nil, fmt.Errorf("метод completeOrRemoveWish не найден в ABI")
+	}
+	var wishRef interface{} = new(big.Int).SetUint64(onchainID)
+	if method.Inputs[1].Type.T == abi.StringTy {
+		wishRef = wishUUID
+	}
+	return d.abi.Pack("completeOrRemoveWish", userAddr, wishRef, remove)
+}
+
+// PackRegisterUser кодирует вызов registerUser
+func (d *Donates) PackRegisterUser(name, uuid string, topics []string) ([]byte, error) {
+	if topics == nil {
+		topics = []string{}
+	}
+	return d.abi.Pack("registerUser", name, uuid, topics)
+}
+
+// DonateArgs данные доната для вызова donate
+type DonateArgs struct {
+	PaymentUUID string
+	UserName    string
+	MessageText string
+	FromUUID    string
+	ToUUID      string
+	WishID      uint64
+	ToAddress   common.Address
+}
+
+// PackDonate кодирует вызов donate. Сумма доната передаётся в value транзакции
+func (d *Donates) PackDonate(args DonateArgs) ([]byte, error) {
+	method, ok := d.abi.Methods["donate"]
+	if !ok || len(method.Inputs) != 3 {
+		return nil, fmt.Errorf("метод donate не найден в ABI")
+	}
+	userData, err := buildTuple(method.Inputs[1].Type, map[string]interface{}{
+		"userName":    args.UserName,
+		"messageText": args.MessageText,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сборки аргументов donate: %w", err)
+	}
+	// Дату и тип платежа контракт выставляет сам
+	info, err := buildTuple(method.Inputs[2].Type, map[string]interface{}{
+		"date":        big.NewInt(0),
+		"fromUUID":    args.FromUUID,
+		"toUUID":      args.ToUUID,
+		"wishId":      new(big.Int).SetUint64(args.WishID),
+		"toAddress":   args.ToAddress,
+		"paymentType": uint8(0),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сборки аргументов donate: %w", err)
+	}
+	return d.abi.Pack("donate", args.PaymentUUID, userData, info)
+}
+
+// PackWithdraw кодирует вызов withdraw
+func (d *Donates) PackWithdraw(paymentUUID, userUUID string, amount *big.Int) ([]byte, error) {
+	return d.abi.Pack("withdraw", paymentUUID, userUUID, amount)
+}
+
+// OnchainUser данные пользователя из публичного маппинга users контракта
+type OnchainUser struct {
+	UUID    string
+	Wishes  []OnchainWish
+	Balance *big.Int
+}
+
+// OnchainWish желание из массива wishes пользователя в контракте
+type OnchainWish struct {
+	ID             uint64
+	Price          *big.Int
+	CurrentBalance *big.Int
+	Completed      bool
+}
+
+// PackUsers кодирует чтение users(address)
+func (d *Donates) PackUsers(addr common.Address) ([]byte, error) {
+	return d.abi.Pack("users", addr)
+}
+
+// UnpackUsers декодирует ответ users(address). Незарегистрированному адресу соответствует пустой UUID
+func (d *Donates) UnpackUsers(data []byte) (*OnchainUser, error) {
+	method, ok := d.abi.Methods["users"]
+	if !ok || len(method.Outputs) != 2 {
+		return nil, fmt.Errorf("метод users не найден в ABI")
+	}
+	values, err := method.Outputs.Unpack(data)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка декодирования users: %w", err)
+	}
+	user := reflect.ValueOf(values[0])
+	result := &OnchainUser{}
+	for i, name := range method.Outputs[0].Type.TupleRawNames {
+		switch name {
+		case "uuid":
+			result.UUID = user.Field(i).String()
+		case "wishes":
+			wishes := user.Field(i)
+			elemType := method.Outputs[0].Type.TupleElems[i].Elem
+			for j := 0; j < wishes.Len(); j++ {
+				result.Wishes = append(result.Wishes, unpackWish(*elemType, wishes.Index(j)))
+			}
+		}
+	}
+	balance, ok := values[1].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("неожиданный тип баланса %T", values[1])
+	}
+	result.Balance = balance
+	return result, nil
+}
+
+// ReadUser читает users(address) на блоке block (nil — последний блок)
+func (d *Donates) ReadUser(ctx context.Context, caller ethereum.ContractCaller, addr common.Address, block *big.Int) (*OnchainUser, error) {
+	data, err := d.PackUsers(addr)
+	if err != nil {
+		return nil, err
+	}
+	result, err := caller.CallContract(ctx, ethereum.CallMsg{To: &d.address, Data: data}, block)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения пользователя %s из контракта: %w", addr.Hex(), err)
+	}
+	return d.UnpackUsers(result)
+}
+
+// unpackWish читает поля желания по их именам в ABI. В ABI с wishUUID числового id нет, и ID остаётся нулевым
+func unpackWish(t abi.Type, value reflect.Value) OnchainWish {
+	wish := OnchainWish{Price: new(big.Int), CurrentBalance: new(big.Int)}
+	for i, name := range t.TupleRawNames {
+		field := value.Field(i).Interface()
+		switch name {
+		case "id":
+			if id, ok := field.(*big.Int); ok && id.IsUint64() {
+				wish.ID = id.Uint64()
+			}
+		case "price":
+			if price, ok := field.(*big.Int); ok {
+				wish.Price = price
+			}
+		case "currentBalance":
+			if balance, ok := field.(*big.Int); ok {
+				wish.CurrentBalance = balance
+			}
+		case "completed":
+			wish.Completed, _ = field.(bool)
+		}
+	}
+	return wish
+}
+
+// ReadCommissionRate читает K — комиссию контракта в тысячных долях суммы доната
+func (d *Donates) ReadCommissionRate(ctx context.Context, caller ethereum.ContractCaller, block *big.Int) (*big.Int, error) {
+	return d.readUint(ctx, caller, "K", block)
+}
+
+// ReadOwnerBalance читает ownerBalance — накопленную и ещё не выведенную владельцем комиссию
+func (d *Donates) ReadOwnerBalance(ctx context.Context, caller ethereum.ContractCaller, block *big.Int) (*big.Int, error) {
+	return d.readUint(ctx, caller, "ownerBalance", block)
+}
+
+// readUint вызывает view-метод контракта без аргументов, возвращающий uint
+func (d *Donates) readUint(ctx context.Context, caller ethereum.ContractCaller, method string, block *big.Int) (*big.Int, error) {
+	data, err := d.abi.Pack(method)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка кодирования %s: %w", method, err)
+	}
+	result, err := caller.CallContract(ctx, ethereum.CallMsg{To: &d.address, Data: data}, block)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения %s из контракта: %w", method, err)
+	}
+	values, err := d.abi.Unpack(method, result)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка декодирования %s: %w", method, err)
+	}
+	value, ok := values[0].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("неожиданный тип %s %T", method, values[0])
+	}
+	return value, nil
+}
+
+// buildTuple собирает значение структуры для tuple-аргумента ABI, заполняя поля по их именам в контракте
+func buildTuple(t abi.Type, values map[string]interface{}) (interface{}, error) {
+	if t.T != abi.TupleTy {
+		return nil, fmt.Errorf("ожидался tuple, получен %s", t.String())
+	}
+	result := reflect.New(t.GetType()).Elem()
+	for i, name := range t.TupleRawNames {
+		value, ok := values[name]
+		if !ok {
+			return nil, fmt.Errorf("нет значения для поля %s", name)
+		}
+		field := result.Field(i)
+		v := reflect.ValueOf(value)
+		if !v.Type().AssignableTo(field.Type()) {
+			return nil, fmt.Errorf("поле %s имеет тип %s, передан %s", name, field.Type(), v.Type())
+		}
+		field.Set(v)
+	}
+	return result.Interface(), nil
+}
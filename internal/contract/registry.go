@@ -0,0 +1,133 @@
+package contract
+
+import (
+	abifiles "backend/internal/abi"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DeploymentConfig описание деплоя контракта в конфигурации
+type DeploymentConfig struct {
+	Address    string `json:"address"`
+	ABIVersion string `json:"abi_version"` // пустая версия — abi.LatestVersion
+	StartBlock uint64 `json:"start_block"` // блок деплоя
+	EndBlock   uint64 `json:"end_block"`   // последний индексируемый блок выведенного из работы деплоя, 0 — деплой действующий
+}
+
+// Deployment задеплоенный экземпляр контракта Donates с ABI его версии
+type Deployment struct {
+	Address    common.Address
+	ABIVersion string
+	ABI        abi.ABI
+	StartBlock uint64
+	EndBlock   uint64
+}
+
+// Donates возвращает кодировщик вызовов этого деплоя
+func (d Deployment) Donates() *Donates {
+	return NewDonates(d.ABI, d.Address)
+}
+
+// Retired сообщает, что деплой выведен из работы и его логи после EndBlock не индексируются
+func (d Deployment) Retired() bool {
+	return d.EndBlock != 0
+}
+
+// Covers сообщает, что логи деплоя в блоке block должны индексироваться
+func (d Deployment) Covers(block uint64) bool {
+	return block >= d.StartBlock && (!d.Retired() || block <= d.EndBlock)
+}
+
+// Registry реестр деплоев контракта. Новые желания и транзакции идут в действующий деплой,
+// а логи выведенных из работы деплоев индексируются до их EndBlock
+type Registry struct {
+	deployments []Deployment
+	current     Deployment
+}
+
+// NewRegistry проверяет конфигурацию деплоев и загружает ABI их версий. Действующим может быть
+// только один деплой; если его нет, Current возвращает деплой с нулевым адресом
+func NewRegistry(configs []DeploymentConfig) (*Registry, error) {
+	latest, err := parseABI(abifiles.LatestVersion)
+	if err != nil {
+		return nil, err
+	}
+	r := &Registry{current: Deployment{ABIVersion: abifiles.LatestVersion, ABI: latest}}
+
+	seen := make(map[common.Address]bool)
+	active := 0
+	for _, config := range configs {
+		if !common.IsHexAddress(config.Address) {
+			return nil, fmt.Errorf("некорректный адрес контракта %q", config.Address)
+		}
+		address := common.HexToAddress(config.Address)
+		if seen[address] {
+			return nil, fmt.Errorf("контракт %s указан в реестре дважды", address.Hex())
+		}
+		seen[address] = true
+
+		version := strings.TrimSpace(config.ABIVersion)
+		if version == "" {
+			version = abifiles.LatestVersion
+		}
+		contractABI, err := parseABI(version)
+		if err != nil {
+			return nil, err
+		}
+		if config.EndBlock != 0 && config.EndBlock < config.StartBlock {
+			return nil, fmt.Errorf("у контракта %s end_block %d меньше start_block %d", address.Hex(), config.EndBlock, config.StartBlock)
+		}
+
+		deployment := Deployment{
+			Address:    address,
+			ABIVersion: version,
+			ABI:        contractABI,
+			StartBlock: config.StartBlock,
+			EndBlock:   config.EndBlock,
+		}
+		if !deployment.Retired() {
+			active++
+			r.current = deployment
+		}
+		r.deployments = append(r.deployments, deployment)
+	}
+	if active > 1 {
+		return nil, fmt.Errorf("в реестре %d действующих контракта, у всех, кроме одного, должен быть указан end_block", active)
+	}
+	return r, nil
+}
+
+// Deployments возвращает все деплои из реестра
+func (r *Registry) Deployments() []Deployment {
+	return r.deployments
+}
+
+// Current возвращает действующий деплой
+func (r *Registry) Current() Deployment {
+	return r.current
+}
+
+// Get возвращает деплой по адресу контракта
+func (r *Registry) Get(address common.Address) (Deployment, bool) {
+	for _, deployment := range r.deployments {
+		if deployment.Address == address {
+			return deployment, true
+		}
+	}
+	return Deployment{}, false
+}
+
+func parseABI(version string) (abi.ABI, error) {
+	source, ok := abifiles.Versions[version]
+	if !ok {
+		return abi.ABI{}, fmt.Errorf("неизвестная версия ABI %q", version)
+	}
+	contractABI, err := abi.JSON(strings.NewReader(source))
+	if err != nil {
+		return abi.ABI{}, fmt.Errorf("ошибка парсинга ABI %s: %w", version, err)
+	}
+	return contractABI, nil
+}
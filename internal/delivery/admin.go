@@ -0,0 +1,85 @@
+package delivery
+
+import (
+	"backend/internal/entity"
+	"backend/internal/usecase"
+	"context"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+)
+
+// defaultRevenueDays период отчёта о доходах, если from не указан
+const defaultRevenueDays = 30
+
+type AdminHandler struct {
+	AdminUC usecase.AdminUsecase
+}
+
+func NewAdminHandler(adminUC usecase.AdminUsecase) *AdminHandler {
+	return &AdminHandler{AdminUC: adminUC}
+}
+
+// Configure настраивает роуты отчётов для администраторов платформы
+func (h *AdminHandler) Configure(e *echo.Group, jwtMiddleware echo.MiddlewareFunc) {
+	g := e.Group("/admin", jwtMiddleware, NewAdminMiddleware(h.AdminUC))
+	g.GET("/revenue/daily", h.GetRevenueByDay)
+	g.GET("/revenue/streamers", h.GetRevenueByStreamer)
+	g.GET("/owner-balance", h.GetOwnerBalance)
+}
+
+func (h *AdminHandler) GetRevenueByDay(c echo.Context) error {
+	return h.getRevenue(c, h.AdminUC.GetRevenueByDay)
+}
+
+func (h *AdminHandler) GetRevenueByStreamer(c echo.Context) error {
+	return h.getRevenue(c, h.AdminUC.GetRevenueByStreamer)
+}
+
+// getRevenue разбирает период from/to (YYYY-MM-DD, UTC, обе даты включительно) и строит отчёт.
+// По умолчанию отчёт за последние 30 дней
+func (h *AdminHandler) getRevenue(c echo.Context, report func(ctx context.Context, from, to time.Time) (*entity.RevenueResponse, error)) error {
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	if value := c.QueryParam("to"); value != "" {
+		parsed, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid to")
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, 0, -(defaultRevenueDays - 1))
+	if value := c.QueryParam("from"); value != "" {
+		parsed, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid from")
+		}
+		from = parsed
+	}
+
+	revenue, err := report(c.Request().Context(), from, to)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidRevenuePeriod):
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid revenue period")
+		default:
+			c.Logger().Error(err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+		}
+	}
+	return c.JSON(http.StatusOK, revenue)
+}
+
+func (h *AdminHandler) GetOwnerBalance(c echo.Context) error {
+	balance, err := h.AdminUC.GetOwnerBalance(c.Request().Context())
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrContractNotConfigured):
+			return echo.NewHTTPError(http.StatusServiceUnavailable, "contract not configured")
+		default:
+			c.Logger().Error(err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+		}
+	}
+	return c.JSON(http.StatusOK, balance)
+}
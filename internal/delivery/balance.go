@@ -0,0 +1,41 @@
+package delivery
+
+import (
+	"backend/internal/usecase"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+type BalanceHandler struct {
+	BalanceUC usecase.BalanceUsecase
+}
+
+func NewBalanceHandler(balanceUC usecase.BalanceUsecase) *BalanceHandler {
+	return &BalanceHandler{BalanceUC: balanceUC}
+}
+
+// Configure настраивает роут баланса стримера
+func (h *BalanceHandler) Configure(e *echo.Group, jwtMiddleware echo.MiddlewareFunc) {
+	g := e.Group("/user")
+	g.GET("/balance", h.GetBalance, jwtMiddleware)
+}
+
+func (h *BalanceHandler) GetBalance(c echo.Context) error {
+	userUUID := c.Get("user_uuid").(string)
+	balance, err := h.BalanceUC.GetBalance(c.Request().Context(), userUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrUserNotFound):
+			return echo.NewHTTPError(http.StatusNotFound, "user not found")
+		case errors.Is(err, usecase.ErrWalletNotSet):
+			return echo.NewHTTPError(http.StatusConflict, "polygon wallet not set")
+		case errors.Is(err, usecase.ErrContractNotConfigured):
+			return echo.NewHTTPError(http.StatusServiceUnavailable, "contract not configured")
+		default:
+			c.Logger().Error(err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+		}
+	}
+	return c.JSON(http.StatusOK, balance)
+}
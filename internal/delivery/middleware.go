@@ -1,6 +1,7 @@
 package delivery
 
 import (
+	"backend/internal/usecase"
 	"backend/pkg/jwt"
 	"github.com/labstack/echo/v4"
 	"net/http"
@@ -23,3 +24,21 @@ func NewJWTMiddleware(jwtService *jwt.JWT) echo.MiddlewareFunc {
 		}
 	}
 }
+
+// NewAdminMiddleware возвращает echo middleware, пропускающий только администраторов. Ставится после JWT middleware
+func NewAdminMiddleware(adminUC usecase.AdminUsecase) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			uuid, _ := c.Get("user_uuid").(string)
+			isAdmin, err := adminUC.IsAdmin(c.Request().Context(), uuid)
+			if err != nil {
+				c.Logger().Error(err)
+				return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+			}
+			if !isAdmin {
+				return echo.NewHTTPError(http.StatusForbidden, usecase.ErrNotAdmin.Error())
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package delivery
+
+import (
+	"backend/internal/entity"
+	"backend/internal/usecase"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+type RelayerHandler struct {
+	RelayerUC usecase.RelayerUsecase
+}
+
+func NewRelayerHandler(relayerUC usecase.RelayerUsecase) *RelayerHandler {
+	return &RelayerHandler{RelayerUC: relayerUC}
+}
+
+// Configure настраивает роуты релеера транзакций желаний
+func (h *RelayerHandler) Configure(e *echo.Group, jwtMiddleware echo.MiddlewareFunc) {
+	g := e.Group("/wishlist")
+	g.POST("/:uuid/relay", h.RelayWish, jwtMiddleware)
+	g.GET("/:uuid/transactions", h.GetWishTransactions, jwtMiddleware)
+}
+
+func (h *RelayerHandler) RelayWish(c echo.Context) error {
+	var req entity.RelayWishRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	req.WishUUID = c.Param("uuid")
+	req.UserUUID = c.Get("user_uuid").(string)
+	tx, err := h.RelayerUC.RelayWish(c.Request().Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrRelayerDisabled):
+			return echo.NewHTTPError(http.StatusServiceUnavailable, "relayer disabled")
+		case errors.Is(err, usecase.ErrInvalidRelayAction):
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid action")
+		case errors.Is(err, usecase.ErrWishNotFound):
+			return echo.NewHTTPError(http.StatusNotFound, "wish not found")
+		case errors.Is(err, usecase.ErrWishStatusForAction):
+			return echo.NewHTTPError(http.StatusConflict, "wish status does not allow this action")
+		case errors.Is(err, usecase.ErrWishOnRetiredContract):
+			return echo.NewHTTPError(http.StatusConflict, "wish belongs to a retired contract")
+		case errors.Is(err, usecase.ErrRelayAlreadyQueued):
+			return echo.NewHTTPError(http.StatusConflict, "transaction already in progress")
+		case errors.Is(err, usecase.ErrRelayerCapacity):
+			return echo.NewHTTPError(http.StatusServiceUnavailable, "relayer wish capacity reached")
+		default:
+			c.Logger().Error(err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+		}
+	}
+	return c.JSON(http.StatusAccepted, tx)
+}
+
+func (h *RelayerHandler) GetWishTransactions(c echo.Context) error {
+	userUUID := c.Get("user_uuid").(string)
+	txs, err := h.RelayerUC.GetWishTransactions(c.Request().Context(), userUUID, c.Param("uuid"))
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrWishNotFound):
+			return echo.NewHTTPError(http.StatusNotFound, "wish not found")
+		default:
+			c.Logger().Error(err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+		}
+	}
+	return c.JSON(http.StatusOK, entity.GetRelayedTransactionsResponse{Transactions: txs})
+}
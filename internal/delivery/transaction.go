@@ -0,0 +1,104 @@
+package delivery
+
+import (
+	"backend/internal/entity"
+	"backend/internal/usecase"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+type TransactionHandler struct {
+	TransactionUC usecase.TransactionUsecase
+}
+
+func NewTransactionHandler(transactionUC usecase.TransactionUsecase) *TransactionHandler {
+	return &TransactionHandler{TransactionUC: transactionUC}
+}
+
+// Configure настраивает роуты сборки неподписанных транзакций
+func (h *TransactionHandler) Configure(e *echo.Group, jwtMiddleware echo.MiddlewareFunc) {
+	g := e.Group("/transaction")
+	g.POST("/register", h.BuildRegisterUser, jwtMiddleware)
+	g.POST("/wish/:uuid", h.BuildAddWish, jwtMiddleware)
+	g.POST("/wish/:uuid/remove", h.BuildRemoveWish, jwtMiddleware)
+	g.POST("/donate", h.BuildDonate)
+	g.POST("/withdraw", h.BuildWithdraw, jwtMiddleware)
+}
+
+func (h *TransactionHandler) BuildRegisterUser(c echo.Context) error {
+	userUUID := c.Get("user_uuid").(string)
+	tx, err := h.TransactionUC.BuildRegisterUser(c.Request().Context(), userUUID)
+	if err != nil {
+		return transactionError(c, err)
+	}
+	return c.JSON(http.StatusOK, tx)
+}
+
+func (h *TransactionHandler) BuildAddWish(c echo.Context) error {
+	userUUID := c.Get("user_uuid").(string)
+	tx, err := h.TransactionUC.BuildAddWish(c.Request().Context(), userUUID, c.Param("uuid"))
+	if err != nil {
+		return transactionError(c, err)
+	}
+	return c.JSON(http.StatusOK, tx)
+}
+
+func (h *TransactionHandler) BuildRemoveWish(c echo.Context) error {
+	userUUID := c.Get("user_uuid").(string)
+	tx, err := h.TransactionUC.BuildRemoveWish(c.Request().Context(), userUUID, c.Param("uuid"))
+	if err != nil {
+		return transactionError(c, err)
+	}
+	return c.JSON(http.StatusOK, tx)
+}
+
+func (h *TransactionHandler) BuildDonate(c echo.Context) error {
+	var req entity.BuildDonateTransactionRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	tx, err := h.TransactionUC.BuildDonate(c.Request().Context(), req)
+	if err != nil {
+		return transactionError(c, err)
+	}
+	return c.JSON(http.StatusOK, tx)
+}
+
+func (h *TransactionHandler) BuildWithdraw(c echo.Context) error {
+	var req entity.BuildWithdrawTransactionRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	req.UserUUID = c.Get("user_uuid").(string)
+	tx, err := h.TransactionUC.BuildWithdraw(c.Request().Context(), req)
+	if err != nil {
+		return transactionError(c, err)
+	}
+	return c.JSON(http.StatusOK, tx)
+}
+
+// transactionError переводит ошибки сборки транзакции в HTTP ответы
+func transactionError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidTransactionRequest):
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid transaction request")
+	case errors.Is(err, usecase.ErrUserNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "user not found")
+	case errors.Is(err, usecase.ErrWishNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "wish not found")
+	case errors.Is(err, usecase.ErrInvalidWish):
+		return echo.NewHTTPError(http.StatusConflict, "wish status does not allow this transaction")
+	case errors.Is(err, usecase.ErrWishOnRetiredContract):
+		return echo.NewHTTPError(http.StatusConflict, "wish belongs to a retired contract")
+	case errors.Is(err, usecase.ErrWalletNotSet):
+		return echo.NewHTTPError(http.StatusConflict, "polygon wallet not set")
+	case errors.Is(err, usecase.ErrTransactionWouldRevert):
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "transaction would revert")
+	case errors.Is(err, usecase.ErrContractNotConfigured):
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "contract not configured")
+	default:
+		c.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+}
@@ -157,5 +157,15 @@ func (h *UserHandler) Login(c echo.Context) error {
 
 func (h *UserHandler) Me(c echo.Context) error {
 	uuid := c.Get("user_uuid").(string)
-	return c.JSON(http.StatusOK, map[string]string{"streamer_uuid": uuid})
+	profile, err := h.UserUC.GetProfile(c.Request().Context(), uuid)
+	if err != nil {
+		if errors.Is(err, usecase.ErrUserNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "user not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, entity.MeResponse{
+		StreamerUUID:      uuid,
+		OnchainRegistered: profile.OnchainRegistered,
+	})
 }
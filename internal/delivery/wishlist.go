@@ -6,14 +6,17 @@ import (
 	"errors"
 	"github.com/labstack/echo/v4"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 type WishlistHandler struct {
-	WishUC usecase.WishUsecase
+	WishUC        usecase.WishUsecase
+	LinkPreviewUC usecase.LinkPreviewUsecase
 }
 
-func NewWishlistHandler(wishUC usecase.WishUsecase) *WishlistHandler {
-	return &WishlistHandler{WishUC: wishUC}
+func NewWishlistHandler(wishUC usecase.WishUsecase, linkPreviewUC usecase.LinkPreviewUsecase) *WishlistHandler {
+	return &WishlistHandler{WishUC: wishUC, LinkPreviewUC: linkPreviewUC}
 }
 
 // Configure настраивает роуты wishlist
@@ -21,7 +24,14 @@ func (h *WishlistHandler) Configure(e *echo.Group, jwtMiddleware echo.Middleware
 	g := e.Group("/wishlist")
 	g.POST("", h.AddWish, jwtMiddleware)
 	g.PUT("", h.UpdateWish, jwtMiddleware)
+	g.PUT("/order", h.ReorderWishes, jwtMiddleware)
+	g.POST("/preview", h.PreviewLink, jwtMiddleware)
+	g.PATCH("/:uuid", h.EditWish, jwtMiddleware)
+	g.DELETE("/:uuid", h.DeleteWish, jwtMiddleware)
 	g.GET("", h.GetWishes)
+	g.GET("/my", h.GetOwnerWishes, jwtMiddleware)
+	g.GET("/mismatches", h.GetPriceMismatches, jwtMiddleware)
+	g.POST("/:uuid/accept-onchain-price", h.AcceptOnchainPrice, jwtMiddleware)
 }
 
 func (h *WishlistHandler) AddWish(c echo.Context) error {
@@ -40,6 +50,8 @@ func (h *WishlistHandler) AddWish(c echo.Context) error {
 			return echo.NewHTTPError(http.StatusNotFound, "user not found")
 		case errors.Is(err, usecase.ErrStaticFileNotFound):
 			return echo.NewHTTPError(http.StatusNotFound, "static file not found")
+		case errors.Is(err, usecase.ErrUserNotRegistered):
+			return echo.NewHTTPError(http.StatusConflict, "streamer is not registered on-chain")
 		default:
 			c.Logger().Error(err)
 			return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
@@ -69,6 +81,158 @@ func (h *WishlistHandler) UpdateWish(c echo.Context) error {
 	return c.NoContent(http.StatusOK)
 }
 
+func (h *WishlistHandler) EditWish(c echo.Context) error {
+	var req entity.EditWishRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	req.WishUUID = c.Param("uuid")
+	req.UserUUID = c.Get("user_uuid").(string)
+	if err := h.WishUC.EditWish(c.Request().Context(), req); err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidWish):
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid wish")
+		case errors.Is(err, usecase.ErrWishNotFound):
+			return echo.NewHTTPError(http.StatusNotFound, "wish not found")
+		case errors.Is(err, usecase.ErrStaticFileNotFound):
+			return echo.NewHTTPError(http.StatusNotFound, "static file not found")
+		case errors.Is(err, usecase.ErrWishFieldLocked):
+			return echo.NewHTTPError(http.StatusConflict, "wish field cannot be changed in current status")
+		case errors.Is(err, usecase.ErrRelayAlreadyQueued):
+			return echo.NewHTTPError(http.StatusConflict, "transaction already in progress")
+		default:
+			c.Logger().Error(err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+		}
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *WishlistHandler) ReorderWishes(c echo.Context) error {
+	var req entity.ReorderWishesRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	req.UserUUID = c.Get("user_uuid").(string)
+	if err := h.WishUC.ReorderWishes(c.Request().Context(), req); err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidWishOrder):
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid wish order")
+		case errors.Is(err, usecase.ErrWishOrderChanged):
+			return echo.NewHTTPError(http.StatusConflict, "wish order changed, reload the list")
+		default:
+			c.Logger().Error(err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+		}
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *WishlistHandler) PreviewLink(c echo.Context) error {
+	var req entity.LinkPreviewRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	req.UserUUID = c.Get("user_uuid").(string)
+	resp, err := h.LinkPreviewUC.Preview(c.Request().Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrLinkPreviewInvalidURL):
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid link")
+		case errors.Is(err, usecase.ErrLinkPreviewForbidden):
+			return echo.NewHTTPError(http.StatusBadRequest, "link address is not allowed")
+		case errors.Is(err, usecase.ErrLinkPreviewUnsupported):
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, "link content is not supported")
+		case errors.Is(err, usecase.ErrLinkPreviewUnavailable):
+			return echo.NewHTTPError(http.StatusBadGateway, "link is unavailable")
+		default:
+			c.Logger().Error(err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+		}
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *WishlistHandler) DeleteWish(c echo.Context) error {
+	userUUID := c.Get("user_uuid").(string)
+	resp, err := h.WishUC.DeleteWish(c.Request().Context(), userUUID, c.Param("uuid"))
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrWishNotFound):
+			return echo.NewHTTPError(http.StatusNotFound, "wish not found")
+		case errors.Is(err, usecase.ErrWishStatusForAction):
+			return echo.NewHTTPError(http.StatusConflict, "wish status does not allow this action")
+		case errors.Is(err, usecase.ErrWishOnRetiredContract):
+			return echo.NewHTTPError(http.StatusConflict, "wish belongs to a retired contract")
+		case errors.Is(err, usecase.ErrRelayAlreadyQueued):
+			return echo.NewHTTPError(http.StatusConflict, "transaction already in progress")
+		case errors.Is(err, usecase.ErrWishRemovalOnchain):
+			return echo.NewHTTPError(http.StatusConflict, "wish must be removed on-chain")
+		default:
+			c.Logger().Error(err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+		}
+	}
+	// Удаление из контракта завершится после обработки события WishDeleted индексатором
+	if resp.Transaction != nil {
+		return c.JSON(http.StatusAccepted, resp)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GetOwnerWishes возвращает желания текущего стримера. Статусы передаются через запятую
+// или повторяющимся параметром status
+func (h *WishlistHandler) GetOwnerWishes(c echo.Context) error {
+	userUUID := c.Get("user_uuid").(string)
+	var statuses []string
+	for _, value := range c.QueryParams()["status"] {
+		for _, status := range strings.Split(value, ",") {
+			if status = strings.TrimSpace(status); status != "" {
+				statuses = append(statuses, status)
+			}
+		}
+	}
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	pageSize, _ := strconv.Atoi(c.QueryParam("page_size"))
+	wishes, err := h.WishUC.GetOwnerWishes(c.Request().Context(), userUUID, statuses, page, pageSize)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidWish):
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid status")
+		default:
+			c.Logger().Error(err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+		}
+	}
+	return c.JSON(http.StatusOK, wishes)
+}
+
+func (h *WishlistHandler) GetPriceMismatches(c echo.Context) error {
+	userUUID := c.Get("user_uuid").(string)
+	wishes, err := h.WishUC.GetPriceMismatches(c.Request().Context(), userUUID)
+	if err != nil {
+		c.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+	return c.JSON(http.StatusOK, entity.GetPriceMismatchesResponse{Wishes: wishes})
+}
+
+func (h *WishlistHandler) AcceptOnchainPrice(c echo.Context) error {
+	userUUID := c.Get("user_uuid").(string)
+	if err := h.WishUC.AcceptOnchainPrice(c.Request().Context(), userUUID, c.Param("uuid")); err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrWishNotFound):
+			return echo.NewHTTPError(http.StatusNotFound, "wish not found")
+		case errors.Is(err, usecase.ErrWishNotMismatched):
+			return echo.NewHTTPError(http.StatusConflict, "wish price is not mismatched")
+		default:
+			c.Logger().Error(err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+		}
+	}
+	return c.NoContent(http.StatusOK)
+}
+
 func (h *WishlistHandler) GetWishes(c echo.Context) error {
 	streamerUUID := c.QueryParam("streamer_uuid")
 	if streamerUUID == "" {
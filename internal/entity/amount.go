@@ -0,0 +1,240 @@
+package entity
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// polDecimals количество знаков после запятой в POL: 1 POL = 10^18 wei
+const polDecimals = 18
+
+var weiPerPOL = new(big.Int).Exp(big.NewInt(10), big.NewInt(polDecimals), nil)
+
+// Amount точная сумма в wei. В Mongo хранится как Decimal128 с целым числом wei, чтобы работал $inc,
+// в JSON передаётся строкой в POL ("1.5"), чтобы клиент не терял точность на float64.
+// Нулевое значение — ноль
+type Amount struct {
+	wei *big.Int
+}
+
+// NewAmount создаёт сумму из значения в wei
+func NewAmount(wei *big.Int) Amount {
+	if wei == nil {
+		return Amount{}
+	}
+	return Amount{wei: new(big.Int).Set(wei)}
+}
+
+// ParsePOL разбирает десятичную запись суммы в POL без потери точности
+func ParsePOL(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	digits := strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+
+	whole, fraction, _ := strings.Cut(digits, ".")
+	if whole == "" && fraction == "" {
+		return Amount{}, fmt.Errorf("некорректная сумма %q", s)
+	}
+	if len(fraction) > polDecimals {
+		return Amount{}, fmt.Errorf("у суммы %q больше %d знаков после запятой", s, polDecimals)
+	}
+	if strings.ContainsAny(whole+fraction, "+-eE") {
+		return Amount{}, fmt.Errorf("некорректная сумма %q", s)
+	}
+	wei, ok := new(big.Int).SetString(whole+fraction+strings.Repeat("0", polDecimals-len(fraction)), 10)
+	if !ok {
+		return Amount{}, fmt.Errorf("некорректная сумма %q", s)
+	}
+	if negative {
+		wei.Neg(wei)
+	}
+	return Amount{wei: wei}, nil
+}
+
+// POLFromFloat переводит сумму в POL из float64 по её кратчайшей десятичной записи.
+// Используется только для старых документов, где суммы хранились как double
+func POLFromFloat(value float64) Amount {
+	amount, err := ParsePOL(strconv.FormatFloat(value, 'f', -1, 64))
+	if err != nil {
+		return Amount{}
+	}
+	return amount
+}
+
+// parseExponentPOL разбирает JSON число в экспоненциальной записи ("1.5e3") точно, через big.Rat.
+// Сумма, не выражаемая целым числом wei, отклоняется, как и в ParsePOL
+func parseExponentPOL(s string) (Amount, error) {
+	value, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return Amount{}, fmt.Errorf("некорректная сумма %q", s)
+	}
+	value.Mul(value, new(big.Rat).SetInt(weiPerPOL))
+	if !value.IsInt() {
+		return Amount{}, fmt.Errorf("у суммы %q больше %d знаков после запятой", s, polDecimals)
+	}
+	return Amount{wei: new(big.Int).Set(value.Num())}, nil
+}
+
+// Wei возвращает копию суммы в wei
+func (a Amount) Wei() *big.Int {
+	if a.wei == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(a.wei)
+}
+
+func (a Amount) Add(b Amount) Amount {
+	return Amount{wei: new(big.Int).Add(a.Wei(), b.Wei())}
+}
+
+func (a Amount) Sub(b Amount) Amount {
+	return Amount{wei: new(big.Int).Sub(a.Wei(), b.Wei())}
+}
+
+func (a Amount) Neg() Amount {
+	return Amount{wei: new(big.Int).Neg(a.Wei())}
+}
+
+func (a Amount) Cmp(b Amount) int {
+	return a.Wei().Cmp(b.Wei())
+}
+
+func (a Amount) Sign() int {
+	if a.wei == nil {
+		return 0
+	}
+	return a.wei.Sign()
+}
+
+func (a Amount) IsZero() bool {
+	return a.Sign() == 0
+}
+
+// String возвращает сумму в POL без лишних нулей: "1.5", "0.000000000000000001", "10"
+func (a Amount) String() string {
+	wei := a.Wei()
+	sign := ""
+	if wei.Sign() < 0 {
+		sign = "-"
+		wei.Neg(wei)
+	}
+	whole, fraction := new(big.Int).QuoRem(wei, weiPerPOL, new(big.Int))
+	if fraction.Sign() == 0 {
+		return sign + whole.String()
+	}
+	fractionDigits := fmt.Sprintf("%0*s", polDecimals, fraction.String())
+	return sign + whole.String() + "." + strings.TrimRight(fractionDigits, "0")
+}
+
+// WeiString возвращает сумму в wei десятичной строкой
+func (a Amount) WeiString() string {
+	return a.Wei().String()
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// UnmarshalJSON принимает сумму в POL строкой или числом; число разбирается по исходной записи, без float64
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	var text string
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+	} else {
+		var number json.Number
+		if err := json.Unmarshal(data, &number); err != nil {
+			return fmt.Errorf("сумма должна быть строкой или числом: %w", err)
+		}
+		text = number.String()
+		if strings.ContainsAny(text, "eE") {
+			amount, err := parseExponentPOL(text)
+			if err != nil {
+				return err
+			}
+			*a = amount
+			return nil
+		}
+	}
+	amount, err := ParsePOL(text)
+	if err != nil {
+		return err
+	}
+	*a = amount
+	return nil
+}
+
+func (a Amount) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	value, err := primitive.ParseDecimal128(a.WeiString())
+	if err != nil {
+		return 0, nil, fmt.Errorf("сумма %s wei не помещается в Decimal128: %w", a.WeiString(), err)
+	}
+	return bsontype.Decimal128, bsoncore.AppendDecimal128(nil, value), nil
+}
+
+// UnmarshalBSONValue читает Decimal128 с суммой в wei, а также double в POL из документов до миграции
+func (a *Amount) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	switch t {
+	case bsontype.Decimal128:
+		value, _, ok := bsoncore.ReadDecimal128(data)
+		if !ok {
+			return fmt.Errorf("некорректное значение Decimal128")
+		}
+		coefficient, exp, err := value.BigInt()
+		if err != nil {
+			return fmt.Errorf("некорректная сумма %s: %w", value.String(), err)
+		}
+		scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(exp))), nil)
+		if exp >= 0 {
+			coefficient.Mul(coefficient, scale)
+		} else if new(big.Int).Rem(coefficient, scale).Sign() != 0 {
+			return fmt.Errorf("сумма в wei должна быть целой: %s", value.String())
+		} else {
+			coefficient.Quo(coefficient, scale)
+		}
+		a.wei = coefficient
+	case bsontype.Double:
+		value, _, ok := bsoncore.ReadDouble(data)
+		if !ok {
+			return fmt.Errorf("некорректное значение double")
+		}
+		*a = POLFromFloat(value)
+	case bsontype.Int32:
+		value, _, ok := bsoncore.ReadInt32(data)
+		if !ok {
+			return fmt.Errorf("некорректное значение int32")
+		}
+		*a = POL(int64(value))
+	case bsontype.Int64:
+		value, _, ok := bsoncore.ReadInt64(data)
+		if !ok {
+			return fmt.Errorf("некорректное значение int64")
+		}
+		*a = POL(value)
+	case bsontype.Null, bsontype.Undefined:
+		*a = Amount{}
+	default:
+		return fmt.Errorf("неподдерживаемый тип суммы %s", t)
+	}
+	return nil
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// POL создаёт сумму из целого числа POL
+func POL(value int64) Amount {
+	return Amount{wei: new(big.Int).Mul(big.NewInt(value), weiPerPOL)}
+}
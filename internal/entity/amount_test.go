@@ -0,0 +1,122 @@
+package entity
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestAmountParseAndFormat(t *testing.T) {
+	cases := map[string]string{
+		"1.5":                  "1500000000000000000",
+		"0.000000000000000001": "1",
+		"10":                   "10000000000000000000",
+		// Больше math.MaxInt64 wei: на int64 такая сумма переполнялась
+		"123456789.123456789123456789": "123456789123456789123456789",
+	}
+	for pol, wei := range cases {
+		amount, err := ParsePOL(pol)
+		if err != nil {
+			t.Fatalf("%s: %v", pol, err)
+		}
+		if amount.WeiString() != wei {
+			t.Fatalf("%s: получено %s wei, ожидалось %s", pol, amount.WeiString(), wei)
+		}
+		if amount.String() != pol {
+			t.Fatalf("%s: обратное форматирование дало %s", pol, amount.String())
+		}
+	}
+
+	for _, invalid := range []string{"", ".", "1.2.3", "1e18", "abc", "0.0000000000000000001"} {
+		if _, err := ParsePOL(invalid); err == nil {
+			t.Fatalf("сумма %q разобрана без ошибки", invalid)
+		}
+	}
+}
+
+func TestAmountJSON(t *testing.T) {
+	var request struct {
+		Number Amount `json:"number"`
+		Text   Amount `json:"text"`
+	}
+	if err := json.Unmarshal([]byte(`{"number": 0.1, "text": "20.000000000000000001"}`), &request); err != nil {
+		t.Fatal(err)
+	}
+	if request.Number.WeiString() != "100000000000000000" {
+		t.Fatalf("число разобрано как %s wei", request.Number.WeiString())
+	}
+	if request.Text.WeiString() != "20000000000000000001" {
+		t.Fatalf("строка разобрана как %s wei", request.Text.WeiString())
+	}
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"number":"0.1","text":"20.000000000000000001"}` {
+		t.Fatalf("сумма сериализована как %s", data)
+	}
+}
+
+func TestAmountJSONExponent(t *testing.T) {
+	cases := map[string]string{
+		"1e3":                      "1000000000000000000000",
+		"1.5E-3":                   "1500000000000000",
+		"1e-18":                    "1",
+		"123456789.123456789123e9": "123456789123456789123000000000000000",
+		// Через float64 последние цифры округлялись бы
+		"9.007199254740993e15": "9007199254740993000000000000000000",
+	}
+	for number, wei := range cases {
+		var amount Amount
+		if err := json.Unmarshal([]byte(number), &amount); err != nil {
+			t.Fatalf("%s: %v", number, err)
+		}
+		if amount.WeiString() != wei {
+			t.Fatalf("%s: получено %s wei, ожидалось %s", number, amount.WeiString(), wei)
+		}
+	}
+
+	for _, invalid := range []string{"1e-19", "1.5e-18"} {
+		var amount Amount
+		if err := json.Unmarshal([]byte(invalid), &amount); err == nil {
+			t.Fatalf("сумма %s меньше 1 wei разобрана без ошибки: %s wei", invalid, amount.WeiString())
+		}
+	}
+}
+
+func TestAmountBSON(t *testing.T) {
+	wei, _ := new(big.Int).SetString("98765432109876543210987654321", 10)
+	doc := struct {
+		Amount Amount `bson:"amount"`
+	}{Amount: NewAmount(wei)}
+
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kind := bson.Raw(data).Lookup("amount").Type; kind.String() != "128-bit decimal" {
+		t.Fatalf("сумма сохранена как %s, ожидался Decimal128", kind)
+	}
+	doc.Amount = Amount{}
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Amount.Wei().Cmp(wei) != 0 {
+		t.Fatalf("после чтения из BSON получено %s wei", doc.Amount.WeiString())
+	}
+
+	// Документы до миграции хранят сумму в POL как double
+	legacy, err := bson.Marshal(bson.M{"amount": 1.1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bson.Unmarshal(legacy, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Amount.WeiString() != "1100000000000000000" {
+		t.Fatalf("double 1.1 прочитан как %s wei", doc.Amount.WeiString())
+	}
+}
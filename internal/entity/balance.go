@@ -0,0 +1,13 @@
+package entity
+
+import "time"
+
+// BalanceResponse баланс стримера: доступная к выводу сумма из контракта и итоги по проиндексированной истории
+type BalanceResponse struct {
+	PolygonWallet string    `json:"polygon_wallet"`
+	Withdrawable  Amount    `json:"withdrawable"` // currentBalance в контракте
+	Donated       Amount    `json:"donated"`      // сумма донатов до вычета комиссии
+	Withdrawn     Amount    `json:"withdrawn"`    // сумма выводов
+	Commission    Amount    `json:"commission"`   // комиссия платформы с донатов
+	UpdatedAt     time.Time `json:"updated_at"`   // время чтения баланса из контракта
+}
@@ -4,18 +4,56 @@ import "time"
 
 // BlockchainState хранит состояние синхронизации с блокчейном
 type BlockchainState struct {
-	ID                 string    `bson:"_id" json:"id"`
-	LastProcessedBlock uint64    `bson:"last_processed_block" json:"last_processed_block"`
-	UpdatedAt          time.Time `bson:"updated_at" json:"updated_at"`
+	ID                 string           `bson:"_id" json:"id"`
+	LastProcessedBlock uint64           `bson:"last_processed_block" json:"last_processed_block"`
+	RecentBlocks       []ProcessedBlock `bson:"recent_blocks" json:"recent_blocks"` // хеши последних обработанных блоков для обнаружения реорганизаций
+	// Checkpoints последний обработанный блок каждого деплоя контракта по его адресу. Деплой, добавленный в реестр позже,
+	// догоняет цепочку от своего блока деплоя, не задерживая остальные
+	Checkpoints map[string]uint64 `bson:"checkpoints,omitempty" json:"checkpoints,omitempty"`
+	UpdatedAt   time.Time         `bson:"updated_at" json:"updated_at"`
+}
+
+// Checkpoint возвращает последний обработанный блок контракта. Состояние, сохранённое до появления реестра
+// контрактов, хранит только общий блок
+func (s *BlockchainState) Checkpoint(contractAddress string) uint64 {
+	if block, ok := s.Checkpoints[contractAddress]; ok {
+		return block
+	}
+	return s.LastProcessedBlock
+}
+
+// ProcessedBlock номер и хеш обработанного блока
+type ProcessedBlock struct {
+	Number uint64 `bson:"number" json:"number"`
+	Hash   string `bson:"hash" json:"hash"`
 }
 
 // BlockchainEvent представляет событие блокчейна для сохранения в БД
 type BlockchainEvent struct {
-	ID          string    `bson:"_id" json:"id"` // уникальный идентификатор события
+	ID              string                 `bson:"_id" json:"id"` // хеш транзакции и индекс лога: <tx_hash>-<log_index>
+	ContractAddress string                 `bson:"contract_address" json:"contract_address"`
+	BlockNumber     uint64                 `bson:"block_number" json:"block_number"`
+	BlockHash       string                 `bson:"block_hash" json:"block_hash"`
+	TxHash          string                 `bson:"tx_hash" json:"tx_hash"`
+	LogIndex        uint                   `bson:"log_index" json:"log_index"`
+	EventType       string                 `bson:"event_type" json:"event_type"` // WishAdded, WishCompleted, WishDeleted, PaymentCredited, ...
+	UserUUID        string                 `bson:"user_uuid" json:"user_uuid"`
+	WishUUID        string                 `bson:"wish_uuid" json:"wish_uuid"`
+	Applied         bool                   `bson:"applied" json:"applied"`                             // событие изменило состояние (используется при откате реорганизации)
+	PrevStatus      string                 `bson:"prev_status,omitempty" json:"prev_status,omitempty"` // статус желания до применения события
+	Payload         map[string]interface{} `bson:"payload" json:"payload"`                             // декодированные аргументы события
+	ProcessedAt     time.Time              `bson:"processed_at" json:"processed_at"`
+}
+
+// BlockchainAnomaly событие контракта, отклонённое при проверке
+type BlockchainAnomaly struct {
+	ID          string    `bson:"_id" json:"id"`
 	BlockNumber uint64    `bson:"block_number" json:"block_number"`
 	TxHash      string    `bson:"tx_hash" json:"tx_hash"`
-	EventType   string    `bson:"event_type" json:"event_type"` // WishAdded, WishCompleted, WishDeleted
-	UserUUID    string    `bson:"user_uuid" json:"user_uuid"`
-	WishUUID    string    `bson:"wish_uuid" json:"wish_uuid"`
-	ProcessedAt time.Time `bson:"processed_at" json:"processed_at"`
+	EventType   string    `bson:"event_type" json:"event_type"`
+	Reason      string    `bson:"reason" json:"reason"`
+	TopicHash   string    `bson:"topic_hash,omitempty" json:"topic_hash,omitempty"`
+	UserUUID    string    `bson:"user_uuid,omitempty" json:"user_uuid,omitempty"`
+	WishUUID    string    `bson:"wish_uuid,omitempty" json:"wish_uuid,omitempty"`
+	DetectedAt  time.Time `bson:"detected_at" json:"detected_at"`
 }
@@ -8,16 +8,19 @@ type History struct {
 	Type         string    `bson:"type" json:"type"` // donate/withdraw
 	Username     *string   `bson:"username,omitempty" json:"username,omitempty"`
 	Datetime     time.Time `bson:"datetime" json:"datetime"`
-	Amount       float64   `bson:"amount" json:"amount"`
+	Amount       Amount    `bson:"amount" json:"amount"`         // сумма платежа, для донатов — до вычета комиссии
+	NetAmount    Amount    `bson:"net_amount" json:"net_amount"` // transferedToUserAmount: сумма, зачисленная на баланс стримера
+	Commission   Amount    `bson:"commission" json:"commission"` // комиссия платформы, для выводов 0
 	WishUUID     *string   `bson:"wish_uuid,omitempty" json:"wish_uuid,omitempty"`
 	Message      *string   `bson:"message,omitempty" json:"message,omitempty"`
+	BlockNumber  uint64    `bson:"block_number" json:"block_number"` // блок, в котором был зачислен платёж
 }
 
 type HistoryItem struct {
 	Type     string  `json:"type"`
 	Username *string `json:"username,omitempty"`
 	Datetime string  `json:"datetime"`
-	Amount   float64 `json:"amount"`
+	Amount   Amount  `json:"amount"`
 	WishUUID *string `json:"wish_uuid,omitempty"`
 	Message  *string `json:"message,omitempty"`
 }
@@ -0,0 +1,20 @@
+package entity
+
+// LinkPreviewRequest ссылка на товар, по которой заполняется черновик желания.
+// ImportImage загружает изображение товара в хранилище как изображение желания
+type LinkPreviewRequest struct {
+	URL         string `json:"url"`
+	ImportImage bool   `json:"import_image"`
+	UserUUID    string `json:"-"`
+}
+
+// LinkPreviewResponse черновик желания по странице товара. Цена магазина возвращается отдельно:
+// курс к POL бэкенд не знает, поэтому pol_target заполняется, только если цена указана в POL
+type LinkPreviewResponse struct {
+	Draft            AddWishRequest `json:"draft"`
+	SiteName         string         `json:"site_name,omitempty"`
+	ImageURL         string         `json:"image_url,omitempty"` // исходное изображение на странице товара
+	Price            string         `json:"price,omitempty"`
+	Currency         string         `json:"currency,omitempty"`
+	ImageImportError string         `json:"image_import_error,omitempty"` // причина, по которой изображение не загружено
+}
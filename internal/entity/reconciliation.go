@@ -0,0 +1,50 @@
+package entity
+
+import "time"
+
+// Виды расхождений между контрактом и базой
+const (
+	// DriftWishStatus статус желания не соответствует его наличию и отметке completed в контракте
+	DriftWishStatus = "wish_status"
+	// DriftWishPrice цена активного желания в контракте не совпадает с целевой суммой
+	DriftWishPrice = "wish_price"
+	// DriftWishAmount накопленная сумма желания не совпадает с суммой донатов на него в истории
+	DriftWishAmount = "wish_amount"
+	// DriftBalance баланс стримера в контракте не совпадает с балансом, посчитанным по истории
+	DriftBalance = "balance"
+)
+
+// ReconciliationReport результат сверки состояния контракта с базой
+type ReconciliationReport struct {
+	ID            string                `bson:"_id" json:"id"`
+	StartedAt     time.Time             `bson:"started_at" json:"started_at"`
+	FinishedAt    time.Time             `bson:"finished_at" json:"finished_at"`
+	AutoRepair    bool                  `bson:"auto_repair" json:"auto_repair"`
+	UsersChecked  int                   `bson:"users_checked" json:"users_checked"`
+	WishesChecked int                   `bson:"wishes_checked" json:"wishes_checked"`
+	Drifts        []ReconciliationDrift `bson:"drifts" json:"drifts"`
+	Errors        []string              `bson:"errors,omitempty" json:"errors,omitempty"` // пользователи, которых не удалось проверить
+}
+
+// ReconciliationDrift расхождение: Onchain — значение по контракту, Stored — значение в базе
+type ReconciliationDrift struct {
+	Kind      string `bson:"kind" json:"kind"` // wish_status, wish_price, wish_amount, balance
+	UserUUID  string `bson:"user_uuid" json:"user_uuid"`
+	WishUUID  string `bson:"wish_uuid,omitempty" json:"wish_uuid,omitempty"`
+	OnchainID uint64 `bson:"onchain_id,omitempty" json:"onchain_id,omitempty"`
+	Onchain   string `bson:"onchain" json:"onchain"`
+	Stored    string `bson:"stored" json:"stored"`
+	Repaired  bool   `bson:"repaired" json:"repaired"`
+}
+
+// HistoryTotals суммы платежей стримера по истории
+type HistoryTotals struct {
+	Donated    Amount `bson:"donated" json:"donated"`
+	DonatedNet Amount `bson:"donated_net" json:"donated_net"`
+	Commission Amount `bson:"commission" json:"commission"`
+	Withdrawn  Amount `bson:"withdrawn" json:"withdrawn"`
+	Donations  int64  `bson:"donations" json:"donations"`
+	// LegacyDonated сумма донатов, проиндексированных до сохранения комиссии: для них известна только сумма до вычета
+	LegacyDonated   Amount `bson:"legacy_donated" json:"legacy_donated"`
+	LegacyDonations int64  `bson:"legacy_donations" json:"legacy_donations"`
+}
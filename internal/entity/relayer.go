@@ -0,0 +1,66 @@
+package entity
+
+import "time"
+
+// Действия релеера и статусы отправляемых им транзакций
+const (
+	RelayActionAdd      = "add"
+	RelayActionComplete = "complete"
+	RelayActionRemove   = "remove"
+	// RelayActionRegister регистрирует адрес релеера в контракте, чтобы события его желаний несли UUID релеера
+	RelayActionRegister = "register"
+	// RelayActionPrune удаляет завершённое желание из массива релеера, чтобы addWish не дорожал с каждым желанием
+	RelayActionPrune = "prune"
+
+	RelayStatusQueued  = "queued"
+	RelayStatusPending = "pending"
+	RelayStatusMined   = "mined"
+	RelayStatusFailed  = "failed"
+)
+
+// RelayedTransaction вызов контракта, который подписывает и отправляет релеер от имени бэкенда
+type RelayedTransaction struct {
+	ID           string    `bson:"_id" json:"id"`
+	WishUUID     string    `bson:"wish_uuid" json:"wish_uuid"`
+	StreamerUUID string    `bson:"streamer_uuid" json:"streamer_uuid"`
+	Action       string    `bson:"action" json:"action"` // add, complete, remove, register, prune
+	Status       string    `bson:"status" json:"status"` // queued, pending, mined, failed
+	From         string    `bson:"from,omitempty" json:"from,omitempty"`
+	Nonce        *uint64   `bson:"nonce,omitempty" json:"nonce,omitempty"`
+	TxHash       string    `bson:"tx_hash,omitempty" json:"tx_hash,omitempty"`             // хеш последней отправленной (или включённой в блок) транзакции
+	TxHashes     []string  `bson:"tx_hashes,omitempty" json:"tx_hashes,omitempty"`         // все отправленные версии транзакции, включая повышения комиссии
+	Data         string    `bson:"data,omitempty" json:"-"`                                // calldata, с которой подписываются повторные версии транзакции
+	RawTx        string    `bson:"raw_tx,omitempty" json:"-"`                              // последняя подписанная версия, сохраняется до отправки в сеть
+	CancelHashes []string  `bson:"cancel_hashes,omitempty" json:"cancel_hashes,omitempty"` // версии отмены: перевод 0 на свой адрес с тем же nonce
+	GasLimit     uint64    `bson:"gas_limit,omitempty" json:"gas_limit,omitempty"`
+	GasFeeCap    string    `bson:"gas_fee_cap,omitempty" json:"gas_fee_cap,omitempty"` // wei
+	GasTipCap    string    `bson:"gas_tip_cap,omitempty" json:"gas_tip_cap,omitempty"` // wei
+	Attempts     int       `bson:"attempts" json:"attempts"`
+	BlockNumber  uint64    `bson:"block_number,omitempty" json:"block_number,omitempty"`
+	Error        string    `bson:"error,omitempty" json:"error,omitempty"`
+	SubmittedAt  time.Time `bson:"submitted_at,omitempty" json:"submitted_at,omitempty"`
+	CreatedAt    time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+type RelayWishRequest struct {
+	Action   string `json:"action"` // add, complete, remove
+	WishUUID string `json:"-"`
+	UserUUID string `json:"-"`
+}
+
+type RelayedTransactionResponse struct {
+	ID          string    `json:"id"`
+	Action      string    `json:"action"`
+	Status      string    `json:"status"`
+	TxHash      string    `json:"tx_hash,omitempty"`
+	Attempts    int       `json:"attempts"`
+	BlockNumber uint64    `json:"block_number,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type GetRelayedTransactionsResponse struct {
+	Transactions []RelayedTransactionResponse `json:"transactions"`
+}
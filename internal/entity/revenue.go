@@ -0,0 +1,34 @@
+package entity
+
+import "time"
+
+// RevenueEntry донаты и комиссия платформы за день или по одному стримеру
+type RevenueEntry struct {
+	Day          string `bson:"day,omitempty" json:"day,omitempty"` // YYYY-MM-DD в UTC
+	StreamerUUID string `bson:"streamer_uuid,omitempty" json:"streamer_uuid,omitempty"`
+	StreamerName string `bson:"streamer_name,omitempty" json:"streamer_name,omitempty"`
+	Donated      Amount `bson:"donated" json:"donated"`       // сумма донатов до вычета комиссии
+	Commission   Amount `bson:"commission" json:"commission"` // комиссия платформы
+	Donations    int64  `bson:"donations" json:"donations"`
+	// LegacyDonated донаты, проиндексированные до сохранения комиссии: комиссия по ним оценивается по K
+	LegacyDonated   Amount `bson:"legacy_donated" json:"-"`
+	LegacyDonations int64  `bson:"legacy_donations" json:"legacy_donations"`
+}
+
+// RevenueResponse доход платформы за период [from, to]
+type RevenueResponse struct {
+	From       string         `json:"from"`
+	To         string         `json:"to"`
+	Donated    Amount         `json:"donated"`
+	Commission Amount         `json:"commission"`
+	Donations  int64          `json:"donations"`
+	Entries    []RevenueEntry `json:"entries"`
+}
+
+// OwnerBalanceResponse комиссия, накопленная в контракте и ещё не выведенная владельцем
+type OwnerBalanceResponse struct {
+	ContractAddress string    `json:"contract_address"`
+	OwnerBalance    Amount    `json:"owner_balance"`
+	CommissionRate  uint64    `json:"commission_rate"` // K: комиссия в тысячных долях суммы доната
+	UpdatedAt       time.Time `json:"updated_at"`
+}
@@ -0,0 +1,25 @@
+package entity
+
+// UnsignedTransaction транзакция вызова контракта, готовая к подписи в кошельке пользователя
+type UnsignedTransaction struct {
+	From    string `json:"from,omitempty"`
+	To      string `json:"to"`
+	Data    string `json:"data"`
+	Value   string `json:"value"` // wei
+	ChainID int64  `json:"chain_id"`
+	Gas     uint64 `json:"gas,omitempty"` // оценка газа, если известен адрес отправителя
+}
+
+type BuildDonateTransactionRequest struct {
+	StreamerUUID string  `json:"streamer_uuid"`
+	WishUUID     *string `json:"wish_uuid,omitempty"`
+	Amount       Amount  `json:"amount"` // POL
+	Username     string  `json:"username"`
+	Message      string  `json:"message"`
+	From         string  `json:"from,omitempty"` // адрес кошелька донатера для оценки газа
+}
+
+type BuildWithdrawTransactionRequest struct {
+	Amount   Amount `json:"amount"` // POL
+	UserUUID string `json:"-"`
+}
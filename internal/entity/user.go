@@ -2,10 +2,13 @@ package entity
 
 import (
 	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
 )
 
 type User struct {
 	UUID                  string    `bson:"uuid" json:"uuid"`
+	UUIDHash              string    `bson:"uuid_hash" json:"-"` // keccak256(uuid), по нему сопоставляются индексированные топики событий
 	PolygonWallet         string    `bson:"polygon_wallet" json:"polygon_wallet"`
 	Name                  string    `bson:"name" json:"name"`
 	Topics                []string  `bson:"topics" json:"topics"`
@@ -18,6 +21,14 @@ type User struct {
 	CreatedAt             time.Time `bson:"created_at" json:"created_at"`
 	UpdatedAt             time.Time `bson:"updated_at" json:"updated_at"`
 	TelegramID            string    `bson:"telegram_id" json:"telegram_id"`
+	// OnchainRegistered кошелёк стримера зарегистрирован в контракте с его UUID (подтверждено событием UserCreated)
+	OnchainRegistered bool   `bson:"onchain_registered" json:"onchain_registered"`
+	RegistrationBlock uint64 `bson:"registration_block,omitempty" json:"-"` // блок события UserCreated, подтвердившего регистрацию
+}
+
+// UUIDTopicHash возвращает keccak256 хеш UUID в том виде, в котором он попадает в индексированный топик события
+func UUIDTopicHash(uuid string) string {
+	return crypto.Keccak256Hash([]byte(uuid)).Hex()
 }
 
 type RegisterUserRequest struct {
@@ -52,4 +63,10 @@ type UserProfileResponse struct {
 	Avatar                string   `json:"avatar"`
 	Topics                []string `json:"topics"`
 	PolygonWallet         string   `json:"polygon_wallet"`
+	OnchainRegistered     bool     `json:"onchain_registered"`
+}
+
+type MeResponse struct {
+	StreamerUUID      string `json:"streamer_uuid"`
+	OnchainRegistered bool   `json:"onchain_registered"`
 }
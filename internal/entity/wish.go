@@ -3,18 +3,25 @@ package entity
 import "time"
 
 type Wish struct {
-	UUID         string    `bson:"uuid" json:"uuid"`
-	StreamerUUID string    `bson:"streamer_uuid" json:"streamer_uuid"`
-	WishURL      *string   `bson:"wish_url,omitempty" json:"wish_url,omitempty"`
-	Name         string    `bson:"name" json:"name"`
-	Description  *string   `bson:"description,omitempty" json:"description,omitempty"`
-	Image        string    `bson:"image" json:"image"`
-	PolTarget    float64   `bson:"pol_target" json:"pol_target"`
-	PolAmount    float64   `bson:"pol_amount" json:"pol_amount"`
-	IsPriority   bool      `bson:"is_priority" json:"is_priority"`
-	Status       string    `bson:"status" json:"status"` // pending, active, complete, deleted
-	CreatedAt    time.Time `bson:"created_at" json:"created_at"`
-	UpdatedAt    time.Time `bson:"updated_at" json:"updated_at"`
+	UUID             string    `bson:"uuid" json:"uuid"`
+	OnchainID        uint64    `bson:"onchain_id" json:"onchain_id"` // числовой идентификатор для PaymentInfo.wishId в контракте
+	StreamerUUID     string    `bson:"streamer_uuid" json:"streamer_uuid"`
+	WishURL          *string   `bson:"wish_url,omitempty" json:"wish_url,omitempty"`
+	Name             string    `bson:"name" json:"name"`
+	Description      *string   `bson:"description,omitempty" json:"description,omitempty"`
+	Image            string    `bson:"image" json:"image"`
+	PolTarget        Amount    `bson:"pol_target" json:"pol_target"`
+	PolAmount        Amount    `bson:"pol_amount" json:"pol_amount"`
+	IsPriority       bool      `bson:"is_priority" json:"is_priority"`
+	Position         int       `bson:"position,omitempty" json:"position"`                           // место в списке стримера, вычисляется из WishOrder при чтении списка
+	Status           string    `bson:"status" json:"status"`                                         // pending, active, mismatch, funded, complete, deleted
+	OnchainPrice     *Amount   `bson:"onchain_price,omitempty" json:"-"`                             // цена из WishAdded, если она не совпала с PolTarget (статус mismatch)
+	CreditedPayments []string  `bson:"credited_payments,omitempty" json:"-"`                         // ID записей истории, уже зачисленных в PolAmount
+	RelayedBy        string    `bson:"relayed_by,omitempty" json:"-"`                                // адрес релеера, под которым желание хранится в контракте
+	RelayReleased    bool      `bson:"relay_released,omitempty" json:"-"`                            // желание уже удалено из массива желаний релеера
+	ContractAddress  string    `bson:"contract_address,omitempty" json:"contract_address,omitempty"` // деплой контракта, в который добавлено желание
+	CreatedAt        time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt        time.Time `bson:"updated_at" json:"updated_at"`
 }
 
 type AddWishRequest struct {
@@ -22,7 +29,7 @@ type AddWishRequest struct {
 	Name        string  `json:"name"`
 	Description *string `json:"description,omitempty"`
 	Image       string  `json:"image"`
-	PolTarget   float64 `json:"pol_target"`
+	PolTarget   Amount  `json:"pol_target"`
 	IsPriority  bool    `json:"is_priority"`
 	UserUUID    string  `json:"-"`
 }
@@ -38,17 +45,96 @@ type UpdateWishRequest struct {
 	UserUUID   string `json:"-"`
 }
 
+// EditWishRequest изменение желания. Незаданные поля остаются прежними, пустые описание и ссылка удаляются.
+// После добавления в контракт целевая сумма не меняется: донаты считаются от цены, записанной в контракте
+type EditWishRequest struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+	WishURL     *string `json:"wish_url,omitempty"`
+	PolTarget   *Amount `json:"pol_target,omitempty"`
+	Image       *string `json:"image,omitempty"`
+	IsPriority  *bool   `json:"is_priority,omitempty"`
+	WishUUID    string  `json:"-"`
+	UserUUID    string  `json:"-"`
+}
+
+// WishOrder порядок желаний стримера, заданный перетаскиванием. Хранится одним документом, поэтому новый
+// порядок применяется атомарно, а Version позволяет сохранить его, только если список не менялся после чтения
+type WishOrder struct {
+	StreamerUUID string   `bson:"_id"`
+	WishUUIDs    []string `bson:"wish_uuids"`
+	Version      int64    `bson:"version"`
+}
+
+// ReorderWishesRequest новый порядок желаний стримера. Список должен содержать все его желания,
+// кроме завершённых и удалённых; их можно не передавать
+type ReorderWishesRequest struct {
+	WishUUIDs []string `json:"wish_uuids"`
+	UserUUID  string   `json:"-"`
+}
+
+// DeleteWishResponse результат удаления желания. Желание из контракта удаляется вызовом релеера,
+// и статус deleted оно получает после события WishDeleted
+type DeleteWishResponse struct {
+	Status      string                      `json:"status"`
+	Transaction *RelayedTransactionResponse `json:"transaction,omitempty"`
+}
+
 type WishResponse struct {
 	UUID        string  `json:"uuid"`
+	OnchainID   uint64  `json:"onchain_id"`
 	WishURL     *string `json:"wish_url,omitempty"`
 	Name        string  `json:"name"`
 	Description *string `json:"description,omitempty"`
 	Image       string  `json:"image"`
-	PolTarget   float64 `json:"pol_target"`
-	PolAmount   float64 `json:"pol_amount"`
+	PolTarget   Amount  `json:"pol_target"`
+	PolAmount   Amount  `json:"pol_amount"`
 	IsPriority  bool    `json:"is_priority"`
+	Position    int     `json:"position"`
+	Status      string  `json:"status"` // в публичном списке active или funded, если цель уже набрана
 }
 
 type GetWishesResponse struct {
 	Wishes []WishResponse `json:"wishes"`
 }
+
+// OwnerWishResponse желание в списке стримера: все статусы, прогресс сбора и транзакции контракта.
+// Время активации, завершения и удаления — время обработки соответствующего события индексатором
+type OwnerWishResponse struct {
+	WishResponse
+	Progress     float64                   `json:"progress"` // набранная доля целевой суммы в процентах
+	ActivatedAt  *time.Time                `json:"activated_at,omitempty"`
+	CompletedAt  *time.Time                `json:"completed_at,omitempty"`
+	DeletedAt    *time.Time                `json:"deleted_at,omitempty"`
+	Transactions []WishTransactionResponse `json:"transactions"`
+	CreatedAt    time.Time                 `json:"created_at"`
+	UpdatedAt    time.Time                 `json:"updated_at"`
+}
+
+// WishTransactionResponse транзакция контракта, изменившая статус желания
+type WishTransactionResponse struct {
+	Event       string    `json:"event"` // WishAdded, WishCompleted, WishDeleted
+	TxHash      string    `json:"tx_hash"`
+	BlockNumber uint64    `json:"block_number"`
+	ProcessedAt time.Time `json:"processed_at"`
+}
+
+type GetOwnerWishesResponse struct {
+	Page     int                 `json:"page"`
+	PageSize int                 `json:"page_size"`
+	Total    int64               `json:"total"`
+	Wishes   []OwnerWishResponse `json:"wishes"`
+}
+
+// PriceMismatchResponse желание, цена которого в контракте не совпала с целевой суммой в приложении
+type PriceMismatchResponse struct {
+	UUID         string `json:"uuid"`
+	OnchainID    uint64 `json:"onchain_id"`
+	Name         string `json:"name"`
+	PolTarget    Amount `json:"pol_target"`
+	OnchainPrice Amount `json:"onchain_price"`
+}
+
+type GetPriceMismatchesResponse struct {
+	Wishes []PriceMismatchResponse `json:"wishes"`
+}
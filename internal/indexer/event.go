@@ -0,0 +1,118 @@
+package indexer
+
+import (
+	"backend/internal/entity"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// errDecode означает, что данные лога не соответствуют ABI. Такой лог не обрабатывается повторно
+var errDecode = errors.New("данные события не соответствуют ABI")
+
+// Event декодированное событие контракта, передаваемое обработчикам
+type Event struct {
+	Name string
+	Log  types.Log
+	// Record будет сохранён в blockchain_events после обработки
+	Record *entity.BlockchainEvent
+	// Backfill выставляется при исторической загрузке (например, чтобы не отправлять уведомления о старых донатах)
+	Backfill bool
+
+	abi *abi.ABI
+}
+
+// Unpack декодирует неиндексированные аргументы события в структуру
+func (e *Event) Unpack(v interface{}) error {
+	if err := e.abi.UnpackIntoInterface(v, e.Name, e.Log.Data); err != nil {
+		return fmt.Errorf("%w: ошибка декодирования %s: %v", errDecode, e.Name, err)
+	}
+	return nil
+}
+
+// EventID возвращает детерминированный идентификатор лога: хеш транзакции и индекс лога в блоке
+func EventID(vLog types.Log) string {
+	return fmt.Sprintf("%s-%d", vLog.TxHash.Hex(), vLog.Index)
+}
+
+// decodeEventPayload декодирует неиндексированные аргументы и индексированные топики события
+// в документ, пригодный для сохранения в MongoDB
+func decodeEventPayload(event *abi.Event, vLog types.Log) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	if err := event.Inputs.UnpackIntoMap(values, vLog.Data); err != nil {
+		return nil, err
+	}
+	payload := make(map[string]interface{}, len(event.Inputs))
+	for name, value := range values {
+		payload[name] = normalizePayloadValue(reflect.ValueOf(value))
+	}
+	topicIndex := 1
+	for _, input := range event.Inputs {
+		if !input.Indexed {
+			continue
+		}
+		if topicIndex < len(vLog.Topics) {
+			// Индексированные динамические типы (string, bytes, tuple) доступны только в виде хеша
+			payload[input.Name] = vLog.Topics[topicIndex].Hex()
+		}
+		topicIndex++
+	}
+	return payload, nil
+}
+
+// normalizePayloadValue приводит значения, декодированные из ABI, к типам, которые корректно сохраняются в BSON.
+// Большие числа сохраняются строками, чтобы не терять точность
+func normalizePayloadValue(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	switch value := v.Interface().(type) {
+	case *big.Int:
+		if value == nil {
+			return nil
+		}
+		return value.String()
+	case common.Address:
+		return value.Hex()
+	case common.Hash:
+		return value.Hex()
+	case []byte:
+		return common.Bytes2Hex(value)
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return normalizePayloadValue(v.Elem())
+	case reflect.Struct:
+		result := make(map[string]interface{}, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			name := field.Tag.Get("json")
+			if name == "" {
+				name = field.Name
+			}
+			result[name] = normalizePayloadValue(v.Field(i))
+		}
+		return result
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Array && v.Type().Elem().Kind() == reflect.Uint8 {
+			bytes := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(bytes), v)
+			return common.Bytes2Hex(bytes)
+		}
+		result := make([]interface{}, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			result[i] = normalizePayloadValue(v.Index(i))
+		}
+		return result
+	default:
+		return v.Interface()
+	}
+}
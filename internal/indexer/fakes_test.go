@@ -0,0 +1,748 @@
+package indexer
+
+import (
+	"backend/internal/abi"
+	"backend/internal/entity"
+	"backend/internal/repo"
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ethabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// donatesABI разбирает встроенный ABI контракта, с которым работает индексатор
+func donatesABI(t *testing.T) ethabi.ABI {
+	t.Helper()
+	contractABI, err := ethabi.JSON(strings.NewReader(abi.DonatesABI))
+	if err != nil {
+		t.Fatalf("ошибка разбора ABI: %v", err)
+	}
+	return contractABI
+}
+
+// eventLog кодирует лог события так, как его выпускает контракт: индексированные строки — keccak256 в топиках,
+// остальные аргументы — в data
+func eventLog(t *testing.T, contractABI ethabi.ABI, name string, indexed []string, args ...interface{}) types.Log {
+	t.Helper()
+	event, ok := contractABI.Events[name]
+	if !ok {
+		t.Fatalf("событие %s не найдено в ABI", name)
+	}
+	data, err := event.Inputs.NonIndexed().Pack(args...)
+	if err != nil {
+		t.Fatalf("ошибка кодирования %s: %v", name, err)
+	}
+	topics := []common.Hash{event.ID}
+	for _, value := range indexed {
+		topics = append(topics, crypto.Keccak256Hash([]byte(value)))
+	}
+	return types.Log{
+		Topics:      topics,
+		Data:        data,
+		BlockNumber: 100,
+		TxHash:      crypto.Keccak256Hash([]byte(name), data),
+	}
+}
+
+// newTestEvent собирает событие так же, как Indexer.processLog
+func newTestEvent(t *testing.T, contractABI *ethabi.ABI, vLog types.Log) *Event {
+	t.Helper()
+	abiEvent, err := contractABI.EventByID(vLog.Topics[0])
+	if err != nil {
+		t.Fatalf("событие не найдено в ABI: %v", err)
+	}
+	return &Event{
+		Name: abiEvent.Name,
+		Log:  vLog,
+		abi:  contractABI,
+		Record: &entity.BlockchainEvent{
+			ID:          EventID(vLog),
+			BlockNumber: vLog.BlockNumber,
+			EventType:   abiEvent.Name,
+		},
+	}
+}
+
+type fakeWishRepo struct {
+	mu     sync.Mutex
+	wishes map[string]*entity.Wish
+}
+
+func newFakeWishRepo(wishes ...*entity.Wish) *fakeWishRepo {
+	r := &fakeWishRepo{wishes: make(map[string]*entity.Wish)}
+	for _, wish := range wishes {
+		r.wishes[wish.UUID] = wish
+	}
+	return r
+}
+
+func (r *fakeWishRepo) get(uuid string) *entity.Wish {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	wish := *r.wishes[uuid]
+	return &wish
+}
+
+func (r *fakeWishRepo) Add(_ context.Context, wish *entity.Wish) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *wish
+	r.wishes[wish.UUID] = &stored
+	return wish.UUID, nil
+}
+
+func (r *fakeWishRepo) Update(_ context.Context, wish *entity.Wish) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.wishes[wish.UUID]; !ok {
+		return repo.ErrWishNotFound
+	}
+	stored := *wish
+	r.wishes[wish.UUID] = &stored
+	return nil
+}
+
+func (r *fakeWishRepo) UpdateDetails(_ context.Context, wish *entity.Wish, status string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.wishes[wish.UUID]
+	if !ok {
+		return repo.ErrWishNotFound
+	}
+	if stored.Status != status {
+		return repo.ErrWishStatusChanged
+	}
+	stored.Name, stored.Description, stored.WishURL = wish.Name, wish.Description, wish.WishURL
+	stored.PolTarget, stored.Image, stored.IsPriority = wish.PolTarget, wish.Image, wish.IsPriority
+	return nil
+}
+
+func (r *fakeWishRepo) GetOrder(_ context.Context, streamerUUID string) (*entity.WishOrder, error) {
+	return &entity.WishOrder{StreamerUUID: streamerUUID}, nil
+}
+
+func (r *fakeWishRepo) Reorder(_ context.Context, streamerUUID string, uuids []string, _ int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for position, uuid := range uuids {
+		if wish, ok := r.wishes[uuid]; ok && wish.StreamerUUID == streamerUUID {
+			wish.Position = position
+		}
+	}
+	return nil
+}
+
+func (r *fakeWishRepo) GetByUUID(_ context.Context, uuid string) (*entity.Wish, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	wish, ok := r.wishes[uuid]
+	if !ok {
+		return nil, repo.ErrWishNotFound
+	}
+	copied := *wish
+	return &copied, nil
+}
+
+func (r *fakeWishRepo) GetByStreamerUUID(_ context.Context, streamerUUID string) ([]*entity.Wish, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var wishes []*entity.Wish
+	for _, wish := range r.wishes {
+		if wish.StreamerUUID == streamerUUID {
+			copied := *wish
+			wishes = append(wishes, &copied)
+		}
+	}
+	sort.Slice(wishes, func(i, j int) bool { return wishes[i].OnchainID < wishes[j].OnchainID })
+	return wishes, nil
+}
+
+func (r *fakeWishRepo) GetPageByStreamerUUID(ctx context.Context, streamerUUID string, statuses []string, page, pageSize int) ([]*entity.Wish, int64, error) {
+	all, _ := r.GetByStreamerUUID(ctx, streamerUUID)
+	var wishes []*entity.Wish
+	for _, wish := range all {
+		if len(statuses) == 0 || slices.Contains(statuses, wish.Status) {
+			wishes = append(wishes, wish)
+		}
+	}
+	from := min((page-1)*pageSize, len(wishes))
+	return wishes[from:min(from+pageSize, len(wishes))], int64(len(wishes)), nil
+}
+
+func (r *fakeWishRepo) GetByOnchainID(_ context.Context, onchainID uint64) (*entity.Wish, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, wish := range r.wishes {
+		if wish.OnchainID == onchainID {
+			copied := *wish
+			return &copied, nil
+		}
+	}
+	return nil, repo.ErrWishNotFound
+}
+
+func (r *fakeWishRepo) UpdateStatus(_ context.Context, uuid, from, to string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	wish, ok := r.wishes[uuid]
+	if !ok {
+		return repo.ErrWishNotFound
+	}
+	if wish.Status != from {
+		return repo.ErrWishStatusChanged
+	}
+	wish.Status = to
+	return nil
+}
+
+func (r *fakeWishRepo) MarkPriceMismatch(_ context.Context, uuid string, onchainPrice entity.Amount) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	wish, ok := r.wishes[uuid]
+	if !ok {
+		return repo.ErrWishNotFound
+	}
+	if wish.Status != "pending" {
+		return repo.ErrWishStatusChanged
+	}
+	wish.Status = "mismatch"
+	wish.OnchainPrice = &onchainPrice
+	return nil
+}
+
+func (r *fakeWishRepo) AcceptOnchainPrice(_ context.Context, uuid string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	wish, ok := r.wishes[uuid]
+	if !ok {
+		return repo.ErrWishNotFound
+	}
+	if wish.Status != "mismatch" || wish.OnchainPrice == nil {
+		return repo.ErrWishStatusChanged
+	}
+	wish.PolTarget = *wish.OnchainPrice
+	wish.OnchainPrice = nil
+	wish.Status = "active"
+	return nil
+}
+
+func (r *fakeWishRepo) RepairCredits(_ context.Context, uuid string, stored entity.Amount, paymentIDs []string, amount entity.Amount) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	wish, ok := r.wishes[uuid]
+	if !ok {
+		return repo.ErrWishNotFound
+	}
+	if wish.PolAmount.Cmp(stored) != 0 {
+		return repo.ErrWishStatusChanged
+	}
+	wish.CreditedPayments = paymentIDs
+	wish.PolAmount = amount
+	return nil
+}
+
+func (r *fakeWishRepo) CreditPayment(_ context.Context, uuid, paymentID string, amount entity.Amount) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	wish, ok := r.wishes[uuid]
+	if !ok {
+		return repo.ErrWishNotFound
+	}
+	for _, id := range wish.CreditedPayments {
+		if id == paymentID {
+			return nil
+		}
+	}
+	wish.PolAmount = wish.PolAmount.Add(amount)
+	wish.CreditedPayments = append(wish.CreditedPayments, paymentID)
+	return nil
+}
+
+func (r *fakeWishRepo) RevertPayment(_ context.Context, uuid, paymentID string, amount entity.Amount) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	wish, ok := r.wishes[uuid]
+	if !ok {
+		return repo.ErrWishNotFound
+	}
+	for i, id := range wish.CreditedPayments {
+		if id == paymentID {
+			wish.PolAmount = wish.PolAmount.Sub(amount)
+			wish.CreditedPayments = append(wish.CreditedPayments[:i], wish.CreditedPayments[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (r *fakeWishRepo) SetRelayHolder(_ context.Context, uuid, relayer string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	wish, ok := r.wishes[uuid]
+	if !ok {
+		return repo.ErrWishNotFound
+	}
+	wish.RelayedBy = relayer
+	wish.RelayReleased = false
+	return nil
+}
+
+func (r *fakeWishRepo) ReleaseRelayHolder(_ context.Context, uuid string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	wish, ok := r.wishes[uuid]
+	if !ok {
+		return repo.ErrWishNotFound
+	}
+	wish.RelayReleased = true
+	return nil
+}
+
+func (r *fakeWishRepo) SetContract(_ context.Context, uuid, contractAddress string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	wish, ok := r.wishes[uuid]
+	if !ok {
+		return repo.ErrWishNotFound
+	}
+	wish.ContractAddress = contractAddress
+	return nil
+}
+
+func (r *fakeWishRepo) CountRelayHeld(context.Context) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var count int64
+	for _, wish := range r.wishes {
+		if wish.RelayedBy != "" && !wish.RelayReleased {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *fakeWishRepo) ResetChainState(context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, wish := range r.wishes {
+		wish.Status = "pending"
+		wish.PolAmount = entity.Amount{}
+		wish.CreditedPayments = nil
+		wish.OnchainPrice = nil
+		wish.ContractAddress = ""
+	}
+	return nil
+}
+
+type fakeUserRepo struct {
+	mu    sync.Mutex
+	users map[string]*entity.User
+}
+
+func newFakeUserRepo(users ...*entity.User) *fakeUserRepo {
+	r := &fakeUserRepo{users: make(map[string]*entity.User)}
+	for _, user := range users {
+		r.users[user.UUID] = user
+	}
+	return r
+}
+
+func (r *fakeUserRepo) Register(_ context.Context, user *entity.User) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.users[user.UUID]; ok {
+		return "", repo.ErrUserAlreadyExists
+	}
+	stored := *user
+	r.users[user.UUID] = &stored
+	return user.UUID, nil
+}
+
+func (r *fakeUserRepo) Update(_ context.Context, user *entity.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.users[user.UUID]; !ok {
+		return repo.ErrUserNotFound
+	}
+	stored := *user
+	r.users[user.UUID] = &stored
+	return nil
+}
+
+func (r *fakeUserRepo) GetByUUID(_ context.Context, uuid string) (*entity.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[uuid]
+	if !ok {
+		return nil, repo.ErrUserNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *fakeUserRepo) GetByTelegramID(_ context.Context, telegramID string) (*entity.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if user.TelegramID == telegramID {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, repo.ErrUserNotFound
+}
+
+func (r *fakeUserRepo) GetByUUIDHash(_ context.Context, uuidHash string) (*entity.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if user.UUIDHash == uuidHash {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, repo.ErrUserNotFound
+}
+
+func (r *fakeUserRepo) GetWithoutUUIDHash(context.Context) ([]*entity.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var users []*entity.User
+	for _, user := range r.users {
+		if user.UUIDHash == "" {
+			copied := *user
+			users = append(users, &copied)
+		}
+	}
+	return users, nil
+}
+
+func (r *fakeUserRepo) GetWithWallet(context.Context) ([]*entity.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var users []*entity.User
+	for _, user := range r.users {
+		if user.PolygonWallet != "" {
+			copied := *user
+			users = append(users, &copied)
+		}
+	}
+	return users, nil
+}
+
+func (r *fakeUserRepo) ConfirmRegistration(_ context.Context, uuid string, blockNumber uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[uuid]
+	if !ok {
+		return repo.ErrUserNotFound
+	}
+	if !user.OnchainRegistered || blockNumber < user.RegistrationBlock {
+		user.RegistrationBlock = blockNumber
+	}
+	user.OnchainRegistered = true
+	return nil
+}
+
+func (r *fakeUserRepo) ResetRegistrations(_ context.Context, fromBlock uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if user.OnchainRegistered && user.RegistrationBlock >= fromBlock {
+			user.OnchainRegistered = false
+			user.RegistrationBlock = 0
+		}
+	}
+	return nil
+}
+
+type fakeHistoryRepo struct {
+	mu      sync.Mutex
+	history map[string]*entity.History
+}
+
+func newFakeHistoryRepo() *fakeHistoryRepo {
+	return &fakeHistoryRepo{history: make(map[string]*entity.History)}
+}
+
+func (r *fakeHistoryRepo) Add(_ context.Context, history *entity.History) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.history[history.ID]; ok {
+		return repo.ErrHistoryAlreadyExists
+	}
+	stored := *history
+	r.history[history.ID] = &stored
+	return nil
+}
+
+func (r *fakeHistoryRepo) GetByStreamerUUID(_ context.Context, streamerUUID string, _ int, _ int) ([]*entity.History, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*entity.History
+	for _, history := range r.history {
+		if history.StreamerUUID == streamerUUID {
+			copied := *history
+			result = append(result, &copied)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].BlockNumber < result[j].BlockNumber })
+	return result, nil
+}
+
+func (r *fakeHistoryRepo) GetFromBlock(_ context.Context, fromBlock uint64) ([]*entity.History, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*entity.History
+	for _, history := range r.history {
+		if history.BlockNumber >= fromBlock {
+			copied := *history
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeHistoryRepo) GetByWishUUID(_ context.Context, wishUUID string) ([]*entity.History, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*entity.History
+	for _, history := range r.history {
+		if history.Type == "donate" && history.WishUUID != nil && *history.WishUUID == wishUUID {
+			copied := *history
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeHistoryRepo) GetTotals(_ context.Context, streamerUUID string) (*entity.HistoryTotals, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	totals := &entity.HistoryTotals{}
+	for _, history := range r.history {
+		if history.StreamerUUID != streamerUUID {
+			continue
+		}
+		switch history.Type {
+		case "donate":
+			totals.Donated = totals.Donated.Add(history.Amount)
+			totals.Donations++
+			// Записи без зачисленной суммы проиндексированы до сохранения комиссии
+			if history.NetAmount.IsZero() {
+				totals.LegacyDonated = totals.LegacyDonated.Add(history.Amount)
+				totals.LegacyDonations++
+				continue
+			}
+			totals.DonatedNet = totals.DonatedNet.Add(history.NetAmount)
+			totals.Commission = totals.Commission.Add(history.Commission)
+		case "withdraw":
+			totals.Withdrawn = totals.Withdrawn.Add(history.Amount)
+		}
+	}
+	return totals, nil
+}
+
+func (r *fakeHistoryRepo) GetRevenueByDay(context.Context, time.Time, time.Time) ([]entity.RevenueEntry, error) {
+	return nil, nil
+}
+
+func (r *fakeHistoryRepo) GetRevenueByStreamer(context.Context, time.Time, time.Time) ([]entity.RevenueEntry, error) {
+	return nil, nil
+}
+
+func (r *fakeHistoryRepo) DeleteFromBlock(_ context.Context, fromBlock uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, history := range r.history {
+		if history.BlockNumber >= fromBlock {
+			delete(r.history, id)
+		}
+	}
+	return nil
+}
+
+type fakeDonationRepo struct {
+	mu     sync.Mutex
+	events []entity.DonationEvent
+}
+
+func (r *fakeDonationRepo) SendDonationEvent(_ context.Context, event entity.DonationEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, sent := range r.events {
+		if sent.UUID == event.UUID {
+			return nil
+		}
+	}
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *fakeDonationRepo) SubscribeDonationEvents(context.Context, string, string) (<-chan entity.DonationEvent, <-chan error) {
+	return nil, nil
+}
+
+type fakeBlockchainRepo struct {
+	mu        sync.Mutex
+	state     *entity.BlockchainState
+	backfill  *entity.BlockchainState
+	events    map[string]*entity.BlockchainEvent
+	anomalies map[string]*entity.BlockchainAnomaly
+}
+
+func newFakeBlockchainRepo() *fakeBlockchainRepo {
+	return &fakeBlockchainRepo{
+		events:    make(map[string]*entity.BlockchainEvent),
+		anomalies: make(map[string]*entity.BlockchainAnomaly),
+	}
+}
+
+func (r *fakeBlockchainRepo) GetLastProcessedBlock(context.Context) (uint64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.state == nil {
+		return 0, nil
+	}
+	return r.state.LastProcessedBlock, nil
+}
+
+func (r *fakeBlockchainRepo) GetCheckpoint(_ context.Context, contractAddress string) (uint64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.state == nil {
+		return 0, nil
+	}
+	return r.state.Checkpoint(contractAddress), nil
+}
+
+func (r *fakeBlockchainRepo) SaveLastProcessedBlock(_ context.Context, blockNumber uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.state == nil {
+		r.state = &entity.BlockchainState{}
+	}
+	r.state.LastProcessedBlock = blockNumber
+	return nil
+}
+
+func (r *fakeBlockchainRepo) GetState(context.Context) (*entity.BlockchainState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.state == nil {
+		return &entity.BlockchainState{}, nil
+	}
+	state := *r.state
+	return &state, nil
+}
+
+func (r *fakeBlockchainRepo) SaveState(_ context.Context, state *entity.BlockchainState) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *state
+	stored.RecentBlocks = append([]entity.ProcessedBlock(nil), state.RecentBlocks...)
+	r.state = &stored
+	return nil
+}
+
+func (r *fakeBlockchainRepo) GetBackfillState(context.Context) (*entity.BlockchainState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.backfill == nil {
+		return nil, nil
+	}
+	state := *r.backfill
+	return &state, nil
+}
+
+func (r *fakeBlockchainRepo) SaveBackfillState(_ context.Context, state *entity.BlockchainState) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *state
+	r.backfill = &stored
+	return nil
+}
+
+func (r *fakeBlockchainRepo) DeleteBackfillState(context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.backfill = nil
+	return nil
+}
+
+func (r *fakeBlockchainRepo) SaveEvent(_ context.Context, event *entity.BlockchainEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.events[event.ID]; ok {
+		return repo.ErrEventAlreadyExists
+	}
+	stored := *event
+	r.events[event.ID] = &stored
+	return nil
+}
+
+func (r *fakeBlockchainRepo) EventExists(_ context.Context, id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.events[id]
+	return ok, nil
+}
+
+func (r *fakeBlockchainRepo) GetEvents(_ context.Context, fromBlock, toBlock uint64) ([]*entity.BlockchainEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var events []*entity.BlockchainEvent
+	for _, event := range r.events {
+		if event.BlockNumber >= fromBlock && event.BlockNumber <= toBlock {
+			copied := *event
+			events = append(events, &copied)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].BlockNumber != events[j].BlockNumber {
+			return events[i].BlockNumber < events[j].BlockNumber
+		}
+		return events[i].LogIndex < events[j].LogIndex
+	})
+	return events, nil
+}
+
+func (r *fakeBlockchainRepo) GetWishEvents(_ context.Context, wishUUIDs []string) ([]*entity.BlockchainEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var events []*entity.BlockchainEvent
+	for _, event := range r.events {
+		if event.Applied && slices.Contains(wishUUIDs, event.WishUUID) && strings.HasPrefix(event.EventType, "Wish") {
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].BlockNumber < events[j].BlockNumber })
+	return events, nil
+}
+
+func (r *fakeBlockchainRepo) DeleteEventsFromBlock(_ context.Context, fromBlock uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, event := range r.events {
+		if event.BlockNumber >= fromBlock {
+			delete(r.events, id)
+		}
+	}
+	return nil
+}
+
+func (r *fakeBlockchainRepo) SaveAnomaly(_ context.Context, anomaly *entity.BlockchainAnomaly) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *anomaly
+	r.anomalies[anomaly.ID] = &stored
+	return nil
+}
@@ -0,0 +1,759 @@
+package indexer
+
+import (
+	"backend/internal/contract"
+	"backend/internal/entity"
+	"backend/internal/repo"
+	"backend/pkg/ethrpc"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// maxRecentBlocks количество хранимых хешей обработанных блоков для обнаружения реорганизаций
+	maxRecentBlocks = 128
+	// maxBlockRange максимальный диапазон блоков в одном запросе логов
+	maxBlockRange = 50000
+	// confirmationPollInterval интервал опроса, пока полученный по подписке лог ждёт подтверждений
+	confirmationPollInterval = 2 * time.Second
+)
+
+// HandlerFunc обрабатывает декодированное событие контракта.
+// Обработчик заполняет event.Record (UserUUID, WishUUID, Applied), запись сохраняется индексатором после обработки
+type HandlerFunc func(ctx context.Context, event *Event) error
+
+// RollbackFunc отменяет изменения, внесённые событиями начиная с блока fromBlock.
+// events — сохранённые события из откатываемых блоков в порядке их обработки
+type RollbackFunc func(ctx context.Context, fromBlock uint64, events []*entity.BlockchainEvent) error
+
+// HookFunc вызывается на этапах жизненного цикла индексатора (запуск, сброс перед исторической загрузкой)
+type HookFunc func(ctx context.Context) error
+
+// Config параметры индексатора
+type Config struct {
+	PollInterval  time.Duration
+	Confirmations uint64
+	// Subscribe включает подписку eth_subscribe на логи контракта (только для ws:// и wss:// RPC).
+	// Опрос с PollInterval при этом продолжает работать и подстраховывает подписку при обрывах
+	Subscribe bool
+}
+
+// Indexer читает логи деплоев контракта, передаёт события зарегистрированным обработчикам
+// и хранит состояние синхронизации с блокчейном
+type Indexer struct {
+	client         ethrpc.Client
+	deployments    []contract.Deployment
+	blockchainRepo repo.BlockchainRepository
+	config         Config
+
+	handlers  map[string]HandlerFunc
+	rollbacks []RollbackFunc
+	resets    []HookFunc
+	onStart   []HookFunc
+
+	mu      sync.Mutex
+	running bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	// Состояние синхронизации используется только из горутины цикла (или из Backfill при остановленном цикле)
+	lastBlock    uint64
+	recentBlocks []entity.ProcessedBlock
+	// checkpoints последний обработанный блок каждого деплоя по адресу. Деплой, которого нет в карте,
+	// считается обработанным до lastBlock (состояние до появления реестра контрактов)
+	checkpoints map[string]uint64
+	backfilling bool
+}
+
+func New(
+	client ethrpc.Client,
+	deployments []contract.Deployment,
+	blockchainRepo repo.BlockchainRepository,
+	config Config,
+) *Indexer {
+	if config.PollInterval <= 0 {
+		config.PollInterval = 15 * time.Second
+	}
+	return &Indexer{
+		client:         client,
+		deployments:    deployments,
+		blockchainRepo: blockchainRepo,
+		config:         config,
+		handlers:       make(map[string]HandlerFunc),
+		checkpoints:    make(map[string]uint64),
+	}
+}
+
+// Handle регистрирует обработчик события контракта по его имени в ABI
+func (ix *Indexer) Handle(eventName string, handler HandlerFunc) {
+	ix.handlers[eventName] = handler
+}
+
+// OnRollback регистрирует функцию отката изменений при реорганизации цепочки
+func (ix *Indexer) OnRollback(fn RollbackFunc) {
+	ix.rollbacks = append(ix.rollbacks, fn)
+}
+
+// OnReset регистрирует функцию сброса состояния перед исторической загрузкой
+func (ix *Indexer) OnReset(fn HookFunc) {
+	ix.resets = append(ix.resets, fn)
+}
+
+// OnStart регистрирует функцию подготовки, выполняемую перед запуском мониторинга и исторической загрузкой
+func (ix *Indexer) OnStart(fn HookFunc) {
+	ix.onStart = append(ix.onStart, fn)
+}
+
+// Start запускает фоновое отслеживание событий блокчейна
+func (ix *Indexer) Start(ctx context.Context) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	if ix.running {
+		return fmt.Errorf("мониторинг блокчейна уже запущен")
+	}
+
+	ix.runStartHooks(ctx)
+
+	// Получаем последний обработанный блок из БД или текущий блок
+	state, err := ix.blockchainRepo.GetState(ctx)
+	if err != nil {
+		log.Printf("Ошибка получения последнего обработанного блока: %v", err)
+		// Если нет записи, начинаем с текущего блока
+		header, err := ix.client.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("ошибка получения текущего блока: %w", err)
+		}
+		ix.lastBlock = header.Number.Uint64()
+		ix.recentBlocks = nil
+		ix.checkpoints = make(map[string]uint64)
+	} else {
+		ix.lastBlock = state.LastProcessedBlock
+		ix.recentBlocks = state.RecentBlocks
+		ix.restoreCheckpoints(state.Checkpoints)
+	}
+
+	loopCtx, cancel := context.WithCancel(ctx)
+	ix.cancel = cancel
+	ix.done = make(chan struct{})
+	ix.running = true
+
+	log.Printf("Запускаем мониторинг событий смарт-контракта с блока %d (подтверждений: %d, интервал: %s, подписка: %t)",
+		ix.lastBlock, ix.config.Confirmations, ix.config.PollInterval, ix.config.Subscribe)
+
+	go ix.loop(loopCtx, ix.done)
+	return nil
+}
+
+// Stop останавливает фоновое отслеживание. Для ожидания завершения используйте Wait
+func (ix *Indexer) Stop() {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	if !ix.running {
+		return
+	}
+	ix.cancel()
+	ix.running = false
+	log.Println("Мониторинг событий блокчейна остановлен")
+}
+
+// Wait блокируется до завершения цикла мониторинга
+func (ix *Indexer) Wait() {
+	ix.mu.Lock()
+	done := ix.done
+	ix.mu.Unlock()
+
+	if done != nil {
+		<-done
+	}
+}
+
+// Run запускает мониторинг и останавливает его при отмене ctx
+func (ix *Indexer) Run(ctx context.Context) error {
+	if err := ix.Start(ctx); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	ix.Stop()
+	ix.Wait()
+	return nil
+}
+
+// IsRunning сообщает, запущен ли мониторинг
+func (ix *Indexer) IsRunning() bool {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return ix.running
+}
+
+// loop основной цикл отслеживания событий блокчейна.
+// Блоки обрабатываются по таймеру, а при включённой подписке — сразу после подтверждения блока с новым логом
+func (ix *Indexer) loop(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(ix.config.PollInterval)
+	defer ticker.Stop()
+
+	var (
+		sub         ethereum.Subscription
+		logsCh      chan types.Log
+		subErr      <-chan error
+		resubscribe <-chan time.Time
+		confirm     <-chan time.Time
+		// awaitBlock последний блок, в котором по подписке пришёл лог, ещё не обработанный индексатором
+		awaitBlock uint64
+	)
+	subscribe := func() {
+		var err error
+		logsCh = make(chan types.Log, 128)
+		var addresses []common.Address
+		for _, deployment := range ix.deployments {
+			if !deployment.Retired() {
+				addresses = append(addresses, deployment.Address)
+			}
+		}
+		if len(addresses) == 0 {
+			// Без адресов подписка получала бы логи всех контрактов сети
+			log.Println("⚠️ В реестре нет действующего контракта, подписка на логи не нужна")
+			return
+		}
+		sub, err = ix.client.SubscribeFilterLogs(ctx, ethereum.FilterQuery{Addresses: addresses}, logsCh)
+		if err != nil {
+			log.Printf("⚠️ Ошибка подписки на логи контракта, продолжаем опрос каждые %s: %v", ix.config.PollInterval, err)
+			sub, logsCh, subErr = nil, nil, nil
+			resubscribe = time.After(ix.config.PollInterval)
+			return
+		}
+		subErr = sub.Err()
+		resubscribe = nil
+		log.Println("Подписка на логи контракта установлена")
+		// Заполняем пропуск между последним обработанным блоком и моментом подписки
+		if err := ix.processNewBlocks(ctx); err != nil {
+			log.Printf("Ошибка обработки новых блоков: %v", err)
+		}
+	}
+	process := func() {
+		if err := ix.processNewBlocks(ctx); err != nil {
+			log.Printf("Ошибка обработки новых блоков: %v", err)
+		}
+		if awaitBlock > ix.lastBlock {
+			confirm = time.After(confirmationPollInterval)
+		} else {
+			confirm = nil
+		}
+	}
+
+	if ix.config.Subscribe {
+		subscribe()
+	}
+	defer func() {
+		if sub != nil {
+			sub.Unsubscribe()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Контекст отменен, останавливаем мониторинг блокчейна")
+			return
+		case <-ticker.C:
+			process()
+		case vLog := <-logsCh:
+			if vLog.Removed {
+				// Откат реорганизаций выполняется по хешам сохранённых блоков
+				continue
+			}
+			if vLog.BlockNumber > awaitBlock {
+				awaitBlock = vLog.BlockNumber
+			}
+			process()
+		case <-confirm:
+			process()
+		case err := <-subErr:
+			log.Printf("⚠️ Подписка на логи контракта прервана, переходим на опрос каждые %s: %v", ix.config.PollInterval, err)
+			sub.Unsubscribe()
+			sub, logsCh, subErr = nil, nil, nil
+			resubscribe = time.After(ix.config.PollInterval)
+		case <-resubscribe:
+			subscribe()
+		}
+	}
+}
+
+func (ix *Indexer) runStartHooks(ctx context.Context) {
+	for _, hook := range ix.onStart {
+		if err := hook(ctx); err != nil {
+			log.Printf("Ошибка подготовки индексатора: %v", err)
+		}
+	}
+}
+
+// processNewBlocks обрабатывает новые блоки и извлекает события.
+// Обрабатываются только блоки, получившие не менее Confirmations подтверждений
+func (ix *Indexer) processNewBlocks(ctx context.Context) error {
+	header, err := ix.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка получения текущего блока: %w", err)
+	}
+
+	headBlock := header.Number.Uint64()
+	if headBlock <= ix.config.Confirmations {
+		return nil
+	}
+	currentBlock := headBlock - ix.config.Confirmations
+
+	// Если lastBlock == 0 или 1, считаем, что только что задеплоили контракт и все старые блоки не нужны
+	if ix.lastBlock == 0 || ix.lastBlock == 1 {
+		for _, deployment := range ix.deployments {
+			ix.checkpoints[deployment.Address.Hex()] = clipToEnd(deployment, currentBlock)
+		}
+		if err := ix.saveCheckpoint(ctx, currentBlock); err != nil {
+			log.Printf("Ошибка сохранения последнего обработанного блока: %v", err)
+		}
+		log.Printf("Пропускаем обработку старых блоков, выставляем lastBlock = %d", currentBlock)
+		return nil
+	}
+
+	if err := ix.detectReorg(ctx); err != nil {
+		return fmt.Errorf("ошибка проверки реорганизации: %w", err)
+	}
+
+	// Деплой, добавленный в реестр позже, догоняет цепочку со своего блока деплоя
+	fromBlock := ix.nextBlock(ix.lastBlock, currentBlock)
+	if fromBlock > currentBlock {
+		return nil // Нет новых подтверждённых блоков
+	}
+
+	log.Printf("Обрабатываем блоки с %d по %d", fromBlock, currentBlock)
+
+	// После каждого чанка сохраняем последний обработанный блок вместе с его хешем
+	return ix.processBlockRange(ctx, fromBlock, currentBlock, func(end uint64) error {
+		if err := ix.saveCheckpoint(ctx, end); err != nil {
+			return fmt.Errorf("ошибка сохранения последнего обработанного блока: %w", err)
+		}
+		return nil
+	})
+}
+
+// processBlockRange читает логи деплоев чанками по maxBlockRange блоков и вызывает onChunk после каждого чанка.
+// Логи деплоя запрашиваются только для блоков после его контрольной точки и внутри его диапазона
+func (ix *Indexer) processBlockRange(ctx context.Context, fromBlock, toBlock uint64, onChunk func(end uint64) error) error {
+	for from := fromBlock; from <= toBlock; from += maxBlockRange {
+		end := from + maxBlockRange - 1
+		if end > toBlock {
+			end = toBlock
+		}
+
+		var addresses []common.Address
+		for _, deployment := range ix.deployments {
+			if ix.checkpoint(deployment) < end && deployment.StartBlock <= end && (!deployment.Retired() || deployment.EndBlock >= from) {
+				addresses = append(addresses, deployment.Address)
+			}
+		}
+		// Пустой список адресов в eth_getLogs означает логи всех контрактов
+		if len(addresses) > 0 {
+			query := ethereum.FilterQuery{
+				FromBlock: new(big.Int).SetUint64(from),
+				ToBlock:   new(big.Int).SetUint64(end),
+				Addresses: addresses,
+			}
+			logs, err := ix.client.FilterLogs(ctx, query)
+			if err != nil {
+				return fmt.Errorf("ошибка получения логов: %w", err)
+			}
+			// Ошибка обработчика (например, недоступность БД) останавливает чанк до сохранения прогресса,
+			// чтобы лог был обработан повторно; уже сохранённые события чанка при повторе пропускаются
+			for _, vLog := range logs {
+				deployment, ok := ix.deployment(vLog.Address)
+				if !ok || vLog.BlockNumber <= ix.checkpoint(deployment) || !deployment.Covers(vLog.BlockNumber) {
+					continue
+				}
+				if err := ix.processLog(ctx, vLog); err != nil {
+					return fmt.Errorf("ошибка обработки лога %s: %w", EventID(vLog), err)
+				}
+			}
+		}
+
+		for _, deployment := range ix.deployments {
+			if checkpoint := ix.checkpoint(deployment); checkpoint < end {
+				ix.checkpoints[deployment.Address.Hex()] = max(checkpoint, clipToEnd(deployment, end))
+			}
+		}
+		if err := onChunk(end); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deployment возвращает деплой по адресу контракта из лога
+func (ix *Indexer) deployment(address common.Address) (contract.Deployment, bool) {
+	for _, deployment := range ix.deployments {
+		if deployment.Address == address {
+			return deployment, true
+		}
+	}
+	return contract.Deployment{}, false
+}
+
+// checkpoint возвращает последний обработанный блок деплоя. Для деплоя без контрольной точки
+// это lastBlock, но не раньше блока деплоя и не позже EndBlock
+func (ix *Indexer) checkpoint(deployment contract.Deployment) uint64 {
+	if block, ok := ix.checkpoints[deployment.Address.Hex()]; ok {
+		return block
+	}
+	return clipToEnd(deployment, max(ix.lastBlock, beforeStart(deployment)))
+}
+
+// nextBlock возвращает первый необработанный блок: следующий после lastBlock или раньше,
+// если какой-то деплой ещё не обработан до toBlock
+func (ix *Indexer) nextBlock(lastBlock, toBlock uint64) uint64 {
+	next := lastBlock + 1
+	for _, deployment := range ix.deployments {
+		if checkpoint := ix.checkpoint(deployment); checkpoint < clipToEnd(deployment, toBlock) && checkpoint+1 < next {
+			next = checkpoint + 1
+		}
+	}
+	return next
+}
+
+// restoreCheckpoints загружает сохранённые контрольные точки. Деплой, появившийся в реестре после сохранения,
+// обрабатывается с его блока деплоя; состояние без контрольных точек сохранено до появления реестра
+func (ix *Indexer) restoreCheckpoints(saved map[string]uint64) {
+	ix.checkpoints = make(map[string]uint64)
+	if saved == nil {
+		return
+	}
+	for _, deployment := range ix.deployments {
+		key := deployment.Address.Hex()
+		if block, ok := saved[key]; ok {
+			ix.checkpoints[key] = block
+		} else {
+			ix.checkpoints[key] = beforeStart(deployment)
+		}
+	}
+}
+
+// beforeStart возвращает блок, предшествующий блоку деплоя
+func beforeStart(deployment contract.Deployment) uint64 {
+	if deployment.StartBlock == 0 {
+		return 0
+	}
+	return deployment.StartBlock - 1
+}
+
+// clipToEnd ограничивает блок последним индексируемым блоком выведенного из работы деплоя
+func clipToEnd(deployment contract.Deployment, block uint64) uint64 {
+	if deployment.Retired() && block > deployment.EndBlock {
+		return deployment.EndBlock
+	}
+	return block
+}
+
+// processLog обрабатывает отдельный лог события.
+// Каждое декодированное событие сохраняется в blockchain_events под детерминированным ID,
+// поэтому повторная обработка того же лога (после падения или ручной перемотки) пропускается
+func (ix *Indexer) processLog(ctx context.Context, vLog types.Log) error {
+	if len(vLog.Topics) == 0 {
+		return nil
+	}
+
+	deployment, ok := ix.deployment(vLog.Address)
+	if !ok {
+		return nil
+	}
+	abiEvent, err := deployment.ABI.EventByID(vLog.Topics[0])
+	if err != nil {
+		// Неизвестное событие, игнорируем
+		return nil
+	}
+
+	eventID := EventID(vLog)
+	exists, err := ix.blockchainRepo.EventExists(ctx, eventID)
+	if err != nil {
+		return fmt.Errorf("ошибка проверки обработанного события: %w", err)
+	}
+	if exists {
+		log.Printf("Событие %s (%s) уже обработано, пропускаем", abiEvent.Name, eventID)
+		return nil
+	}
+
+	payload, decodeErr := decodeEventPayload(abiEvent, vLog)
+	event := &Event{
+		Name:     abiEvent.Name,
+		Log:      vLog,
+		Backfill: ix.backfilling,
+		abi:      &deployment.ABI,
+		Record: &entity.BlockchainEvent{
+			ID:              eventID,
+			ContractAddress: vLog.Address.Hex(),
+			BlockNumber:     vLog.BlockNumber,
+			BlockHash:       vLog.BlockHash.Hex(),
+			TxHash:          vLog.TxHash.Hex(),
+			LogIndex:        vLog.Index,
+			EventType:       abiEvent.Name,
+			Payload:         payload,
+		},
+	}
+
+	err = nil
+	if decodeErr != nil {
+		err = fmt.Errorf("%w: %v", errDecode, decodeErr)
+	} else if handler, ok := ix.handlers[abiEvent.Name]; ok {
+		err = handler(ctx, event)
+	}
+	if err != nil {
+		if !errors.Is(err, errDecode) {
+			return err
+		}
+		// Лог, который не декодируется по ABI, не обработается и при повторе: фиксируем аномалию и идём дальше
+		if err := ix.saveDecodeAnomaly(ctx, event, err); err != nil {
+			return err
+		}
+	}
+
+	if err := ix.blockchainRepo.SaveEvent(ctx, event.Record); err != nil {
+		return fmt.Errorf("ошибка сохранения события %s: %w", abiEvent.Name, err)
+	}
+	return nil
+}
+
+// saveDecodeAnomaly сохраняет лог, который не удалось декодировать, в blockchain_anomalies
+func (ix *Indexer) saveDecodeAnomaly(ctx context.Context, event *Event, cause error) error {
+	log.Printf("⚠️ Событие %s в транзакции %s не декодировано: %v", event.Name, event.Log.TxHash.Hex(), cause)
+	event.Record.Applied = false
+	anomaly := &entity.BlockchainAnomaly{
+		ID:          event.Record.ID,
+		BlockNumber: event.Log.BlockNumber,
+		TxHash:      event.Log.TxHash.Hex(),
+		EventType:   event.Name,
+		Reason:      cause.Error(),
+	}
+	if err := ix.blockchainRepo.SaveAnomaly(ctx, anomaly); err != nil {
+		return fmt.Errorf("ошибка сохранения аномалии: %w", err)
+	}
+	return nil
+}
+
+// saveCheckpoint запоминает хеш нового обработанного блока и сохраняет состояние синхронизации.
+// Блоки до lastBlock обрабатываются, когда новый деплой догоняет цепочку, и их хеши уже сохранены
+func (ix *Indexer) saveCheckpoint(ctx context.Context, blockNumber uint64) error {
+	if blockNumber > ix.lastBlock {
+		if err := ix.rememberBlock(ctx, blockNumber); err != nil {
+			return err
+		}
+	}
+	return ix.saveState(ctx)
+}
+
+// rememberBlock добавляет хеш блока в список недавних и делает его последним обработанным
+func (ix *Indexer) rememberBlock(ctx context.Context, blockNumber uint64) error {
+	header, err := ix.client.HeaderByNumber(ctx, new(big.Int).SetUint64(blockNumber))
+	if err != nil {
+		return fmt.Errorf("ошибка получения блока %d: %w", blockNumber, err)
+	}
+	ix.recentBlocks = append(ix.recentBlocks, entity.ProcessedBlock{
+		Number: blockNumber,
+		Hash:   header.Hash().Hex(),
+	})
+	if len(ix.recentBlocks) > maxRecentBlocks {
+		ix.recentBlocks = ix.recentBlocks[len(ix.recentBlocks)-maxRecentBlocks:]
+	}
+	ix.lastBlock = blockNumber
+	return nil
+}
+
+// saveState сохраняет последний обработанный блок, хеши недавних блоков и контрольные точки деплоев в БД
+func (ix *Indexer) saveState(ctx context.Context) error {
+	return ix.blockchainRepo.SaveState(ctx, &entity.BlockchainState{
+		LastProcessedBlock: ix.lastBlock,
+		RecentBlocks:       ix.recentBlocks,
+		Checkpoints:        ix.snapshotCheckpoints(),
+	})
+}
+
+// snapshotCheckpoints возвращает контрольные точки всех деплоев для сохранения
+func (ix *Indexer) snapshotCheckpoints() map[string]uint64 {
+	checkpoints := make(map[string]uint64, len(ix.deployments))
+	for _, deployment := range ix.deployments {
+		checkpoints[deployment.Address.Hex()] = ix.checkpoint(deployment)
+	}
+	return checkpoints
+}
+
+// detectReorg сверяет хеши сохранённых блоков с канонической цепочкой.
+// При расхождении откатывает изменения до последнего общего блока
+func (ix *Indexer) detectReorg(ctx context.Context) error {
+	if len(ix.recentBlocks) == 0 {
+		return nil
+	}
+
+	var ancestor uint64
+	found := false
+	for i := len(ix.recentBlocks) - 1; i >= 0; i-- {
+		block := ix.recentBlocks[i]
+		header, err := ix.client.HeaderByNumber(ctx, new(big.Int).SetUint64(block.Number))
+		if err != nil {
+			return fmt.Errorf("ошибка получения блока %d: %w", block.Number, err)
+		}
+		if header.Hash().Hex() == block.Hash {
+			if i == len(ix.recentBlocks)-1 {
+				return nil // Реорганизации не было
+			}
+			ancestor = block.Number
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		oldest := ix.recentBlocks[0].Number
+		if oldest > 0 {
+			ancestor = oldest - 1
+		}
+		log.Printf("⚠️ Реорганизация глубже %d сохранённых блоков, откатываемся к блоку %d", len(ix.recentBlocks), ancestor)
+	}
+
+	log.Printf("⚠️ Обнаружена реорганизация цепочки: откатываем блоки после %d (последний обработанный %d)", ancestor, ix.lastBlock)
+	return ix.rollbackToBlock(ctx, ancestor)
+}
+
+// rollbackToBlock отменяет изменения из блоков после ancestor через зарегистрированные функции отката
+// и переставляет последний обработанный блок, чтобы каноническая цепочка была обработана заново
+func (ix *Indexer) rollbackToBlock(ctx context.Context, ancestor uint64) error {
+	fromBlock := ancestor + 1
+
+	events, err := ix.blockchainRepo.GetEvents(ctx, fromBlock, math.MaxInt64)
+	if err != nil {
+		return fmt.Errorf("ошибка получения событий для отката: %w", err)
+	}
+	for _, rollback := range ix.rollbacks {
+		if err := rollback(ctx, fromBlock, events); err != nil {
+			return err
+		}
+	}
+	if err := ix.blockchainRepo.DeleteEventsFromBlock(ctx, fromBlock); err != nil {
+		return fmt.Errorf("ошибка удаления событий: %w", err)
+	}
+
+	kept := ix.recentBlocks[:0]
+	for _, block := range ix.recentBlocks {
+		if block.Number <= ancestor {
+			kept = append(kept, block)
+		}
+	}
+	ix.recentBlocks = kept
+	for _, deployment := range ix.deployments {
+		ix.checkpoints[deployment.Address.Hex()] = min(ix.checkpoint(deployment), ancestor)
+	}
+	ix.lastBlock = ancestor
+
+	log.Printf("Откат завершён: отменено событий %d", len(events))
+	return ix.saveState(ctx)
+}
+
+// Backfill восстанавливает состояние, заново обрабатывая логи всех деплоев контракта начиная с их блоков деплоя.
+// Прогресс сохраняется после каждого чанка, поэтому прерванную загрузку можно продолжить повторным запуском.
+// Мониторинг блокчейна во время загрузки должен быть остановлен
+func (ix *Indexer) Backfill(ctx context.Context) error {
+	if ix.IsRunning() {
+		return errors.New("нельзя запускать историческую загрузку при работающем мониторинге")
+	}
+
+	ix.runStartHooks(ctx)
+
+	progress, err := ix.blockchainRepo.GetBackfillState(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка получения прогресса загрузки: %w", err)
+	}
+	if progress == nil {
+		progress = &entity.BlockchainState{Checkpoints: make(map[string]uint64)}
+		for i, deployment := range ix.deployments {
+			block := beforeStart(deployment)
+			progress.Checkpoints[deployment.Address.Hex()] = block
+			if i == 0 || block < progress.LastProcessedBlock {
+				progress.LastProcessedBlock = block
+			}
+		}
+		log.Printf("Начинаем историческую загрузку с блока %d, сбрасываем состояние", progress.LastProcessedBlock+1)
+		if err := ix.reset(ctx); err != nil {
+			return err
+		}
+		if err := ix.blockchainRepo.SaveBackfillState(ctx, progress); err != nil {
+			return fmt.Errorf("ошибка сохранения прогресса загрузки: %w", err)
+		}
+	} else {
+		log.Printf("Продолжаем историческую загрузку с блока %d", progress.LastProcessedBlock+1)
+	}
+	ix.lastBlock = progress.LastProcessedBlock
+	ix.restoreCheckpoints(progress.Checkpoints)
+
+	header, err := ix.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка получения текущего блока: %w", err)
+	}
+	headBlock := header.Number.Uint64()
+	if headBlock <= ix.config.Confirmations {
+		return errors.New("в цепочке недостаточно блоков для загрузки")
+	}
+	targetBlock := headBlock - ix.config.Confirmations
+
+	ix.backfilling = true
+	defer func() { ix.backfilling = false }()
+
+	if fromBlock := ix.nextBlock(ix.lastBlock, targetBlock); fromBlock <= targetBlock {
+		err = ix.processBlockRange(ctx, fromBlock, targetBlock, func(end uint64) error {
+			ix.lastBlock = max(ix.lastBlock, end)
+			progress.LastProcessedBlock = ix.lastBlock
+			progress.Checkpoints = ix.snapshotCheckpoints()
+			if err := ix.blockchainRepo.SaveBackfillState(ctx, progress); err != nil {
+				return fmt.Errorf("ошибка сохранения прогресса загрузки: %w", err)
+			}
+			log.Printf("Историческая загрузка: обработаны блоки до %d из %d", end, targetBlock)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Передаём управление обычному мониторингу с блока, на котором закончили
+	ix.recentBlocks = nil
+	if err := ix.rememberBlock(ctx, ix.lastBlock); err != nil {
+		return fmt.Errorf("ошибка сохранения последнего обработанного блока: %w", err)
+	}
+	if err := ix.saveState(ctx); err != nil {
+		return fmt.Errorf("ошибка сохранения последнего обработанного блока: %w", err)
+	}
+	if err := ix.blockchainRepo.DeleteBackfillState(ctx); err != nil {
+		return fmt.Errorf("ошибка удаления прогресса загрузки: %w", err)
+	}
+
+	log.Printf("Историческая загрузка завершена на блоке %d", ix.lastBlock)
+	return nil
+}
+
+// reset сбрасывает всё, что было получено из блокчейна, перед исторической загрузкой
+func (ix *Indexer) reset(ctx context.Context) error {
+	for _, reset := range ix.resets {
+		if err := reset(ctx); err != nil {
+			return err
+		}
+	}
+	if err := ix.blockchainRepo.DeleteEventsFromBlock(ctx, 0); err != nil {
+		return fmt.Errorf("ошибка очистки событий: %w", err)
+	}
+	return nil
+}
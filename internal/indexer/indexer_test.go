@@ -0,0 +1,234 @@
+package indexer
+
+import (
+	"backend/internal/contract"
+	"backend/internal/entity"
+	"backend/pkg/ethrpc"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// fakeClient отдаёт заранее заданные логи и заголовки блоков. Остальные методы ethrpc.Client не используются
+type fakeClient struct {
+	ethrpc.Client
+	head uint64
+	logs []types.Log
+}
+
+func (c *fakeClient) HeaderByNumber(_ context.Context, number *big.Int) (*types.Header, error) {
+	if number == nil {
+		number = new(big.Int).SetUint64(c.head)
+	}
+	return &types.Header{Number: new(big.Int).Set(number)}, nil
+}
+
+func (c *fakeClient) FilterLogs(_ context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	var logs []types.Log
+	for _, vLog := range c.logs {
+		if vLog.BlockNumber >= query.FromBlock.Uint64() && vLog.BlockNumber <= query.ToBlock.Uint64() && queriedAddress(query, vLog.Address) {
+			logs = append(logs, vLog)
+		}
+	}
+	return logs, nil
+}
+
+func queriedAddress(query ethereum.FilterQuery, address common.Address) bool {
+	if len(query.Addresses) == 0 {
+		return true
+	}
+	for _, queried := range query.Addresses {
+		if queried == address {
+			return true
+		}
+	}
+	return false
+}
+
+// testDeployments действующий деплой с нулевым адресом, как у логов из eventLog
+func testDeployments(contractABI abi.ABI, startBlock uint64) []contract.Deployment {
+	return []contract.Deployment{{ABI: contractABI, StartBlock: startBlock}}
+}
+
+func TestProcessNewBlocksRetriesFailedHandler(t *testing.T) {
+	ctx := context.Background()
+	contractABI := donatesABI(t)
+
+	first := eventLog(t, contractABI, "UserCreated", []string{"streamer"}, "first")
+	first.BlockNumber = 11
+	second := eventLog(t, contractABI, "UserCreated", []string{"streamer"}, "second")
+	second.BlockNumber = 12
+	client := &fakeClient{head: 20, logs: []types.Log{first, second}}
+
+	blockchainRepo := newFakeBlockchainRepo()
+	ix := New(client, testDeployments(contractABI, 0), blockchainRepo, Config{})
+	ix.lastBlock = 10
+
+	failing := true
+	var handled []string
+	ix.Handle("UserCreated", func(_ context.Context, event *Event) error {
+		if event.Log.BlockNumber == 12 && failing {
+			return errors.New("база недоступна")
+		}
+		handled = append(handled, event.Record.ID)
+		return nil
+	})
+
+	if err := ix.processNewBlocks(ctx); err == nil {
+		t.Fatal("ошибка обработчика не остановила обработку блоков")
+	}
+	if ix.lastBlock != 10 {
+		t.Fatalf("прогресс сохранён после ошибки обработчика: lastBlock=%d", ix.lastBlock)
+	}
+	if _, ok := blockchainRepo.events[EventID(second)]; ok {
+		t.Fatal("событие с ошибкой обработчика сохранено как обработанное")
+	}
+
+	failing = false
+	if err := ix.processNewBlocks(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if ix.lastBlock != 20 {
+		t.Fatalf("lastBlock=%d, ожидался 20", ix.lastBlock)
+	}
+	if len(handled) != 2 {
+		t.Fatalf("обработано событий %d, ожидалось 2 (первое не должно обрабатываться повторно)", len(handled))
+	}
+	if _, ok := blockchainRepo.events[EventID(second)]; !ok {
+		t.Fatal("событие не сохранено после повторной обработки")
+	}
+}
+
+func TestProcessLogRecordsUndecodableEvent(t *testing.T) {
+	ctx := context.Background()
+	contractABI := donatesABI(t)
+
+	broken := eventLog(t, contractABI, "WishAdded", []string{"streamer"}, big.NewInt(1), big.NewInt(1))
+	broken.Data = broken.Data[:10]
+
+	blockchainRepo := newFakeBlockchainRepo()
+	ix := New(&fakeClient{}, testDeployments(contractABI, 0), blockchainRepo, Config{})
+	ix.Handle("WishAdded", func(context.Context, *Event) error {
+		t.Fatal("обработчик вызван для недекодируемого лога")
+		return nil
+	})
+
+	if err := ix.processLog(ctx, broken); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := blockchainRepo.anomalies[EventID(broken)]; !ok {
+		t.Fatal("недекодируемый лог не записан как аномалия")
+	}
+	if _, ok := blockchainRepo.events[EventID(broken)]; !ok {
+		t.Fatal("недекодируемый лог будет обрабатываться повторно")
+	}
+}
+
+func TestBackfillRebuildsWishStateFromLogs(t *testing.T) {
+	ctx := context.Background()
+	contractABI := donatesABI(t)
+
+	streamer := &entity.User{UUID: "streamer", UUIDHash: entity.UUIDTopicHash("streamer"), PolygonWallet: streamerWallet.Hex()}
+	// Состояние в базе расходится с цепочкой: первое желание ошибочно завершено с лишней суммой, второе не удалено
+	wishRepo := newFakeWishRepo(
+		&entity.Wish{UUID: "wish-1", OnchainID: 7, StreamerUUID: "streamer", PolTarget: entity.POL(5), Status: "complete", PolAmount: entity.POL(99)},
+		&entity.Wish{UUID: "wish-2", OnchainID: 8, StreamerUUID: "streamer", PolTarget: entity.POL(1), Status: "active"},
+	)
+	historyRepo := newFakeHistoryRepo()
+	blockchainRepo := newFakeBlockchainRepo()
+	users := NewUserResolver(newFakeUserRepo(streamer), blockchainRepo)
+
+	logs := []types.Log{
+		eventLog(t, contractABI, "WishAdded", []string{"streamer"}, big.NewInt(7), big.NewInt(5e18)),
+		eventLog(t, contractABI, "WishAdded", []string{"streamer"}, big.NewInt(8), big.NewInt(1e18)),
+		paymentLog(t, contractABI, "streamer", streamerWallet, 7, big.NewInt(2e18), paymentTypeDonate),
+		eventLog(t, contractABI, "WishDeleted", []string{"streamer"}, big.NewInt(8), big.NewInt(0)),
+	}
+	for i := range logs {
+		logs[i].BlockNumber = uint64(5 + i)
+	}
+
+	ix := New(&fakeClient{head: 20, logs: logs}, testDeployments(contractABI, 3), blockchainRepo, Config{})
+	NewWishHandler(wishRepo, users).Register(ix)
+	NewPaymentHandler(wishRepo, historyRepo, &fakeDonationRepo{}, users).Register(ix)
+
+	// Повторная загрузка сбрасывает состояние и приходит к тому же результату
+	for run := 0; run < 2; run++ {
+		if err := ix.Backfill(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if wish := wishRepo.get("wish-1"); wish.Status != "active" || wish.PolAmount.Cmp(entity.POL(2)) != 0 {
+			t.Fatalf("запуск %d: wish-1 status=%s amount=%v, ожидалось active и 2", run, wish.Status, wish.PolAmount)
+		}
+		if status := wishRepo.get("wish-2").Status; status != "deleted" {
+			t.Fatalf("запуск %d: wish-2 status=%s, ожидался deleted", run, status)
+		}
+		if len(historyRepo.history) != 1 {
+			t.Fatalf("запуск %d: записей истории %d, ожидалась 1", run, len(historyRepo.history))
+		}
+		if len(blockchainRepo.anomalies) != 0 {
+			t.Fatalf("запуск %d: неожиданные аномалии %v", run, blockchainRepo.anomalies)
+		}
+		if ix.lastBlock != 20 {
+			t.Fatalf("запуск %d: lastBlock=%d, ожидался 20", run, ix.lastBlock)
+		}
+	}
+}
+
+func TestNewDeploymentCatchesUpFromStartBlock(t *testing.T) {
+	ctx := context.Background()
+	contractABI := donatesABI(t)
+
+	retired := contract.Deployment{Address: common.HexToAddress("0x01"), ABI: contractABI, EndBlock: 15}
+	current := contract.Deployment{Address: common.HexToAddress("0x02"), ABI: contractABI, StartBlock: 12}
+	emitted := func(deployment contract.Deployment, block uint64, name string) types.Log {
+		vLog := eventLog(t, contractABI, "UserCreated", []string{name}, name)
+		vLog.Address = deployment.Address
+		vLog.BlockNumber = block
+		return vLog
+	}
+	logs := []types.Log{
+		emitted(retired, 14, "retired-processed"),
+		emitted(current, 13, "current-13"),
+		emitted(retired, 17, "retired-after-end"),
+		emitted(current, 19, "current-19"),
+	}
+
+	blockchainRepo := newFakeBlockchainRepo()
+	ix := New(&fakeClient{head: 20, logs: logs}, []contract.Deployment{retired, current}, blockchainRepo, Config{})
+	// Действующий деплой добавлен в реестр после того, как индексатор обработал блок 16
+	ix.lastBlock = 16
+	ix.restoreCheckpoints(map[string]uint64{retired.Address.Hex(): 15})
+
+	var handled []string
+	ix.Handle("UserCreated", func(_ context.Context, event *Event) error {
+		handled = append(handled, fmt.Sprintf("%s@%d", event.Record.ContractAddress, event.Log.BlockNumber))
+		return nil
+	})
+
+	if err := ix.processNewBlocks(ctx); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{current.Address.Hex() + "@13", current.Address.Hex() + "@19"}
+	if !slices.Equal(handled, want) {
+		t.Fatalf("обработаны события %v, ожидались %v", handled, want)
+	}
+	if ix.lastBlock != 20 {
+		t.Fatalf("lastBlock=%d, ожидался 20", ix.lastBlock)
+	}
+	state, _ := blockchainRepo.GetState(ctx)
+	if block := state.Checkpoint(retired.Address.Hex()); block != 15 {
+		t.Fatalf("контрольная точка выведенного деплоя %d, ожидался его end_block 15", block)
+	}
+	if block := state.Checkpoint(current.Address.Hex()); block != 20 {
+		t.Fatalf("контрольная точка действующего деплоя %d, ожидался 20", block)
+	}
+}
@@ -0,0 +1,99 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+const (
+	// leaderSessionTTL время жизни сессии Consul. Если реплика перестаёт продлевать сессию,
+	// блокировка освобождается и лидерство переходит к другой реплике
+	leaderSessionTTL = "15s"
+	// leaderRetryInterval пауза перед повторной попыткой получить блокировку после ошибки
+	leaderRetryInterval = 5 * time.Second
+)
+
+// Leader выбирает среди реплик индексатора единственного лидера через сессионную блокировку в Consul.
+// Логи контракта опрашивает (и отправляет транзакции релеера) только реплика, удерживающая блокировку
+type Leader struct {
+	client *consulapi.Client
+	key    string
+}
+
+func NewLeader(client *consulapi.Client, key string) *Leader {
+	return &Leader{
+		client: client,
+		key:    key,
+	}
+}
+
+// Run выполняет fn, пока реплика является лидером; контекст fn отменяется при потере сессии.
+// После потери лидерства реплика снова встаёт в очередь на блокировку. Завершается при отмене ctx
+func (l *Leader) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+	for {
+		err := l.Do(ctx, fn)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			log.Printf("⚠️ Ошибка работы лидера: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(leaderRetryInterval):
+		}
+	}
+}
+
+// Do дожидается блокировки и выполняет fn, пока она удерживается.
+// Контекст fn отменяется при потере сессии, после завершения fn блокировка освобождается
+func (l *Leader) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	hostname, _ := os.Hostname()
+	lock, err := l.client.LockOpts(&consulapi.LockOptions{
+		Key:            l.key,
+		Value:          []byte(hostname),
+		SessionName:    "donly-indexer",
+		SessionTTL:     leaderSessionTTL,
+		MonitorRetries: 3,
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка создания блокировки: %w", err)
+	}
+
+	log.Printf("Ожидаем лидерство (ключ %s)", l.key)
+	lostCh, err := lock.Lock(ctx.Done())
+	if err != nil {
+		return fmt.Errorf("ошибка получения блокировки: %w", err)
+	}
+	if lostCh == nil {
+		// Ожидание прервано отменой контекста
+		return ctx.Err()
+	}
+	defer func() {
+		if err := lock.Unlock(); err != nil && !errors.Is(err, consulapi.ErrLockNotHeld) {
+			log.Printf("Ошибка освобождения блокировки: %v", err)
+		}
+	}()
+	log.Printf("✅ Реплика %s стала лидером", hostname)
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-lostCh:
+			log.Println("⚠️ Сессия Consul потеряна, лидерство утрачено")
+			cancel()
+		case <-leaderCtx.Done():
+		}
+	}()
+
+	return fn(leaderCtx)
+}
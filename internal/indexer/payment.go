@@ -0,0 +1,332 @@
+package indexer
+
+import (
+	"backend/internal/entity"
+	"backend/internal/repo"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// PaymentCreditedEvent событие зачисления платежа (донат или вывод средств)
+type PaymentCreditedEvent struct {
+	Payment ContractPayment
+}
+
+// ContractPayment структура Payment из контракта.
+// Поля декодируются по порядку, поэтому их порядок должен совпадать с Types.sol
+type ContractPayment struct {
+	UUID                   string
+	PaymentUserData        ContractPaymentUserData
+	PaymentInfo            ContractPaymentInfo
+	Amount                 *big.Int
+	TransferedToUserAmount *big.Int
+}
+
+// ContractPaymentUserData структура PaymentUserData из контракта
+type ContractPaymentUserData struct {
+	UserName    string
+	MessageText string
+}
+
+// ContractPaymentInfo структура PaymentInfo из контракта
+type ContractPaymentInfo struct {
+	Date        *big.Int
+	FromUUID    string
+	ToUUID      string
+	WishID      *big.Int
+	ToAddress   common.Address
+	PaymentType uint8
+}
+
+// Значения enum PaymentType из контракта
+const (
+	paymentTypeDonate   uint8 = 0
+	paymentTypeWithdraw uint8 = 1
+)
+
+// FundedHook вызывается, когда донат набрал целевую сумму желания и оно перешло в статус funded
+type FundedHook func(ctx context.Context, wish *entity.Wish) error
+
+// PaymentHandler записывает платежи в историю, увеличивает накопленные суммы желаний
+// и отправляет донаты в стрим событий
+type PaymentHandler struct {
+	wishRepo     repo.WishRepository
+	historyRepo  repo.HistoryRepository
+	donationRepo repo.DonationEventRepo
+	users        *UserResolver
+	onFunded     FundedHook
+}
+
+func NewPaymentHandler(
+	wishRepo repo.WishRepository,
+	historyRepo repo.HistoryRepository,
+	donationRepo repo.DonationEventRepo,
+	users *UserResolver,
+) *PaymentHandler {
+	return &PaymentHandler{
+		wishRepo:     wishRepo,
+		historyRepo:  historyRepo,
+		donationRepo: donationRepo,
+		users:        users,
+	}
+}
+
+// Register регистрирует обработчик платежей в индексаторе
+func (h *PaymentHandler) Register(ix *Indexer) {
+	ix.Handle("PaymentCredited", h.handlePaymentCredited)
+	ix.OnRollback(h.rollback)
+	ix.OnReset(h.reset)
+}
+
+// OnFunded задаёт действие, выполняемое при наборе целевой суммы желания, например
+// постановку вызова completeOrRemoveWish в очередь релеера. При исторической загрузке не вызывается
+func (h *PaymentHandler) OnFunded(fn FundedHook) {
+	h.onFunded = fn
+}
+
+// handlePaymentCredited обрабатывает событие зачисления платежа: записывает историю
+// и для донатов на желание увеличивает накопленную сумму
+func (h *PaymentHandler) handlePaymentCredited(ctx context.Context, event *Event) error {
+	var data PaymentCreditedEvent
+	if err := event.Unpack(&data); err != nil {
+		return err
+	}
+
+	payment := data.Payment
+	info := payment.PaymentInfo
+
+	// Индексированный streamerUUid должен соответствовать получателю из тела платежа
+	user, err := h.users.Resolve(ctx, event, "")
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return nil
+	}
+	event.Record.UserUUID = user.UUID
+	if user.UUID != info.ToUUID {
+		h.users.Reject(ctx, event, "получатель платежа не совпадает с топиком события", user.UUID, "")
+		return nil
+	}
+	// toUUID и toAddress в donate() задаёт вызывающий, а деньги зачисляются на toAddress.
+	// Платёж засчитывается стримеру, только если он пришёл на его кошелёк
+	if !common.IsHexAddress(user.PolygonWallet) || common.HexToAddress(user.PolygonWallet) != info.ToAddress {
+		h.users.Reject(ctx, event, fmt.Sprintf("адрес получателя %s не совпадает с кошельком стримера", info.ToAddress.Hex()), user.UUID, "")
+		return nil
+	}
+
+	log.Printf("Обнаружен платёж в контракте: uuid=%s, стример=%s, тип=%d, сумма=%s",
+		payment.UUID, info.ToUUID, info.PaymentType, payment.Amount.String())
+
+	// Контракт зачисляет стримеру transferedToUserAmount, а разницу с amount оставляет себе как комиссию
+	net := payment.TransferedToUserAmount
+	if net == nil {
+		net = payment.Amount
+	}
+	history := &entity.History{
+		ID:           EventID(event.Log),
+		StreamerUUID: info.ToUUID,
+		Datetime:     time.Unix(info.Date.Int64(), 0),
+		Amount:       entity.NewAmount(payment.Amount),
+		NetAmount:    entity.NewAmount(net),
+		Commission:   entity.NewAmount(new(big.Int).Sub(payment.Amount, net)),
+		BlockNumber:  event.Log.BlockNumber,
+	}
+
+	var wish *entity.Wish
+	switch info.PaymentType {
+	case paymentTypeDonate:
+		history.Type = "donate"
+		if payment.PaymentUserData.UserName != "" {
+			history.Username = stringPtr(payment.PaymentUserData.UserName)
+		}
+		if payment.PaymentUserData.MessageText != "" {
+			history.Message = stringPtr(payment.PaymentUserData.MessageText)
+		}
+		if info.WishID != nil && info.WishID.Sign() > 0 {
+			wish, err = h.wishRepo.GetByOnchainID(ctx, info.WishID.Uint64())
+			if err != nil {
+				log.Printf("Не найдено желание с onchain ID %s: %v", info.WishID.String(), err)
+				wish = nil
+			} else if wish.StreamerUUID != info.ToUUID {
+				log.Printf("Желание %s не принадлежит стримеру %s", wish.UUID, info.ToUUID)
+				wish = nil
+			} else if wish.ContractAddress != "" && wish.ContractAddress != event.Log.Address.Hex() {
+				log.Printf("Желание %s добавлено в контракт %s, а донат пришёл в %s", wish.UUID, wish.ContractAddress, event.Log.Address.Hex())
+				wish = nil
+			} else {
+				history.WishUUID = stringPtr(wish.UUID)
+			}
+		}
+	case paymentTypeWithdraw:
+		history.Type = "withdraw"
+	default:
+		log.Printf("Неизвестный тип платежа %d в транзакции %s", info.PaymentType, event.Log.TxHash.Hex())
+		return nil
+	}
+
+	alreadyRecorded := false
+	if err := h.historyRepo.Add(ctx, history); err != nil {
+		if !errors.Is(err, repo.ErrHistoryAlreadyExists) {
+			return fmt.Errorf("ошибка сохранения истории платежа: %w", err)
+		}
+		log.Printf("Платёж %s уже записан в историю", history.ID)
+		alreadyRecorded = true
+	}
+
+	event.Record.Applied = !alreadyRecorded
+	if history.WishUUID != nil {
+		event.Record.WishUUID = *history.WishUUID
+	}
+
+	// Зачисление идемпотентно по ID записи истории, поэтому выполняется и при повторной обработке:
+	// если процесс упал между записью истории и зачислением, сумма будет зачислена сейчас
+	if wish != nil {
+		if err := h.wishRepo.CreditPayment(ctx, wish.UUID, history.ID, history.Amount); err != nil {
+			return fmt.Errorf("ошибка увеличения накопленной суммы желания: %w", err)
+		}
+		log.Printf("Накопленная сумма желания %s увеличена на %s POL", wish.UUID, history.Amount)
+	}
+
+	funded := false
+	if wish != nil {
+		if funded, err = h.markFunded(ctx, event, wish.UUID, history.Amount); err != nil {
+			return err
+		}
+	}
+
+	// При исторической загрузке уведомления о старых донатах не отправляем
+	if history.Type == "donate" && !event.Backfill {
+		if err := h.publishDonationEvent(ctx, history, funded); err != nil {
+			return fmt.Errorf("ошибка отправки события доната: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// markFunded переводит желание в статус funded, если накопленная сумма достигла цели, и сообщает,
+// что цель набрана именно этим платежом. Проверка выполняется по сумме после зачисления,
+// поэтому повторная обработка того же лога снова вызывает хук, если прошлая попытка завершилась ошибкой
+func (h *PaymentHandler) markFunded(ctx context.Context, event *Event, wishUUID string, amount entity.Amount) (bool, error) {
+	wish, err := h.wishRepo.GetByUUID(ctx, wishUUID)
+	if err != nil {
+		return false, fmt.Errorf("ошибка получения желания %s: %w", wishUUID, err)
+	}
+	if wish.PolTarget.IsZero() || wish.PolAmount.Cmp(wish.PolTarget) < 0 || wish.PolAmount.Sub(amount).Cmp(wish.PolTarget) >= 0 {
+		return false, nil
+	}
+
+	switch wish.Status {
+	case "active":
+		if err := h.wishRepo.UpdateStatus(ctx, wish.UUID, "active", "funded"); err != nil {
+			if errors.Is(err, repo.ErrWishStatusChanged) {
+				log.Printf("Статус желания %s изменился, отметка о наборе суммы пропущена", wish.UUID)
+				return false, nil
+			}
+			return false, fmt.Errorf("ошибка перевода желания %s в статус funded: %w", wish.UUID, err)
+		}
+		wish.Status = "funded"
+		log.Printf("Желание %s набрало целевую сумму %s POL", wish.UUID, wish.PolTarget)
+	case "funded":
+	default:
+		// Завершённое или удалённое желание уже не собирает средства
+		return false, nil
+	}
+
+	if h.onFunded != nil && !event.Backfill {
+		if err := h.onFunded(ctx, wish); err != nil {
+			return false, fmt.Errorf("ошибка обработки набранного желания %s: %w", wish.UUID, err)
+		}
+	}
+	return true, nil
+}
+
+// publishDonationEvent отправляет донат в стрим событий для SSE и Telegram бота.
+// UUID события совпадает с ID записи истории, поэтому повторная обработка
+// того же лога после перезапуска не приводит к дублированию уведомлений
+func (h *PaymentHandler) publishDonationEvent(ctx context.Context, history *entity.History, wishFunded bool) error {
+	event := entity.DonationEvent{
+		UUID:         history.ID,
+		StreamerUUID: history.StreamerUUID,
+		Amount:       history.Amount,
+		WishFunded:   wishFunded,
+		Datetime:     history.Datetime,
+	}
+	if history.Username != nil {
+		event.DonorUsername = *history.Username
+	}
+	if history.Message != nil {
+		event.Message = *history.Message
+	}
+	if history.WishUUID != nil {
+		event.WishUUID = *history.WishUUID
+	}
+	return h.donationRepo.SendDonationEvent(ctx, event)
+}
+
+// rollback удаляет записи истории из откатываемых блоков, уменьшает накопленные суммы желаний
+// и возвращает в active желания, которые после отката уже не набирают целевую сумму
+func (h *PaymentHandler) rollback(ctx context.Context, fromBlock uint64, _ []*entity.BlockchainEvent) error {
+	histories, err := h.historyRepo.GetFromBlock(ctx, fromBlock)
+	if err != nil {
+		return fmt.Errorf("ошибка получения истории для отката: %w", err)
+	}
+	reverted := make(map[string]bool)
+	for _, history := range histories {
+		if history.Type != "donate" || history.WishUUID == nil {
+			continue
+		}
+		if err := h.wishRepo.RevertPayment(ctx, *history.WishUUID, history.ID, history.Amount); err != nil {
+			if errors.Is(err, repo.ErrWishNotFound) {
+				continue
+			}
+			return fmt.Errorf("ошибка отката накопленной суммы желания %s: %w", *history.WishUUID, err)
+		}
+		reverted[*history.WishUUID] = true
+	}
+	for wishUUID := range reverted {
+		if err := h.unmarkFunded(ctx, wishUUID); err != nil {
+			return err
+		}
+	}
+	if err := h.historyRepo.DeleteFromBlock(ctx, fromBlock); err != nil {
+		return fmt.Errorf("ошибка удаления истории: %w", err)
+	}
+	log.Printf("Откат платежей: удалено записей истории %d", len(histories))
+	return nil
+}
+
+// unmarkFunded возвращает желание из funded в active, если накопленная сумма стала меньше цели
+func (h *PaymentHandler) unmarkFunded(ctx context.Context, wishUUID string) error {
+	wish, err := h.wishRepo.GetByUUID(ctx, wishUUID)
+	if err != nil {
+		return fmt.Errorf("ошибка получения желания %s: %w", wishUUID, err)
+	}
+	if wish.Status != "funded" || wish.PolAmount.Cmp(wish.PolTarget) >= 0 {
+		return nil
+	}
+	if err := h.wishRepo.UpdateStatus(ctx, wish.UUID, "funded", "active"); err != nil && !errors.Is(err, repo.ErrWishStatusChanged) {
+		return fmt.Errorf("ошибка возврата желания %s в статус active: %w", wish.UUID, err)
+	}
+	log.Printf("Желание %s после отката платежей снова собирает средства", wish.UUID)
+	return nil
+}
+
+// reset удаляет всю историю платежей перед исторической загрузкой
+func (h *PaymentHandler) reset(ctx context.Context) error {
+	if err := h.historyRepo.DeleteFromBlock(ctx, 0); err != nil {
+		return fmt.Errorf("ошибка очистки истории: %w", err)
+	}
+	return nil
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
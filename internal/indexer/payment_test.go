@@ -0,0 +1,190 @@
+package indexer
+
+import (
+	"backend/internal/entity"
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+
+	ethabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	streamerWallet = common.HexToAddress("0x1000000000000000000000000000000000000001")
+	attackerWallet = common.HexToAddress("0x2000000000000000000000000000000000000002")
+)
+
+// paymentLog кодирует событие PaymentCredited с платежом, собранным по типу tuple из ABI.
+// С донатов удерживается комиссия 5%, как в контракте с K = 50
+func paymentLog(t *testing.T, contractABI ethabi.ABI, toUUID string, toAddress common.Address, wishID int64, amount *big.Int, paymentType uint8) types.Log {
+	t.Helper()
+	event := contractABI.Events["PaymentCredited"]
+	tupleType := event.Inputs[1].Type
+	payment := reflect.New(tupleType.GetType()).Elem()
+	transfered := amount
+	if paymentType == paymentTypeDonate {
+		transfered = new(big.Int).Sub(amount, new(big.Int).Div(new(big.Int).Mul(amount, big.NewInt(50)), big.NewInt(1000)))
+	}
+	values := map[string]interface{}{
+		"uuid":                   "payment-" + toUUID,
+		"amount":                 amount,
+		"transferedToUserAmount": transfered,
+	}
+	for i, name := range tupleType.TupleRawNames {
+		elem := tupleType.TupleElems[i]
+		field := payment.Field(i)
+		switch name {
+		case "paymentUserData":
+			field.Field(0).SetString("donater")
+			field.Field(1).SetString("hello")
+		case "paymentInfo":
+			info := map[string]interface{}{
+				"date":        big.NewInt(1_700_000_000),
+				"fromUUID":    "",
+				"toUUID":      toUUID,
+				"wishId":      big.NewInt(wishID),
+				"toAddress":   toAddress,
+				"paymentType": paymentType,
+			}
+			for j, infoName := range elem.TupleRawNames {
+				field.Field(j).Set(reflect.ValueOf(info[infoName]))
+			}
+		default:
+			field.Set(reflect.ValueOf(values[name]))
+		}
+	}
+
+	vLog := eventLog(t, contractABI, "PaymentCredited", []string{toUUID}, payment.Interface())
+	vLog.Topics = append(vLog.Topics, common.BigToHash(big.NewInt(int64(paymentType))))
+	return vLog
+}
+
+func newPaymentFixture(t *testing.T) (*PaymentHandler, *fakeWishRepo, *fakeHistoryRepo, *fakeBlockchainRepo) {
+	t.Helper()
+	streamer := &entity.User{UUID: "streamer", UUIDHash: entity.UUIDTopicHash("streamer"), PolygonWallet: streamerWallet.Hex()}
+	wishRepo := newFakeWishRepo(&entity.Wish{UUID: "wish-1", OnchainID: 3, StreamerUUID: "streamer", Status: "active"})
+	historyRepo := newFakeHistoryRepo()
+	blockchainRepo := newFakeBlockchainRepo()
+	users := NewUserResolver(newFakeUserRepo(streamer), blockchainRepo)
+	return NewPaymentHandler(wishRepo, historyRepo, &fakeDonationRepo{}, users), wishRepo, historyRepo, blockchainRepo
+}
+
+func TestPaymentCreditedIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	contractABI := donatesABI(t)
+	handler, wishRepo, historyRepo, _ := newPaymentFixture(t)
+
+	vLog := paymentLog(t, contractABI, "streamer", streamerWallet, 3, big.NewInt(2e18), paymentTypeDonate)
+
+	// Запись истории уже есть, а зачисление не выполнено (процесс упал между двумя операциями)
+	if err := historyRepo.Add(ctx, &entity.History{ID: EventID(vLog), StreamerUUID: "streamer", Type: "donate", Amount: entity.POL(2), BlockNumber: vLog.BlockNumber}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := handler.handlePaymentCredited(ctx, newTestEvent(t, &contractABI, vLog)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if amount := wishRepo.get("wish-1").PolAmount; amount.Cmp(entity.POL(2)) != 0 {
+		t.Fatalf("накопленная сумма %v, ожидалось 2", amount)
+	}
+}
+
+func TestPaymentCreditedRejectsForeignAddress(t *testing.T) {
+	ctx := context.Background()
+	contractABI := donatesABI(t)
+	handler, wishRepo, historyRepo, blockchainRepo := newPaymentFixture(t)
+
+	for _, paymentType := range []uint8{paymentTypeDonate, paymentTypeWithdraw} {
+		vLog := paymentLog(t, contractABI, "streamer", attackerWallet, 3, big.NewInt(1e15), paymentType)
+		event := newTestEvent(t, &contractABI, vLog)
+		if err := handler.handlePaymentCredited(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := blockchainRepo.anomalies[event.Record.ID]; !ok {
+			t.Fatalf("платёж типа %d на чужой адрес не записан как аномалия", paymentType)
+		}
+	}
+	if len(historyRepo.history) != 0 {
+		t.Fatalf("платёж на чужой адрес записан в историю: %d записей", len(historyRepo.history))
+	}
+	if amount := wishRepo.get("wish-1").PolAmount; !amount.IsZero() {
+		t.Fatalf("платёж на чужой адрес зачислен желанию: %v", amount)
+	}
+}
+
+func TestPaymentCreditedStoresCommission(t *testing.T) {
+	ctx := context.Background()
+	contractABI := donatesABI(t)
+	handler, _, historyRepo, _ := newPaymentFixture(t)
+
+	donate := paymentLog(t, contractABI, "streamer", streamerWallet, 3, big.NewInt(2e18), paymentTypeDonate)
+	withdraw := paymentLog(t, contractABI, "streamer", streamerWallet, 0, big.NewInt(1e18), paymentTypeWithdraw)
+	withdraw.Index = donate.Index + 1
+	for _, vLog := range []types.Log{donate, withdraw} {
+		if err := handler.handlePaymentCredited(ctx, newTestEvent(t, &contractABI, vLog)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history := historyRepo.history[EventID(donate)]
+	if history == nil || history.Amount.Cmp(entity.POL(2)) != 0 || history.NetAmount.Cmp(entity.POLFromFloat(1.9)) != 0 ||
+		history.Commission.Cmp(entity.POLFromFloat(0.1)) != 0 {
+		t.Fatalf("донат записан без разделения на зачисление и комиссию: %+v", history)
+	}
+	history = historyRepo.history[EventID(withdraw)]
+	if history == nil || history.NetAmount.Cmp(entity.POL(1)) != 0 || !history.Commission.IsZero() {
+		t.Fatalf("вывод записан с комиссией: %+v", history)
+	}
+}
+
+func TestPaymentCreditedMarksWishFunded(t *testing.T) {
+	ctx := context.Background()
+	contractABI := donatesABI(t)
+	streamer := &entity.User{UUID: "streamer", UUIDHash: entity.UUIDTopicHash("streamer"), PolygonWallet: streamerWallet.Hex()}
+	wishRepo := newFakeWishRepo(&entity.Wish{UUID: "wish-1", OnchainID: 3, StreamerUUID: "streamer", Status: "active", PolTarget: entity.POL(3)})
+	historyRepo := newFakeHistoryRepo()
+	blockchainRepo := newFakeBlockchainRepo()
+	donationRepo := &fakeDonationRepo{}
+	handler := NewPaymentHandler(wishRepo, historyRepo, donationRepo, NewUserResolver(newFakeUserRepo(streamer), blockchainRepo))
+	var funded []string
+	handler.OnFunded(func(_ context.Context, wish *entity.Wish) error {
+		funded = append(funded, wish.UUID)
+		return nil
+	})
+
+	first := paymentLog(t, contractABI, "streamer", streamerWallet, 3, big.NewInt(2e18), paymentTypeDonate)
+	second := paymentLog(t, contractABI, "streamer", streamerWallet, 3, big.NewInt(15e17), paymentTypeDonate)
+	second.BlockNumber = first.BlockNumber + 1
+
+	if err := handler.handlePaymentCredited(ctx, newTestEvent(t, &contractABI, first)); err != nil {
+		t.Fatal(err)
+	}
+	if status := wishRepo.get("wish-1").Status; status != "active" || len(funded) != 0 {
+		t.Fatalf("желание без набранной цели в статусе %s, хук вызван %d раз", status, len(funded))
+	}
+	if err := handler.handlePaymentCredited(ctx, newTestEvent(t, &contractABI, second)); err != nil {
+		t.Fatal(err)
+	}
+	if status := wishRepo.get("wish-1").Status; status != "funded" {
+		t.Fatalf("желание с набранной целью в статусе %s, ожидался funded", status)
+	}
+	if len(funded) != 1 || funded[0] != "wish-1" {
+		t.Fatalf("хук набора цели вызван для %v, ожидался wish-1", funded)
+	}
+	if len(donationRepo.events) != 2 || donationRepo.events[0].WishFunded || !donationRepo.events[1].WishFunded {
+		t.Fatalf("отметка набора цели в событиях донатов: %+v", donationRepo.events)
+	}
+
+	// Откат блока с последним донатом возвращает желание к сбору средств
+	if err := handler.rollback(ctx, second.BlockNumber, nil); err != nil {
+		t.Fatal(err)
+	}
+	if wish := wishRepo.get("wish-1"); wish.Status != "active" || wish.PolAmount.Cmp(entity.POL(2)) != 0 {
+		t.Fatalf("после отката желание в статусе %s с суммой %v, ожидалось active и 2", wish.Status, wish.PolAmount)
+	}
+}
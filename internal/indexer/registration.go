@@ -0,0 +1,113 @@
+package indexer
+
+import (
+	"backend/internal/contract"
+	"backend/internal/entity"
+	"backend/internal/repo"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// UserCreatedEvent событие регистрации пользователя в контракте. Индексированный uuid передаётся в топике
+type UserCreatedEvent struct {
+	Name string
+}
+
+// UserReader читает текущее состояние пользователя по адресу кошелька из деплоя контракта contractAddr
+type UserReader func(ctx context.Context, contractAddr, addr common.Address) (*contract.OnchainUser, error)
+
+// RegistrationHandler подтверждает регистрацию стримеров в контракте по событиям UserCreated
+type RegistrationHandler struct {
+	userRepo repo.UserRepository
+	users    *UserResolver
+	readUser UserReader
+	// relayerHash keccak256 хеш UUID релеера: его регистрация не относится к стримерам
+	relayerHash string
+}
+
+func NewRegistrationHandler(userRepo repo.UserRepository, users *UserResolver, readUser UserReader) *RegistrationHandler {
+	return &RegistrationHandler{
+		userRepo: userRepo,
+		users:    users,
+		readUser: readUser,
+	}
+}
+
+// Register регистрирует обработчик UserCreated в индексаторе
+func (h *RegistrationHandler) Register(ix *Indexer) {
+	ix.Handle("UserCreated", h.handleUserCreated)
+	ix.OnRollback(h.rollback)
+	ix.OnReset(h.reset)
+}
+
+// TrustRelayer пропускает регистрацию адреса релеера без записи аномалии
+func (h *RegistrationHandler) TrustRelayer(relayerUUID string) {
+	h.relayerHash = entity.UUIDTopicHash(relayerUUID)
+}
+
+// handleUserCreated подтверждает регистрацию стримера. В событии нет адреса отправителя, а registerUser
+// может вызвать кто угодно с любым UUID, поэтому регистрация засчитывается, только если в контракте
+// под кошельком стримера записан его UUID
+func (h *RegistrationHandler) handleUserCreated(ctx context.Context, event *Event) error {
+	var data UserCreatedEvent
+	if err := event.Unpack(&data); err != nil {
+		return err
+	}
+	if h.relayerHash != "" && len(event.Log.Topics) > 1 && event.Log.Topics[1].Hex() == h.relayerHash {
+		log.Printf("Адрес релеера зарегистрирован в контракте в блоке %d", event.Log.BlockNumber)
+		return nil
+	}
+
+	user, err := h.users.Resolve(ctx, event, "")
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return nil
+	}
+	event.Record.UserUUID = user.UUID
+	log.Printf("Обнаружена регистрация стримера %s в контракте: имя=%s", user.UUID, data.Name)
+
+	if !common.IsHexAddress(user.PolygonWallet) {
+		h.users.Reject(ctx, event, "у стримера не указан кошелёк", user.UUID, "")
+		return nil
+	}
+	onchain, err := h.readUser(ctx, event.Log.Address, common.HexToAddress(user.PolygonWallet))
+	if err != nil {
+		return fmt.Errorf("ошибка чтения регистрации стримера %s: %w", user.UUID, err)
+	}
+	if onchain.UUID != user.UUID {
+		h.users.Reject(ctx, event, "UUID стримера зарегистрирован не с его кошелька", user.UUID, "")
+		return nil
+	}
+
+	if err := h.userRepo.ConfirmRegistration(ctx, user.UUID, event.Log.BlockNumber); err != nil {
+		if errors.Is(err, repo.ErrUserNotFound) {
+			return nil
+		}
+		return fmt.Errorf("ошибка сохранения регистрации стримера: %w", err)
+	}
+	event.Record.Applied = true
+	log.Printf("Регистрация стримера %s в контракте подтверждена", user.UUID)
+	return nil
+}
+
+// rollback снимает подтверждения регистрации из откатываемых блоков
+func (h *RegistrationHandler) rollback(ctx context.Context, fromBlock uint64, _ []*entity.BlockchainEvent) error {
+	if err := h.userRepo.ResetRegistrations(ctx, fromBlock); err != nil {
+		return fmt.Errorf("ошибка отката регистраций: %w", err)
+	}
+	return nil
+}
+
+// reset снимает все подтверждения регистрации перед исторической загрузкой
+func (h *RegistrationHandler) reset(ctx context.Context) error {
+	if err := h.userRepo.ResetRegistrations(ctx, 0); err != nil {
+		return fmt.Errorf("ошибка сброса регистраций: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package indexer
+
+import (
+	"backend/internal/contract"
+	"backend/internal/entity"
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// newRegistrationFixture возвращает обработчик, у которого в контракте под кошельком стримера записан onchainUUID
+func newRegistrationFixture(onchainUUID string) (*RegistrationHandler, *fakeUserRepo, *fakeBlockchainRepo) {
+	streamer := &entity.User{UUID: "streamer", UUIDHash: entity.UUIDTopicHash("streamer"), PolygonWallet: streamerWallet.Hex()}
+	userRepo := newFakeUserRepo(streamer)
+	blockchainRepo := newFakeBlockchainRepo()
+	readUser := func(_ context.Context, _, addr common.Address) (*contract.OnchainUser, error) {
+		if addr != streamerWallet {
+			return &contract.OnchainUser{}, nil
+		}
+		return &contract.OnchainUser{UUID: onchainUUID}, nil
+	}
+	handler := NewRegistrationHandler(userRepo, NewUserResolver(userRepo, blockchainRepo), readUser)
+	return handler, userRepo, blockchainRepo
+}
+
+func TestUserCreatedConfirmsRegistration(t *testing.T) {
+	ctx := context.Background()
+	contractABI := donatesABI(t)
+	handler, userRepo, _ := newRegistrationFixture("streamer")
+
+	vLog := eventLog(t, contractABI, "UserCreated", []string{"streamer"}, "name")
+	vLog.BlockNumber = 42
+	event := newTestEvent(t, &contractABI, vLog)
+	if err := handler.handleUserCreated(ctx, event); err != nil {
+		t.Fatal(err)
+	}
+	user, _ := userRepo.GetByUUID(ctx, "streamer")
+	if !user.OnchainRegistered || user.RegistrationBlock != 42 {
+		t.Fatalf("регистрация не подтверждена: %+v", user)
+	}
+	if !event.Record.Applied {
+		t.Fatal("событие не отмечено как применённое")
+	}
+
+	// Откат блока регистрации снимает подтверждение
+	if err := handler.rollback(ctx, 42, nil); err != nil {
+		t.Fatal(err)
+	}
+	if user, _ := userRepo.GetByUUID(ctx, "streamer"); user.OnchainRegistered {
+		t.Fatal("подтверждение регистрации осталось после отката")
+	}
+}
+
+func TestUserCreatedRejectsForeignWallet(t *testing.T) {
+	ctx := context.Background()
+	contractABI := donatesABI(t)
+	// UUID стримера зарегистрирован с чужого кошелька, под кошельком стримера записи нет
+	handler, userRepo, blockchainRepo := newRegistrationFixture("")
+
+	event := newTestEvent(t, &contractABI, eventLog(t, contractABI, "UserCreated", []string{"streamer"}, "name"))
+	if err := handler.handleUserCreated(ctx, event); err != nil {
+		t.Fatal(err)
+	}
+	if user, _ := userRepo.GetByUUID(ctx, "streamer"); user.OnchainRegistered {
+		t.Fatal("регистрация с чужого кошелька подтверждена")
+	}
+	if _, ok := blockchainRepo.anomalies[event.Record.ID]; !ok {
+		t.Fatal("регистрация с чужого кошелька не записана как аномалия")
+	}
+}
@@ -0,0 +1,80 @@
+package indexer
+
+import (
+	"backend/internal/entity"
+	"backend/internal/repo"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// UserResolver сопоставляет индексированные топики событий с пользователями
+// и фиксирует отклонённые события как аномалии
+type UserResolver struct {
+	userRepo       repo.UserRepository
+	blockchainRepo repo.BlockchainRepository
+}
+
+func NewUserResolver(userRepo repo.UserRepository, blockchainRepo repo.BlockchainRepository) *UserResolver {
+	return &UserResolver{
+		userRepo:       userRepo,
+		blockchainRepo: blockchainRepo,
+	}
+}
+
+// Resolve находит пользователя по keccak256 хешу UUID из первого индексированного топика.
+// Если пользователь неизвестен, событие фиксируется как аномалия и возвращается nil
+func (r *UserResolver) Resolve(ctx context.Context, event *Event, wishUUID string) (*entity.User, error) {
+	if len(event.Log.Topics) < 2 {
+		r.Reject(ctx, event, "в событии отсутствует индексированный UUID пользователя", "", wishUUID)
+		return nil, nil
+	}
+	user, err := r.userRepo.GetByUUIDHash(ctx, event.Log.Topics[1].Hex())
+	if err != nil {
+		if errors.Is(err, repo.ErrUserNotFound) {
+			r.Reject(ctx, event, "неизвестный пользователь в топике события", "", wishUUID)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка поиска пользователя по хешу UUID: %w", err)
+	}
+	return user, nil
+}
+
+// Reject сохраняет отклонённое событие для последующего разбора
+func (r *UserResolver) Reject(ctx context.Context, event *Event, reason, userUUID, wishUUID string) {
+	log.Printf("⚠️ Событие %s в транзакции %s отклонено: %s", event.Name, event.Log.TxHash.Hex(), reason)
+	anomaly := &entity.BlockchainAnomaly{
+		ID:          EventID(event.Log),
+		BlockNumber: event.Log.BlockNumber,
+		TxHash:      event.Log.TxHash.Hex(),
+		EventType:   event.Name,
+		Reason:      reason,
+		UserUUID:    userUUID,
+		WishUUID:    wishUUID,
+	}
+	if len(event.Log.Topics) > 1 {
+		anomaly.TopicHash = event.Log.Topics[1].Hex()
+	}
+	if err := r.blockchainRepo.SaveAnomaly(ctx, anomaly); err != nil {
+		log.Printf("Ошибка сохранения аномалии: %v", err)
+	}
+}
+
+// IndexUUIDHashes заполняет хеши UUID для пользователей, зарегистрированных до появления индекса
+func (r *UserResolver) IndexUUIDHashes(ctx context.Context) error {
+	users, err := r.userRepo.GetWithoutUUIDHash(ctx)
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		user.UUIDHash = entity.UUIDTopicHash(user.UUID)
+		if err := r.userRepo.Update(ctx, user); err != nil {
+			return fmt.Errorf("ошибка сохранения хеша UUID пользователя %s: %w", user.UUID, err)
+		}
+	}
+	if len(users) > 0 {
+		log.Printf("Проиндексированы хеши UUID для %d пользователей", len(users))
+	}
+	return nil
+}
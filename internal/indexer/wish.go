@@ -0,0 +1,310 @@
+package indexer
+
+import (
+	"backend/internal/entity"
+	"backend/internal/repo"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"slices"
+	"strings"
+)
+
+// WishAddedEvent событие добавления желания в контракт.
+// Индексированный userUUID передаётся в топике, желание идентифицируется числовым wishId (Wish.OnchainID)
+type WishAddedEvent struct {
+	WishID *big.Int `abi:"wishId"`
+	Price  *big.Int
+}
+
+// WishCompletedEvent событие завершения желания
+type WishCompletedEvent struct {
+	WishID *big.Int `abi:"wishid"`
+	Price  *big.Int
+}
+
+// WishDeletedEvent событие удаления желания
+type WishDeletedEvent struct {
+	WishID            *big.Int `abi:"wishid"`
+	AccumulatedAmount *big.Int
+}
+
+// DeletedWishAddedHook вызывается, когда в контракт попало желание, удалённое в приложении
+type DeletedWishAddedHook func(ctx context.Context, wish *entity.Wish) error
+
+// WishHandler переводит желания между статусами по событиям WishAdded, WishCompleted и WishDeleted
+type WishHandler struct {
+	wishRepo repo.WishRepository
+	users    *UserResolver
+	// relayerHash keccak256 хеш UUID, под которым в контракте зарегистрирован адрес релеера
+	relayerHash    string
+	onDeletedAdded DeletedWishAddedHook
+}
+
+func NewWishHandler(wishRepo repo.WishRepository, users *UserResolver) *WishHandler {
+	return &WishHandler{
+		wishRepo: wishRepo,
+		users:    users,
+	}
+}
+
+// Register регистрирует обработчики событий желаний в индексаторе
+func (h *WishHandler) Register(ix *Indexer) {
+	ix.Handle("WishAdded", h.handleWishAdded)
+	ix.Handle("WishCompleted", h.handleWishCompleted)
+	ix.Handle("WishDeleted", h.handleWishDeleted)
+	ix.OnRollback(h.rollback)
+	ix.OnReset(h.reset)
+}
+
+// TrustRelayer разрешает событиям желаний, добавленных релеером, нести UUID релеера вместо UUID стримера:
+// completeOrRemoveWish берёт UUID из пользователя, под чьим адресом хранится желание
+func (h *WishHandler) TrustRelayer(relayerUUID string) {
+	h.relayerHash = entity.UUIDTopicHash(relayerUUID)
+}
+
+// OnDeletedAdded задаёт действие для желания, удалённого в приложении, пока его addWish ещё не был
+// проиндексирован, например постановку удаления в очередь релеера. Желание передаётся с адресом контракта
+// из события. При исторической загрузке не вызывается
+func (h *WishHandler) OnDeletedAdded(fn DeletedWishAddedHook) {
+	h.onDeletedAdded = fn
+}
+
+// handleWishAdded обрабатывает событие добавления желания в контракт.
+// Если цена в контракте не совпала с целевой суммой, желание переводится в mismatch и ждёт решения стримера
+func (h *WishHandler) handleWishAdded(ctx context.Context, event *Event) error {
+	var data WishAddedEvent
+	if err := event.Unpack(&data); err != nil {
+		return err
+	}
+	log.Printf("Обнаружено новое желание в контракте: wishId=%s, цена=%s", data.WishID.String(), data.Price.String())
+
+	wish, err := h.resolve(ctx, event, data.WishID)
+	if err != nil || wish == nil {
+		return err
+	}
+	if wish.Status == "deleted" {
+		return h.handleDeletedAdded(ctx, event, wish)
+	}
+	if data.Price == nil || wish.PolTarget.Wei().Cmp(data.Price) == 0 {
+		if err := h.apply(ctx, event, wish, "active", "pending"); err != nil || !event.Record.Applied {
+			return err
+		}
+		return h.bindContract(ctx, event, wish)
+	}
+
+	if wish.Status != "pending" {
+		log.Printf("Желание %s не в статусе pending (текущий статус: %s)", wish.UUID, wish.Status)
+		return nil
+	}
+	onchainPrice := entity.NewAmount(data.Price)
+	if err := h.wishRepo.MarkPriceMismatch(ctx, wish.UUID, onchainPrice); err != nil {
+		if errors.Is(err, repo.ErrWishStatusChanged) {
+			log.Printf("Статус желания %s изменился во время обработки события, пропускаем", wish.UUID)
+			return nil
+		}
+		return fmt.Errorf("ошибка отметки расхождения цены желания: %w", err)
+	}
+	event.Record.Applied = true
+	event.Record.PrevStatus = "pending"
+	log.Printf("Цена желания %s в контракте (%s POL) не совпадает с целевой суммой (%s POL), желание переведено в статус 'mismatch'",
+		wish.UUID, onchainPrice, wish.PolTarget)
+	return h.bindContract(ctx, event, wish)
+}
+
+// handleDeletedAdded обрабатывает addWish, включённый в блок после удаления желания в приложении: стример удалил
+// желание, пока транзакция была в пути. Желание остаётся deleted, но хранится в контракте и занимает место
+// в массиве владельца, поэтому событие записывается как аномалия, а удаление из контракта передаётся хуку
+func (h *WishHandler) handleDeletedAdded(ctx context.Context, event *Event, wish *entity.Wish) error {
+	h.users.Reject(ctx, event, "желание удалено в приложении до добавления в контракт", wish.StreamerUUID, wish.UUID)
+	if h.onDeletedAdded == nil || event.Backfill {
+		return nil
+	}
+	wish.ContractAddress = event.Log.Address.Hex()
+	if err := h.onDeletedAdded(ctx, wish); err != nil {
+		return fmt.Errorf("ошибка обработки удалённого желания %s: %w", wish.UUID, err)
+	}
+	return nil
+}
+
+// bindContract запоминает деплой контракта, в который добавлено желание: события и донаты
+// того же wishId из других деплоев к этому желанию не относятся
+func (h *WishHandler) bindContract(ctx context.Context, event *Event, wish *entity.Wish) error {
+	if err := h.wishRepo.SetContract(ctx, wish.UUID, event.Log.Address.Hex()); err != nil {
+		return fmt.Errorf("ошибка сохранения контракта желания %s: %w", wish.UUID, err)
+	}
+	return nil
+}
+
+// handleWishCompleted обрабатывает событие завершения желания
+func (h *WishHandler) handleWishCompleted(ctx context.Context, event *Event) error {
+	var data WishCompletedEvent
+	if err := event.Unpack(&data); err != nil {
+		return err
+	}
+	log.Printf("Желание завершено в контракте: wishId=%s", data.WishID.String())
+	return h.transition(ctx, event, data.WishID, "complete", "active", "funded")
+}
+
+// handleWishDeleted обрабатывает событие удаления желания. Желание с расхождением цены тоже есть в контракте,
+// поэтому стример может удалить его вместо принятия цены
+func (h *WishHandler) handleWishDeleted(ctx context.Context, event *Event) error {
+	var data WishDeletedEvent
+	if err := event.Unpack(&data); err != nil {
+		return err
+	}
+	log.Printf("Желание удалено в контракте: wishId=%s", data.WishID.String())
+	return h.transition(ctx, event, data.WishID, "deleted", "active", "mismatch", "funded")
+}
+
+// transition находит желание по числовому идентификатору из события, проверяет владельца
+// и переводит желание в toStatus из одного из статусов fromStatuses
+func (h *WishHandler) transition(ctx context.Context, event *Event, wishID *big.Int, toStatus string, fromStatuses ...string) error {
+	wish, err := h.resolve(ctx, event, wishID)
+	if err != nil || wish == nil {
+		return err
+	}
+	return h.apply(ctx, event, wish, toStatus, fromStatuses...)
+}
+
+// resolve находит желание по числовому идентификатору из события и проверяет владельца.
+// Возвращает nil без ошибки, если событие записано как аномалия
+func (h *WishHandler) resolve(ctx context.Context, event *Event, wishID *big.Int) (*entity.Wish, error) {
+	if wishID == nil || wishID.Sign() <= 0 || !wishID.IsUint64() {
+		h.users.Reject(ctx, event, fmt.Sprintf("некорректный идентификатор желания %v", wishID), "", "")
+		return nil, nil
+	}
+
+	wish, err := h.wishRepo.GetByOnchainID(ctx, wishID.Uint64())
+	if err != nil {
+		if errors.Is(err, repo.ErrWishNotFound) {
+			h.users.Reject(ctx, event, fmt.Sprintf("не найдено желание с onchain ID %s", wishID.String()), "", "")
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка поиска желания по onchain ID %s: %w", wishID.String(), err)
+	}
+	event.Record.WishUUID = wish.UUID
+
+	if contractAddress := event.Log.Address.Hex(); wish.ContractAddress != "" && wish.ContractAddress != contractAddress {
+		h.users.Reject(ctx, event, fmt.Sprintf("желание добавлено в контракт %s", wish.ContractAddress), wish.StreamerUUID, wish.UUID)
+		return nil, nil
+	}
+
+	if h.isRelayerEvent(event, wish) {
+		event.Record.UserUUID = wish.StreamerUUID
+		return wish, nil
+	}
+
+	// Индексированный userUUID хранится в топике как keccak256 хеш, сопоставляем его с известным пользователем
+	user, err := h.users.Resolve(ctx, event, wish.UUID)
+	if err != nil || user == nil {
+		return nil, err
+	}
+	event.Record.UserUUID = user.UUID
+
+	if wish.StreamerUUID != user.UUID {
+		h.users.Reject(ctx, event, "желание принадлежит другому стримеру", user.UUID, wish.UUID)
+		return nil, nil
+	}
+	return wish, nil
+}
+
+// apply переводит желание в toStatus, если его текущий статус входит в fromStatuses,
+// и запоминает прежний статус в записи события для отката
+func (h *WishHandler) apply(ctx context.Context, event *Event, wish *entity.Wish, toStatus string, fromStatuses ...string) error {
+	if !slices.Contains(fromStatuses, wish.Status) {
+		log.Printf("Желание %s не в статусе %s (текущий статус: %s)", wish.UUID, strings.Join(fromStatuses, "/"), wish.Status)
+		return nil
+	}
+
+	if err := h.wishRepo.UpdateStatus(ctx, wish.UUID, wish.Status, toStatus); err != nil {
+		if errors.Is(err, repo.ErrWishStatusChanged) {
+			log.Printf("Статус желания %s изменился во время обработки события, пропускаем", wish.UUID)
+			return nil
+		}
+		return fmt.Errorf("ошибка обновления статуса желания на %s: %w", toStatus, err)
+	}
+
+	event.Record.Applied = true
+	event.Record.PrevStatus = wish.Status
+	log.Printf("Желание %s переведено в статус '%s'", wish.UUID, toStatus)
+	return nil
+}
+
+// isRelayerEvent сообщает, что событие относится к желанию, которое хранится под адресом релеера, и несёт его UUID
+func (h *WishHandler) isRelayerEvent(event *Event, wish *entity.Wish) bool {
+	if h.relayerHash == "" || wish.RelayedBy == "" || len(event.Log.Topics) < 2 {
+		return false
+	}
+	return event.Log.Topics[1].Hex() == h.relayerHash
+}
+
+// rollback возвращает желания в статусы, которые были до применения откатываемых событий
+func (h *WishHandler) rollback(ctx context.Context, _ uint64, events []*entity.BlockchainEvent) error {
+	// Откатываем в обратном порядке, чтобы восстановить исходные статусы
+	for i := len(events) - 1; i >= 0; i-- {
+		if err := h.revert(ctx, events[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revert возвращает желание в статус, который был до применения события
+func (h *WishHandler) revert(ctx context.Context, event *entity.BlockchainEvent) error {
+	if !event.Applied || event.WishUUID == "" {
+		return nil
+	}
+
+	var fromStatuses []string
+	var toStatus string
+	switch event.EventType {
+	case "WishAdded":
+		fromStatuses, toStatus = []string{"active", "mismatch"}, "pending"
+	case "WishCompleted":
+		fromStatuses, toStatus = []string{"complete"}, "active"
+	case "WishDeleted":
+		fromStatuses, toStatus = []string{"deleted"}, "active"
+	default:
+		return nil
+	}
+	// События, записанные до появления prev_status, откатываются в статус по умолчанию
+	if event.PrevStatus != "" {
+		toStatus = event.PrevStatus
+	}
+
+	wish, err := h.wishRepo.GetByUUID(ctx, event.WishUUID)
+	if err != nil {
+		log.Printf("Не найдено желание с UUID %s для отката: %v", event.WishUUID, err)
+		return nil
+	}
+	if !slices.Contains(fromStatuses, wish.Status) {
+		log.Printf("Желание %s в статусе %s, откат события %s пропущен", wish.UUID, wish.Status, event.EventType)
+		return nil
+	}
+
+	if err := h.wishRepo.UpdateStatus(ctx, wish.UUID, wish.Status, toStatus); err != nil {
+		if errors.Is(err, repo.ErrWishStatusChanged) {
+			log.Printf("Статус желания %s изменился, откат события %s пропущен", wish.UUID, event.EventType)
+			return nil
+		}
+		return fmt.Errorf("ошибка отката статуса желания %s: %w", wish.UUID, err)
+	}
+	if event.EventType == "WishAdded" {
+		if err := h.wishRepo.SetContract(ctx, wish.UUID, ""); err != nil {
+			return fmt.Errorf("ошибка сброса контракта желания %s: %w", wish.UUID, err)
+		}
+	}
+	log.Printf("Желание %s возвращено в статус '%s'", wish.UUID, toStatus)
+	return nil
+}
+
+// reset возвращает все желания в исходное состояние перед исторической загрузкой
+func (h *WishHandler) reset(ctx context.Context) error {
+	if err := h.wishRepo.ResetChainState(ctx); err != nil {
+		return fmt.Errorf("ошибка сброса состояния желаний: %w", err)
+	}
+	return nil
+}
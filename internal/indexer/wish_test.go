@@ -0,0 +1,266 @@
+package indexer
+
+import (
+	"backend/internal/entity"
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestWishEventsUnpackEmbeddedABI(t *testing.T) {
+	contractABI := donatesABI(t)
+
+	added := newTestEvent(t, &contractABI, eventLog(t, contractABI, "WishAdded", []string{"streamer"}, big.NewInt(7), big.NewInt(1500)))
+	var addedData WishAddedEvent
+	if err := added.Unpack(&addedData); err != nil {
+		t.Fatalf("WishAdded: %v", err)
+	}
+	if addedData.WishID.Uint64() != 7 || addedData.Price.Int64() != 1500 {
+		t.Fatalf("WishAdded декодирован неверно: %+v", addedData)
+	}
+
+	completed := newTestEvent(t, &contractABI, eventLog(t, contractABI, "WishCompleted", []string{"streamer"}, big.NewInt(8), big.NewInt(2500)))
+	var completedData WishCompletedEvent
+	if err := completed.Unpack(&completedData); err != nil {
+		t.Fatalf("WishCompleted: %v", err)
+	}
+	if completedData.WishID.Uint64() != 8 || completedData.Price.Int64() != 2500 {
+		t.Fatalf("WishCompleted декодирован неверно: %+v", completedData)
+	}
+
+	deleted := newTestEvent(t, &contractABI, eventLog(t, contractABI, "WishDeleted", []string{"streamer"}, big.NewInt(9), big.NewInt(300)))
+	var deletedData WishDeletedEvent
+	if err := deleted.Unpack(&deletedData); err != nil {
+		t.Fatalf("WishDeleted: %v", err)
+	}
+	if deletedData.WishID.Uint64() != 9 || deletedData.AccumulatedAmount.Int64() != 300 {
+		t.Fatalf("WishDeleted декодирован неверно: %+v", deletedData)
+	}
+}
+
+func TestWishHandlerTransitionsByOnchainID(t *testing.T) {
+	ctx := context.Background()
+	contractABI := donatesABI(t)
+
+	streamer := &entity.User{UUID: "streamer", UUIDHash: entity.UUIDTopicHash("streamer")}
+	other := &entity.User{UUID: "other", UUIDHash: entity.UUIDTopicHash("other")}
+	wishRepo := newFakeWishRepo(&entity.Wish{UUID: "wish-1", OnchainID: 7, StreamerUUID: "streamer", PolTarget: entity.NewAmount(big.NewInt(1)), Status: "pending"})
+	blockchainRepo := newFakeBlockchainRepo()
+	handler := NewWishHandler(wishRepo, NewUserResolver(newFakeUserRepo(streamer, other), blockchainRepo))
+
+	// Событие от другого стримера отклоняется и не меняет статус
+	foreign := newTestEvent(t, &contractABI, eventLog(t, contractABI, "WishAdded", []string{"other"}, big.NewInt(7), big.NewInt(1)))
+	if err := handler.handleWishAdded(ctx, foreign); err != nil {
+		t.Fatal(err)
+	}
+	if status := wishRepo.get("wish-1").Status; status != "pending" {
+		t.Fatalf("желание чужого стримера переведено в статус %s", status)
+	}
+	if _, ok := blockchainRepo.anomalies[foreign.Record.ID]; !ok {
+		t.Fatal("событие чужого стримера не записано как аномалия")
+	}
+
+	added := newTestEvent(t, &contractABI, eventLog(t, contractABI, "WishAdded", []string{"streamer"}, big.NewInt(7), big.NewInt(1)))
+	if err := handler.handleWishAdded(ctx, added); err != nil {
+		t.Fatal(err)
+	}
+	if status := wishRepo.get("wish-1").Status; status != "active" {
+		t.Fatalf("после WishAdded статус %s, ожидался active", status)
+	}
+	if !added.Record.Applied || added.Record.WishUUID != "wish-1" || added.Record.UserUUID != "streamer" {
+		t.Fatalf("запись события заполнена неверно: %+v", added.Record)
+	}
+
+	// Набравшее цель желание завершается так же, как активное
+	if err := wishRepo.UpdateStatus(ctx, "wish-1", "active", "funded"); err != nil {
+		t.Fatal(err)
+	}
+	completed := newTestEvent(t, &contractABI, eventLog(t, contractABI, "WishCompleted", []string{"streamer"}, big.NewInt(7), big.NewInt(1)))
+	if err := handler.handleWishCompleted(ctx, completed); err != nil {
+		t.Fatal(err)
+	}
+	if status := wishRepo.get("wish-1").Status; status != "complete" {
+		t.Fatalf("после WishCompleted статус %s, ожидался complete", status)
+	}
+	if completed.Record.PrevStatus != "funded" {
+		t.Fatalf("предыдущий статус %q, ожидался funded", completed.Record.PrevStatus)
+	}
+
+	unknown := newTestEvent(t, &contractABI, eventLog(t, contractABI, "WishDeleted", []string{"streamer"}, big.NewInt(42), big.NewInt(0)))
+	if err := handler.handleWishDeleted(ctx, unknown); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := blockchainRepo.anomalies[unknown.Record.ID]; !ok {
+		t.Fatal("событие с неизвестным wishId не записано как аномалия")
+	}
+}
+
+func TestWishHandlerAcceptsRelayerUUIDForRelayedWishes(t *testing.T) {
+	ctx := context.Background()
+	contractABI := donatesABI(t)
+
+	streamer := &entity.User{UUID: "streamer", UUIDHash: entity.UUIDTopicHash("streamer")}
+	wishRepo := newFakeWishRepo(
+		&entity.Wish{UUID: "relayed", OnchainID: 1, StreamerUUID: "streamer", Status: "active", RelayedBy: "0x00000000000000000000000000000000000000aa"},
+		&entity.Wish{UUID: "own", OnchainID: 2, StreamerUUID: "streamer", Status: "active"},
+	)
+	blockchainRepo := newFakeBlockchainRepo()
+	handler := NewWishHandler(wishRepo, NewUserResolver(newFakeUserRepo(streamer), blockchainRepo))
+	handler.TrustRelayer("relayer")
+
+	completed := newTestEvent(t, &contractABI, eventLog(t, contractABI, "WishCompleted", []string{"relayer"}, big.NewInt(1), big.NewInt(1)))
+	if err := handler.handleWishCompleted(ctx, completed); err != nil {
+		t.Fatal(err)
+	}
+	if status := wishRepo.get("relayed").Status; status != "complete" {
+		t.Fatalf("желание релеера в статусе %s, ожидался complete", status)
+	}
+	if completed.Record.UserUUID != "streamer" {
+		t.Fatalf("событие релеера записано на пользователя %q", completed.Record.UserUUID)
+	}
+
+	// UUID релеера не принимается для желаний, которые хранятся под адресом стримера
+	deleted := newTestEvent(t, &contractABI, eventLog(t, contractABI, "WishDeleted", []string{"relayer"}, big.NewInt(2), big.NewInt(0)))
+	if err := handler.handleWishDeleted(ctx, deleted); err != nil {
+		t.Fatal(err)
+	}
+	if status := wishRepo.get("own").Status; status != "active" {
+		t.Fatalf("желание стримера переведено событием релеера в статус %s", status)
+	}
+	if _, ok := blockchainRepo.anomalies[deleted.Record.ID]; !ok {
+		t.Fatal("событие релеера для чужого желания не записано как аномалия")
+	}
+}
+
+func TestWishHandlerFlagsPriceMismatch(t *testing.T) {
+	ctx := context.Background()
+	contractABI := donatesABI(t)
+
+	streamer := &entity.User{UUID: "streamer", UUIDHash: entity.UUIDTopicHash("streamer")}
+	wishRepo := newFakeWishRepo(&entity.Wish{UUID: "wish-1", OnchainID: 7, StreamerUUID: "streamer", PolTarget: entity.POL(10), Status: "pending"})
+	handler := NewWishHandler(wishRepo, NewUserResolver(newFakeUserRepo(streamer), newFakeBlockchainRepo()))
+
+	// Транзакцию подписал кошелёк стримера, и цена в ней отличается от целевой суммы
+	added := newTestEvent(t, &contractABI, eventLog(t, contractABI, "WishAdded", []string{"streamer"}, big.NewInt(7), big.NewInt(1e18)))
+	if err := handler.handleWishAdded(ctx, added); err != nil {
+		t.Fatal(err)
+	}
+	wish := wishRepo.get("wish-1")
+	if wish.Status != "mismatch" || wish.OnchainPrice == nil || wish.OnchainPrice.Cmp(entity.POL(1)) != 0 {
+		t.Fatalf("расхождение цены не отмечено: статус %s, цена в контракте %v", wish.Status, wish.OnchainPrice)
+	}
+	if !added.Record.Applied || added.Record.PrevStatus != "pending" {
+		t.Fatalf("запись события заполнена неверно: %+v", added.Record)
+	}
+
+	// Желание с расхождением можно удалить, а откат удаления возвращает его в mismatch
+	deleted := newTestEvent(t, &contractABI, eventLog(t, contractABI, "WishDeleted", []string{"streamer"}, big.NewInt(7), big.NewInt(0)))
+	if err := handler.handleWishDeleted(ctx, deleted); err != nil {
+		t.Fatal(err)
+	}
+	if status := wishRepo.get("wish-1").Status; status != "deleted" {
+		t.Fatalf("после WishDeleted статус %s, ожидался deleted", status)
+	}
+	if err := handler.rollback(ctx, 0, []*entity.BlockchainEvent{added.Record, deleted.Record}); err != nil {
+		t.Fatal(err)
+	}
+	if status := wishRepo.get("wish-1").Status; status != "pending" {
+		t.Fatalf("после отката статус %s, ожидался pending", status)
+	}
+
+	// Стример принимает цену из контракта
+	if err := handler.handleWishAdded(ctx, added); err != nil {
+		t.Fatal(err)
+	}
+	if err := wishRepo.AcceptOnchainPrice(ctx, "wish-1"); err != nil {
+		t.Fatal(err)
+	}
+	if wish := wishRepo.get("wish-1"); wish.Status != "active" || wish.PolTarget.Cmp(entity.POL(1)) != 0 {
+		t.Fatalf("цена из контракта не принята: статус %s, цель %s", wish.Status, wish.PolTarget)
+	}
+}
+
+func TestWishHandlerRemovesWishDeletedBeforeAdd(t *testing.T) {
+	ctx := context.Background()
+	contractABI := donatesABI(t)
+
+	streamer := &entity.User{UUID: "streamer", UUIDHash: entity.UUIDTopicHash("streamer")}
+	wishRepo := newFakeWishRepo(&entity.Wish{UUID: "wish-1", OnchainID: 7, StreamerUUID: "streamer", PolTarget: entity.NewAmount(big.NewInt(1)), Status: "deleted"})
+	blockchainRepo := newFakeBlockchainRepo()
+	handler := NewWishHandler(wishRepo, NewUserResolver(newFakeUserRepo(streamer), blockchainRepo))
+	var removed []*entity.Wish
+	handler.OnDeletedAdded(func(_ context.Context, wish *entity.Wish) error {
+		removed = append(removed, wish)
+		return nil
+	})
+
+	// Стример удалил желание, пока addWish ждал подтверждений
+	added := newTestEvent(t, &contractABI, eventLog(t, contractABI, "WishAdded", []string{"streamer"}, big.NewInt(7), big.NewInt(1)))
+	if err := handler.handleWishAdded(ctx, added); err != nil {
+		t.Fatal(err)
+	}
+	if status := wishRepo.get("wish-1").Status; status != "deleted" {
+		t.Fatalf("удалённое желание переведено в статус %s", status)
+	}
+	if added.Record.Applied {
+		t.Fatal("событие для удалённого желания отмечено применённым")
+	}
+	if _, ok := blockchainRepo.anomalies[added.Record.ID]; !ok {
+		t.Fatal("addWish удалённого желания не записан как аномалия")
+	}
+	if len(removed) != 1 || removed[0].UUID != "wish-1" || removed[0].ContractAddress != added.Log.Address.Hex() {
+		t.Fatalf("удаление из контракта не передано хуку: %+v", removed)
+	}
+
+	// При исторической загрузке хук не вызывается
+	replayed := newTestEvent(t, &contractABI, eventLog(t, contractABI, "WishAdded", []string{"streamer"}, big.NewInt(7), big.NewInt(1)))
+	replayed.Backfill = true
+	if err := handler.handleWishAdded(ctx, replayed); err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 {
+		t.Fatalf("хук вызван при исторической загрузке %d раз", len(removed)-1)
+	}
+}
+
+func TestWishEventsBoundToContract(t *testing.T) {
+	ctx := context.Background()
+	contractABI := donatesABI(t)
+
+	streamer := &entity.User{UUID: "streamer", UUIDHash: entity.UUIDTopicHash("streamer")}
+	wishRepo := newFakeWishRepo(&entity.Wish{UUID: "wish-1", OnchainID: 7, StreamerUUID: "streamer", PolTarget: entity.NewAmount(big.NewInt(1)), Status: "pending"})
+	blockchainRepo := newFakeBlockchainRepo()
+	handler := NewWishHandler(wishRepo, NewUserResolver(newFakeUserRepo(streamer), blockchainRepo))
+
+	added := newTestEvent(t, &contractABI, eventLog(t, contractABI, "WishAdded", []string{"streamer"}, big.NewInt(7), big.NewInt(1)))
+	added.Log.Address = common.HexToAddress("0x01")
+	if err := handler.handleWishAdded(ctx, added); err != nil {
+		t.Fatal(err)
+	}
+	if contract := wishRepo.get("wish-1").ContractAddress; contract != added.Log.Address.Hex() {
+		t.Fatalf("контракт желания %q, ожидался %s", contract, added.Log.Address.Hex())
+	}
+
+	// Тот же wishId в другом деплое относится к другому желанию
+	foreign := newTestEvent(t, &contractABI, eventLog(t, contractABI, "WishDeleted", []string{"streamer"}, big.NewInt(7), big.NewInt(0)))
+	foreign.Log.Address = common.HexToAddress("0x02")
+	if err := handler.handleWishDeleted(ctx, foreign); err != nil {
+		t.Fatal(err)
+	}
+	if status := wishRepo.get("wish-1").Status; status != "active" {
+		t.Fatalf("событие другого деплоя перевело желание в статус %s", status)
+	}
+	if _, ok := blockchainRepo.anomalies[foreign.Record.ID]; !ok {
+		t.Fatal("событие другого деплоя не записано как аномалия")
+	}
+
+	// Откат WishAdded снимает привязку к контракту
+	if err := handler.rollback(ctx, 0, []*entity.BlockchainEvent{added.Record}); err != nil {
+		t.Fatal(err)
+	}
+	if wish := wishRepo.get("wish-1"); wish.Status != "pending" || wish.ContractAddress != "" {
+		t.Fatalf("после отката status=%s contract=%q, ожидались pending без контракта", wish.Status, wish.ContractAddress)
+	}
+}
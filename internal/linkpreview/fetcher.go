@@ -0,0 +1,211 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	// ErrInvalidURL ссылка не является абсолютным http(s) URL
+	ErrInvalidURL = errors.New("invalid url")
+	// ErrForbiddenAddress ссылка ведёт во внутреннюю сеть или на нестандартный порт
+	ErrForbiddenAddress = errors.New("forbidden address")
+	// ErrUnsupportedContent ответ не является HTML страницей или изображением
+	ErrUnsupportedContent = errors.New("unsupported content")
+	// ErrTooLarge ответ больше допустимого размера
+	ErrTooLarge = errors.New("response too large")
+	// ErrFetch страница недоступна: ошибка сети, таймаут или код ответа не 2xx
+	ErrFetch = errors.New("fetch failed")
+)
+
+// Config ограничения загрузки страниц
+type Config struct {
+	// Timeout общее время на запрос, включая редиректы и чтение тела
+	Timeout time.Duration
+	// MaxPageSize максимальный размер HTML страницы
+	MaxPageSize int64
+	// MaxImageSize максимальный размер изображения
+	MaxImageSize int64
+	// MaxRedirects максимальное число редиректов
+	MaxRedirects int
+	// UserAgent заголовок User-Agent. Маркетплейсы отдают OpenGraph разметку не всем клиентам
+	UserAgent string
+}
+
+func (c Config) withDefaults() Config {
+	if c.Timeout <= 0 {
+		c.Timeout = 5 * time.Second
+	}
+	if c.MaxPageSize <= 0 {
+		c.MaxPageSize = 2 << 20
+	}
+	if c.MaxImageSize <= 0 {
+		c.MaxImageSize = 10 << 20
+	}
+	if c.MaxRedirects <= 0 {
+		c.MaxRedirects = 3
+	}
+	if c.UserAgent == "" {
+		c.UserAgent = "Mozilla/5.0 (compatible; DonlyBot/1.0; +https://donly.app)"
+	}
+	return c
+}
+
+// Fetcher загружает страницы и изображения по ссылкам пользователей. Адрес проверяется при каждом
+// соединении, уже после разрешения DNS, поэтому ни редирект, ни DNS rebinding не приводят во внутреннюю сеть
+type Fetcher struct {
+	client *http.Client
+	config Config
+	// allowAddr решает, можно ли соединяться с адресом. В тестах заменяется, чтобы обращаться к httptest
+	allowAddr func(netip.AddrPort) bool
+}
+
+func NewFetcher(config Config) *Fetcher {
+	f := &Fetcher{config: config.withDefaults(), allowAddr: isPublicAddr}
+	dialer := &net.Dialer{
+		Timeout: f.config.Timeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !f.allowAddr(addrPort) {
+				return ErrForbiddenAddress
+			}
+			return nil
+		},
+	}
+	f.client = &http.Client{
+		Timeout: f.config.Timeout,
+		Transport: &http.Transport{
+			// Прокси из окружения обошёл бы проверку адреса
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   f.config.Timeout,
+			ResponseHeaderTimeout: f.config.Timeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > f.config.MaxRedirects {
+				return fmt.Errorf("%w: слишком много редиректов", ErrFetch)
+			}
+			_, err := ParseURL(req.URL.String())
+			return err
+		},
+	}
+	return f
+}
+
+// ParseURL проверяет, что ссылка — абсолютный http(s) URL без учётных данных
+func ParseURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || u.User != nil {
+		return nil, ErrInvalidURL
+	}
+	return u, nil
+}
+
+// FetchPage загружает HTML страницу и возвращает её тело и итоговый URL после редиректов
+func (f *Fetcher) FetchPage(ctx context.Context, rawURL string) ([]byte, *url.URL, error) {
+	body, contentType, finalURL, err := f.fetch(ctx, rawURL, "text/html,application/xhtml+xml", f.config.MaxPageSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	if contentType != "text/html" && contentType != "application/xhtml+xml" {
+		return nil, nil, ErrUnsupportedContent
+	}
+	return body, finalURL, nil
+}
+
+// FetchImage загружает изображение и возвращает его тело и тип содержимого
+func (f *Fetcher) FetchImage(ctx context.Context, rawURL string) ([]byte, string, error) {
+	body, contentType, _, err := f.fetch(ctx, rawURL, "image/jpeg,image/png", f.config.MaxImageSize)
+	if err != nil {
+		return nil, "", err
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, "", ErrUnsupportedContent
+	}
+	return body, contentType, nil
+}
+
+func (f *Fetcher) fetch(ctx context.Context, rawURL, accept string, maxSize int64) ([]byte, string, *url.URL, error) {
+	u, err := ParseURL(rawURL)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, "", nil, ErrInvalidURL
+	}
+	req.Header.Set("User-Agent", f.config.UserAgent)
+	req.Header.Set("Accept", accept)
+	req.Header.Set("Accept-Language", "ru,en;q=0.8")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		for _, target := range []error{ErrForbiddenAddress, ErrInvalidURL} {
+			if errors.Is(err, target) {
+				return nil, "", nil, target
+			}
+		}
+		return nil, "", nil, fmt.Errorf("%w: %v", ErrFetch, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, "", nil, fmt.Errorf("%w: код ответа %d", ErrFetch, resp.StatusCode)
+	}
+	if resp.ContentLength > maxSize {
+		return nil, "", nil, ErrTooLarge
+	}
+
+	// Читаем на байт больше лимита, чтобы отличить ответ ровно в лимит от обрезанного
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("%w: %v", ErrFetch, err)
+	}
+	if int64(len(body)) > maxSize {
+		return nil, "", nil, ErrTooLarge
+	}
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return body, contentType, resp.Request.URL, nil
+}
+
+// isPublicAddr разрешает соединения только с публичными адресами на стандартных портах HTTP(S)
+func isPublicAddr(addrPort netip.AddrPort) bool {
+	if port := addrPort.Port(); port != 80 && port != 443 {
+		return false
+	}
+	addr := addrPort.Addr().Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() {
+		return false
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// reservedPrefixes диапазоны, которые IsGlobalUnicast считает публичными, но которые не маршрутизируются в интернет
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // CGNAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64 отображает на IPv4, включая внутренние адреса
+	netip.MustParsePrefix("2001:db8::/32"),
+}
@@ -0,0 +1,254 @@
+package linkpreview
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Preview данные товара со страницы. Пустые поля на странице не найдены
+type Preview struct {
+	URL         string // канонический URL страницы или URL после редиректов
+	SiteName    string
+	Title       string
+	Description string
+	Image       string // абсолютный URL изображения
+	Price       string // десятичное число в валюте Currency
+	Currency    string
+}
+
+// Parse извлекает описание товара из HTML страницы. Разметка schema.org Product в JSON-LD точнее
+// OpenGraph, поэтому её поля имеют приоритет; заголовок и meta description используются последними
+func Parse(body []byte, base *url.URL) Preview {
+	page := scan(body)
+
+	var preview Preview
+	if product := findProduct(page.jsonLD); product != nil {
+		preview.Title = stringField(product["name"])
+		preview.Description = stringField(product["description"])
+		preview.Image = imageField(product["image"])
+		preview.Price, preview.Currency = offerPrice(product["offers"])
+	}
+
+	preview.SiteName = page.meta["og:site_name"]
+	preview.Title = firstNonEmpty(preview.Title, page.meta["og:title"], page.meta["twitter:title"], page.title)
+	preview.Description = firstNonEmpty(preview.Description, page.meta["og:description"], page.meta["twitter:description"], page.meta["description"])
+	preview.Image = firstNonEmpty(preview.Image, page.meta["og:image"], page.meta["og:image:url"], page.meta["twitter:image"])
+	if preview.Price == "" {
+		preview.Price = firstNonEmpty(page.meta["product:price:amount"], page.meta["og:price:amount"])
+		preview.Currency = firstNonEmpty(page.meta["product:price:currency"], page.meta["og:price:currency"])
+	}
+
+	preview.Title = normalizeSpace(preview.Title)
+	preview.Description = normalizeSpace(preview.Description)
+	preview.Price = normalizePrice(preview.Price)
+	if preview.Price == "" {
+		preview.Currency = ""
+	}
+	preview.Currency = strings.ToUpper(strings.TrimSpace(preview.Currency))
+	preview.Image = resolve(base, preview.Image)
+	preview.URL = firstNonEmpty(resolve(base, firstNonEmpty(page.canonical, page.meta["og:url"])), base.String())
+	return preview
+}
+
+// page разметка страницы, из которой собирается описание
+type page struct {
+	title     string
+	canonical string
+	meta      map[string]string
+	jsonLD    []string
+}
+
+// scan проходит по токенам HTML и собирает meta теги, заголовок, canonical и блоки JSON-LD.
+// Полное дерево не строится: нужные теги находятся в head и не зависят от вложенности
+func scan(body []byte) page {
+	result := page{meta: make(map[string]string)}
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return result
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.DataAtom {
+			case atom.Meta:
+				key := strings.ToLower(firstNonEmpty(attr(token, "property"), attr(token, "name"), attr(token, "itemprop")))
+				// Первое значение обычно основное: og:image повторяется для дополнительных фотографий
+				if _, seen := result.meta[key]; key != "" && !seen {
+					result.meta[key] = strings.TrimSpace(attr(token, "content"))
+				}
+			case atom.Link:
+				if strings.EqualFold(attr(token, "rel"), "canonical") && result.canonical == "" {
+					result.canonical = attr(token, "href")
+				}
+			case atom.Title:
+				if result.title == "" && tokenizer.Next() == html.TextToken {
+					result.title = string(tokenizer.Text())
+				}
+			case atom.Script:
+				if strings.EqualFold(attr(token, "type"), "application/ld+json") && tokenizer.Next() == html.TextToken {
+					result.jsonLD = append(result.jsonLD, string(tokenizer.Text()))
+				}
+			}
+		}
+	}
+}
+
+func attr(token html.Token, name string) string {
+	for _, a := range token.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// findProduct ищет объект с @type Product в блоках JSON-LD, включая массивы и @graph
+func findProduct(blocks []string) map[string]interface{} {
+	for _, block := range blocks {
+		var data interface{}
+		if err := json.Unmarshal([]byte(block), &data); err != nil {
+			continue
+		}
+		if product := productNode(data); product != nil {
+			return product
+		}
+	}
+	return nil
+}
+
+func productNode(data interface{}) map[string]interface{} {
+	switch node := data.(type) {
+	case []interface{}:
+		for _, item := range node {
+			if product := productNode(item); product != nil {
+				return product
+			}
+		}
+	case map[string]interface{}:
+		if hasType(node["@type"], "Product") {
+			return node
+		}
+		if graph, ok := node["@graph"]; ok {
+			return productNode(graph)
+		}
+	}
+	return nil
+}
+
+func hasType(value interface{}, name string) bool {
+	switch t := value.(type) {
+	case string:
+		return t == name
+	case []interface{}:
+		for _, item := range t {
+			if s, ok := item.(string); ok && s == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// offerPrice возвращает цену и валюту из offers: объекта Offer, AggregateOffer или массива предложений
+func offerPrice(value interface{}) (string, string) {
+	switch offers := value.(type) {
+	case []interface{}:
+		for _, offer := range offers {
+			if price, currency := offerPrice(offer); price != "" {
+				return price, currency
+			}
+		}
+	case map[string]interface{}:
+		price := firstNonEmpty(stringField(offers["price"]), stringField(offers["lowPrice"]))
+		if price == "" {
+			if spec, ok := offers["priceSpecification"].(map[string]interface{}); ok {
+				return stringField(spec["price"]), stringField(spec["priceCurrency"])
+			}
+		}
+		return price, stringField(offers["priceCurrency"])
+	}
+	return "", ""
+}
+
+// stringField читает строку или число из JSON-LD
+func stringField(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+// imageField читает image, заданный строкой, массивом или объектом ImageObject
+func imageField(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []interface{}:
+		for _, item := range v {
+			if image := imageField(item); image != "" {
+				return image
+			}
+		}
+	case map[string]interface{}:
+		return firstNonEmpty(stringField(v["url"]), stringField(v["contentUrl"]))
+	}
+	return ""
+}
+
+// resolve приводит ссылку к абсолютному http(s) URL относительно страницы
+func resolve(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
+
+var (
+	spaceRe = regexp.MustCompile(`\s+`)
+	priceRe = regexp.MustCompile(`^\d+(\.\d+)?$`)
+)
+
+func normalizeSpace(s string) string {
+	return strings.TrimSpace(spaceRe.ReplaceAllString(s, " "))
+}
+
+// normalizePrice приводит цену вида "1 299,90" к "1299.90". Нераспознанная цена отбрасывается
+func normalizePrice(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\u00a0', '\u202f':
+			return -1
+		case ',':
+			return '.'
+		}
+		return r
+	}, s)
+	if !priceRe.MatchString(s) {
+		return ""
+	}
+	return s
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return value
+		}
+	}
+	return ""
+}
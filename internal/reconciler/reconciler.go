@@ -0,0 +1,398 @@
+package reconciler
+
+import (
+	"backend/internal/contract"
+	"backend/internal/entity"
+	"backend/internal/repo"
+	"backend/pkg/ethrpc"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+)
+
+// snapshotAttempts сколько раз сверка пользователя повторяется, если индексатор продвинулся во время чтения
+const snapshotAttempts = 3
+
+// Config параметры сверки
+type Config struct {
+	// Interval интервал между сверками
+	Interval time.Duration
+	// AutoRepair исправлять статусы желаний и накопленные суммы. Исправляются только расхождения,
+	// найденные две сверки подряд, чтобы не откатить изменения, которые индексатор ещё не успел записать
+	AutoRepair bool
+	// SkipBalance не сверять баланс стримера. История платежей не разделена по деплоям контракта,
+	// поэтому после перехода на новый деплой её итоги не совпадают с балансом в действующем контракте
+	SkipBalance bool
+}
+
+// Reconciler периодически сверяет состояние пользователей в контракте (users(address)) с базой:
+// статусы и цены желаний, накопленные суммы желаний по истории и баланс стримера.
+// Пропущенный лог иначе оставил бы базу расходящейся с контрактом навсегда
+type Reconciler struct {
+	client         ethrpc.Client
+	contract       *contract.Donates
+	userRepo       repo.UserRepository
+	wishRepo       repo.WishRepository
+	historyRepo    repo.HistoryRepository
+	blockchainRepo repo.BlockchainRepository
+	reportRepo     repo.ReconciliationRepository
+	config         Config
+
+	// previous расхождения предыдущей сверки
+	previous map[string]bool
+}
+
+func New(
+	client ethrpc.Client,
+	donates *contract.Donates,
+	userRepo repo.UserRepository,
+	wishRepo repo.WishRepository,
+	historyRepo repo.HistoryRepository,
+	blockchainRepo repo.BlockchainRepository,
+	reportRepo repo.ReconciliationRepository,
+	config Config,
+) *Reconciler {
+	if config.Interval <= 0 {
+		config.Interval = time.Hour
+	}
+	return &Reconciler{
+		client:         client,
+		contract:       donates,
+		userRepo:       userRepo,
+		wishRepo:       wishRepo,
+		historyRepo:    historyRepo,
+		blockchainRepo: blockchainRepo,
+		reportRepo:     reportRepo,
+		config:         config,
+		previous:       make(map[string]bool),
+	}
+}
+
+// Run запускает сверку сразу и затем с интервалом из конфигурации до отмены ctx
+func (r *Reconciler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.Reconcile(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Ошибка сверки с контрактом: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			log.Println("Сверка с контрактом остановлена")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile сверяет всех пользователей с кошельком и сохраняет отчёт о расхождениях
+func (r *Reconciler) Reconcile(ctx context.Context) (*entity.ReconciliationReport, error) {
+	report := &entity.ReconciliationReport{
+		ID:         uuid.New().String(),
+		StartedAt:  time.Now(),
+		AutoRepair: r.config.AutoRepair,
+		Drifts:     []entity.ReconciliationDrift{},
+	}
+
+	users, err := r.userRepo.GetWithWallet(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения пользователей: %w", err)
+	}
+	rate, err := r.contract.ReadCommissionRate(ctx, r.client, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	current := make(map[string]bool)
+	for _, user := range users {
+		if !common.IsHexAddress(user.PolygonWallet) {
+			continue
+		}
+		drifts, wishes, err := r.reconcileUser(ctx, user, rate)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", user.UUID, err))
+			continue
+		}
+		report.UsersChecked++
+		report.WishesChecked += wishes
+		for _, drift := range drifts {
+			current[driftKey(drift)] = true
+			report.Drifts = append(report.Drifts, drift)
+		}
+	}
+	r.previous = current
+	report.FinishedAt = time.Now()
+
+	if err := r.reportRepo.Add(ctx, report); err != nil {
+		return nil, fmt.Errorf("ошибка сохранения отчёта сверки: %w", err)
+	}
+	repaired := 0
+	for _, drift := range report.Drifts {
+		if drift.Repaired {
+			repaired++
+		}
+	}
+	log.Printf("Сверка с контрактом завершена: пользователей %d, желаний %d, расхождений %d, исправлено %d",
+		report.UsersChecked, report.WishesChecked, len(report.Drifts), repaired)
+	return report, nil
+}
+
+// userSnapshot состояние пользователя в базе и в контракте на один и тот же обработанный блок
+type userSnapshot struct {
+	block   uint64
+	wishes  []*entity.Wish
+	totals  *entity.HistoryTotals
+	onchain *contract.OnchainUser
+	// holders содержимое массивов желаний по адресам, под которыми хранятся желания пользователя
+	holders map[common.Address]map[uint64]contract.OnchainWish
+}
+
+// reconcileUser сверяет одного пользователя и возвращает расхождения и число проверенных желаний
+func (r *Reconciler) reconcileUser(ctx context.Context, user *entity.User, rate *big.Int) ([]entity.ReconciliationDrift, int, error) {
+	snapshot, err := r.snapshot(ctx, user)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var drifts []entity.ReconciliationDrift
+	wallet := common.HexToAddress(user.PolygonWallet)
+	for _, wish := range snapshot.wishes {
+		holder := wallet
+		if wish.RelayedBy != "" {
+			holder = common.HexToAddress(wish.RelayedBy)
+		}
+		onchain, found := snapshot.holders[holder][wish.OnchainID]
+		if drift := r.checkStatus(ctx, wish, onchain, found); drift != nil {
+			drifts = append(drifts, *drift)
+		}
+		if found && !onchain.Completed && (wish.Status == "active" || wish.Status == "funded") && wish.PolTarget.Wei().Cmp(onchain.Price) != 0 {
+			drifts = append(drifts, entity.ReconciliationDrift{
+				Kind:      entity.DriftWishPrice,
+				UserUUID:  user.UUID,
+				WishUUID:  wish.UUID,
+				OnchainID: wish.OnchainID,
+				Onchain:   entity.NewAmount(onchain.Price).String(),
+				Stored:    wish.PolTarget.String(),
+			})
+		}
+		drift, err := r.checkAmount(ctx, wish)
+		if err != nil {
+			return nil, 0, err
+		}
+		if drift != nil {
+			drifts = append(drifts, *drift)
+		}
+	}
+
+	if !r.config.SkipBalance {
+		if drift := checkBalance(user, snapshot, rate); drift != nil {
+			drifts = append(drifts, *drift)
+		}
+	}
+	return drifts, len(snapshot.wishes), nil
+}
+
+// snapshot читает контракт на последнем обработанном индексатором блоке. Если за время чтения индексатор
+// обработал новые блоки, чтение повторяется, чтобы не сравнивать базу с более старым состоянием контракта
+func (r *Reconciler) snapshot(ctx context.Context, user *entity.User) (*userSnapshot, error) {
+	for attempt := 0; attempt < snapshotAttempts; attempt++ {
+		block, err := r.blockchainRepo.GetCheckpoint(ctx, r.contract.Address().Hex())
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения последнего обработанного блока: %w", err)
+		}
+		if block == 0 {
+			return nil, errors.New("индексатор ещё не обработал ни одного блока")
+		}
+		snapshot := &userSnapshot{block: block, holders: make(map[common.Address]map[uint64]contract.OnchainWish)}
+
+		wishes, err := r.wishRepo.GetByStreamerUUID(ctx, user.UUID)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения желаний: %w", err)
+		}
+		// Желания из выведенных из работы деплоев в действующем контракте не хранятся
+		for _, wish := range wishes {
+			if wish.ContractAddress == "" || wish.ContractAddress == r.contract.Address().Hex() {
+				snapshot.wishes = append(snapshot.wishes, wish)
+			}
+		}
+		if snapshot.totals, err = r.historyRepo.GetTotals(ctx, user.UUID); err != nil {
+			return nil, fmt.Errorf("ошибка подсчёта истории: %w", err)
+		}
+
+		wallet := common.HexToAddress(user.PolygonWallet)
+		if snapshot.onchain, err = r.readUser(ctx, wallet, block); err != nil {
+			return nil, err
+		}
+		snapshot.holders[wallet] = wishesByID(snapshot.onchain)
+		for _, wish := range snapshot.wishes {
+			if wish.RelayedBy == "" {
+				continue
+			}
+			holder := common.HexToAddress(wish.RelayedBy)
+			if _, ok := snapshot.holders[holder]; ok {
+				continue
+			}
+			relayer, err := r.readUser(ctx, holder, block)
+			if err != nil {
+				return nil, err
+			}
+			snapshot.holders[holder] = wishesByID(relayer)
+		}
+
+		latest, err := r.blockchainRepo.GetCheckpoint(ctx, r.contract.Address().Hex())
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения последнего обработанного блока: %w", err)
+		}
+		if latest == block {
+			return snapshot, nil
+		}
+	}
+	return nil, errors.New("индексатор продвигается быстрее, чем выполняется чтение, сверка отложена")
+}
+
+// checkStatus сравнивает статус желания с его наличием в массиве желаний контракта
+func (r *Reconciler) checkStatus(ctx context.Context, wish *entity.Wish, onchain contract.OnchainWish, found bool) *entity.ReconciliationDrift {
+	expected := wish.Status
+	switch {
+	case found && onchain.Completed:
+		expected = "complete"
+	case found && (wish.Status == "active" || wish.Status == "mismatch" || wish.Status == "funded"):
+	case found && wish.Status == "pending" && wish.PolTarget.Wei().Cmp(onchain.Price) != 0:
+		expected = "mismatch"
+	case found:
+		expected = "active"
+	case wish.Status == "active" || wish.Status == "mismatch" || wish.Status == "funded":
+		// Из массива релеера удаляются только завершённые желания
+		expected = "deleted"
+		if wish.RelayedBy != "" && wish.RelayReleased {
+			expected = "complete"
+		}
+	}
+	if expected == wish.Status {
+		return nil
+	}
+
+	drift := &entity.ReconciliationDrift{
+		Kind:      entity.DriftWishStatus,
+		UserUUID:  wish.StreamerUUID,
+		WishUUID:  wish.UUID,
+		OnchainID: wish.OnchainID,
+		Onchain:   expected,
+		Stored:    wish.Status,
+	}
+	if !r.shouldRepair(drift) {
+		return drift
+	}
+
+	var err error
+	if expected == "mismatch" {
+		err = r.wishRepo.MarkPriceMismatch(ctx, wish.UUID, entity.NewAmount(onchain.Price))
+	} else {
+		err = r.wishRepo.UpdateStatus(ctx, wish.UUID, wish.Status, expected)
+	}
+	if err != nil {
+		log.Printf("Ошибка исправления статуса желания %s: %v", wish.UUID, err)
+		return drift
+	}
+	drift.Repaired = true
+	log.Printf("Статус желания %s исправлен по контракту: %s -> %s", wish.UUID, wish.Status, expected)
+	return drift
+}
+
+// checkAmount сравнивает накопленную сумму желания с суммой донатов на него в истории
+func (r *Reconciler) checkAmount(ctx context.Context, wish *entity.Wish) (*entity.ReconciliationDrift, error) {
+	histories, err := r.historyRepo.GetByWishUUID(ctx, wish.UUID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения донатов на желание %s: %w", wish.UUID, err)
+	}
+	var total entity.Amount
+	paymentIDs := make([]string, 0, len(histories))
+	for _, history := range histories {
+		total = total.Add(history.Amount)
+		paymentIDs = append(paymentIDs, history.ID)
+	}
+	if total.Cmp(wish.PolAmount) == 0 {
+		return nil, nil
+	}
+
+	drift := &entity.ReconciliationDrift{
+		Kind:      entity.DriftWishAmount,
+		UserUUID:  wish.StreamerUUID,
+		WishUUID:  wish.UUID,
+		OnchainID: wish.OnchainID,
+		Onchain:   total.String(),
+		Stored:    wish.PolAmount.String(),
+	}
+	if !r.shouldRepair(drift) {
+		return drift, nil
+	}
+	if err := r.wishRepo.RepairCredits(ctx, wish.UUID, wish.PolAmount, paymentIDs, total); err != nil {
+		log.Printf("Ошибка исправления накопленной суммы желания %s: %v", wish.UUID, err)
+		return drift, nil
+	}
+	drift.Repaired = true
+	log.Printf("Накопленная сумма желания %s исправлена по истории: %s -> %s POL", wish.UUID, wish.PolAmount, total)
+	return drift, nil
+}
+
+// checkBalance сравнивает баланс стримера в контракте с балансом по истории: зачисленные суммы донатов минус выводы.
+// Для донатов, проиндексированных до сохранения комиссии, она считается по K. Контракт округляет комиссию вниз
+// по каждому донату, поэтому для них допускается расхождение до числа таких донатов в wei.
+// Баланс не исправляется: пропущенные платежи восстанавливает историческая загрузка
+func checkBalance(user *entity.User, snapshot *userSnapshot, rate *big.Int) *entity.ReconciliationDrift {
+	totals := snapshot.totals
+	legacy := totals.LegacyDonated.Wei()
+	legacyCommission := new(big.Int).Div(new(big.Int).Mul(legacy, rate), big.NewInt(1000))
+	expected := new(big.Int).Add(totals.DonatedNet.Wei(), legacy)
+	expected.Sub(expected, legacyCommission)
+	expected.Sub(expected, totals.Withdrawn.Wei())
+
+	onchain := snapshot.onchain.Balance
+	if onchain == nil {
+		onchain = new(big.Int)
+	}
+	diff := new(big.Int).Sub(onchain, expected)
+	if diff.Sign() >= 0 && diff.Cmp(big.NewInt(totals.LegacyDonations)) <= 0 {
+		return nil
+	}
+	return &entity.ReconciliationDrift{
+		Kind:     entity.DriftBalance,
+		UserUUID: user.UUID,
+		Onchain:  entity.NewAmount(onchain).String(),
+		Stored:   entity.NewAmount(expected).String(),
+	}
+}
+
+// shouldRepair сообщает, нужно ли исправлять расхождение: автоисправление включено и расхождение найдено и в прошлой сверке
+func (r *Reconciler) shouldRepair(drift *entity.ReconciliationDrift) bool {
+	return r.config.AutoRepair && r.previous[driftKey(*drift)]
+}
+
+// readUser читает users(address) на указанном блоке
+func (r *Reconciler) readUser(ctx context.Context, addr common.Address, block uint64) (*contract.OnchainUser, error) {
+	return r.contract.ReadUser(ctx, r.client, addr, new(big.Int).SetUint64(block))
+}
+
+func wishesByID(user *contract.OnchainUser) map[uint64]contract.OnchainWish {
+	wishes := make(map[uint64]contract.OnchainWish, len(user.Wishes))
+	for _, wish := range user.Wishes {
+		wishes[wish.ID] = wish
+	}
+	return wishes
+}
+
+// driftKey идентифицирует расхождение между сверками
+func driftKey(drift entity.ReconciliationDrift) string {
+	return strings.Join([]string{drift.Kind, drift.UserUUID, drift.WishUUID, drift.Onchain, drift.Stored}, "|")
+}
@@ -0,0 +1,356 @@
+package reconciler
+
+import (
+	"backend/internal/abi"
+	"backend/internal/contract"
+	"backend/internal/entity"
+	"backend/internal/repo"
+	"backend/pkg/ethrpc"
+	"context"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	ethabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const processedBlock = 100
+
+var streamerWallet = common.HexToAddress("0x1000000000000000000000000000000000000001")
+
+// fakeClient отвечает на users(address) и K() состоянием, заданным в тесте. Остальные методы ethrpc.Client не используются
+type fakeClient struct {
+	ethrpc.Client
+	t        *testing.T
+	abi      ethabi.ABI
+	rate     int64
+	balances map[common.Address]*big.Int
+	wishes   map[common.Address][]contract.OnchainWish
+}
+
+func (c *fakeClient) CallContract(_ context.Context, msg ethereum.CallMsg, block *big.Int) ([]byte, error) {
+	method, err := c.abi.MethodById(msg.Data[:4])
+	if err != nil {
+		return nil, err
+	}
+	if method.Name == "K" {
+		return method.Outputs.Pack(big.NewInt(c.rate))
+	}
+	if block == nil || block.Uint64() != processedBlock {
+		c.t.Fatalf("users прочитан на блоке %v, ожидался последний обработанный", block)
+	}
+	args, err := method.Inputs.Unpack(msg.Data[4:])
+	if err != nil {
+		return nil, err
+	}
+	addr := args[0].(common.Address)
+	return method.Outputs.Pack(c.onchainUser(method.Outputs[0].Type, addr), c.balance(addr))
+}
+
+func (c *fakeClient) balance(addr common.Address) *big.Int {
+	if balance, ok := c.balances[addr]; ok {
+		return balance
+	}
+	return new(big.Int)
+}
+
+// onchainUser собирает значение tuple пользователя из ABI с желаниями, заданными в тесте
+func (c *fakeClient) onchainUser(t ethabi.Type, addr common.Address) interface{} {
+	user := reflect.New(t.GetType()).Elem()
+	for i, name := range t.TupleRawNames {
+		field := user.Field(i)
+		switch name {
+		case "uuid":
+			if len(c.wishes[addr]) > 0 {
+				field.SetString("streamer")
+			}
+		case "wishes":
+			wishes := reflect.MakeSlice(field.Type(), 0, len(c.wishes[addr]))
+			elemType := t.TupleElems[i].Elem
+			for _, wish := range c.wishes[addr] {
+				elem := reflect.New(elemType.GetType()).Elem()
+				for j, wishField := range elemType.TupleRawNames {
+					switch wishField {
+					case "id":
+						elem.Field(j).Set(reflect.ValueOf(new(big.Int).SetUint64(wish.ID)))
+					case "price":
+						elem.Field(j).Set(reflect.ValueOf(wish.Price))
+					case "currentBalance":
+						elem.Field(j).Set(reflect.ValueOf(new(big.Int)))
+					case "completed":
+						elem.Field(j).SetBool(wish.Completed)
+					}
+				}
+				wishes = reflect.Append(wishes, elem)
+			}
+			field.Set(wishes)
+		}
+	}
+	return user.Interface()
+}
+
+type fakeUserRepo struct {
+	repo.UserRepository
+	users []*entity.User
+}
+
+func (r *fakeUserRepo) GetWithWallet(context.Context) ([]*entity.User, error) {
+	return r.users, nil
+}
+
+type fakeWishRepo struct {
+	repo.WishRepository
+	wishes []*entity.Wish
+}
+
+func (r *fakeWishRepo) get(uuid string) *entity.Wish {
+	for _, wish := range r.wishes {
+		if wish.UUID == uuid {
+			return wish
+		}
+	}
+	return nil
+}
+
+func (r *fakeWishRepo) GetByStreamerUUID(_ context.Context, streamerUUID string) ([]*entity.Wish, error) {
+	var result []*entity.Wish
+	for _, wish := range r.wishes {
+		if wish.StreamerUUID == streamerUUID {
+			copied := *wish
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeWishRepo) UpdateStatus(_ context.Context, uuid, from, to string) error {
+	wish := r.get(uuid)
+	if wish.Status != from {
+		return repo.ErrWishStatusChanged
+	}
+	wish.Status = to
+	return nil
+}
+
+func (r *fakeWishRepo) MarkPriceMismatch(_ context.Context, uuid string, onchainPrice entity.Amount) error {
+	wish := r.get(uuid)
+	if wish.Status != "pending" {
+		return repo.ErrWishStatusChanged
+	}
+	wish.Status = "mismatch"
+	wish.OnchainPrice = &onchainPrice
+	return nil
+}
+
+func (r *fakeWishRepo) RepairCredits(_ context.Context, uuid string, stored entity.Amount, paymentIDs []string, amount entity.Amount) error {
+	wish := r.get(uuid)
+	if wish.PolAmount.Cmp(stored) != 0 {
+		return repo.ErrWishStatusChanged
+	}
+	wish.CreditedPayments = paymentIDs
+	wish.PolAmount = amount
+	return nil
+}
+
+type fakeHistoryRepo struct {
+	repo.HistoryRepository
+	history []*entity.History
+}
+
+func (r *fakeHistoryRepo) GetByWishUUID(_ context.Context, wishUUID string) ([]*entity.History, error) {
+	var result []*entity.History
+	for _, history := range r.history {
+		if history.Type == "donate" && history.WishUUID != nil && *history.WishUUID == wishUUID {
+			result = append(result, history)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeHistoryRepo) GetTotals(_ context.Context, streamerUUID string) (*entity.HistoryTotals, error) {
+	totals := &entity.HistoryTotals{}
+	for _, history := range r.history {
+		if history.StreamerUUID != streamerUUID {
+			continue
+		}
+		switch history.Type {
+		case "donate":
+			totals.Donated = totals.Donated.Add(history.Amount)
+			totals.Donations++
+			// Записи без зачисленной суммы проиндексированы до сохранения комиссии
+			if history.NetAmount.IsZero() {
+				totals.LegacyDonated = totals.LegacyDonated.Add(history.Amount)
+				totals.LegacyDonations++
+				continue
+			}
+			totals.DonatedNet = totals.DonatedNet.Add(history.NetAmount)
+			totals.Commission = totals.Commission.Add(history.Commission)
+		case "withdraw":
+			totals.Withdrawn = totals.Withdrawn.Add(history.Amount)
+		}
+	}
+	return totals, nil
+}
+
+type fakeBlockchainRepo struct {
+	repo.BlockchainRepository
+}
+
+func (fakeBlockchainRepo) GetCheckpoint(context.Context, string) (uint64, error) {
+	return processedBlock, nil
+}
+
+type fakeReportRepo struct {
+	reports []*entity.ReconciliationReport
+}
+
+func (r *fakeReportRepo) Add(_ context.Context, report *entity.ReconciliationReport) error {
+	r.reports = append(r.reports, report)
+	return nil
+}
+
+type fixture struct {
+	reconciler *Reconciler
+	client     *fakeClient
+	wishRepo   *fakeWishRepo
+	reportRepo *fakeReportRepo
+}
+
+// newFixture готовит стримера с четырьмя желаниями, каждое из которых расходится с контрактом по-своему
+func newFixture(t *testing.T, autoRepair bool) *fixture {
+	t.Helper()
+	contractABI, err := ethabi.JSON(strings.NewReader(abi.DonatesABI))
+	if err != nil {
+		t.Fatalf("ошибка разбора ABI: %v", err)
+	}
+	wishUUID := "donated"
+	client := &fakeClient{
+		t:    t,
+		abi:  contractABI,
+		rate: 50,
+		// 2 POL доната без сохранённой комиссии за вычетом 5% и 0.95 POL, зачисленных с доната в 1 POL
+		balances: map[common.Address]*big.Int{streamerWallet: entity.POLFromFloat(2.85).Wei()},
+		wishes: map[common.Address][]contract.OnchainWish{streamerWallet: {
+			{ID: 1, Price: entity.POL(1).Wei()},
+			{ID: 2, Price: entity.POL(1).Wei(), Completed: true},
+			{ID: 4, Price: entity.POL(10).Wei()},
+		}},
+	}
+	wishRepo := &fakeWishRepo{wishes: []*entity.Wish{
+		// WishAdded пропущен
+		{UUID: "added", OnchainID: 1, StreamerUUID: "streamer", PolTarget: entity.POL(1), Status: "pending"},
+		// WishCompleted пропущен
+		{UUID: "completed", OnchainID: 2, StreamerUUID: "streamer", PolTarget: entity.POL(1), Status: "active"},
+		// WishDeleted пропущен
+		{UUID: "deleted", OnchainID: 3, StreamerUUID: "streamer", PolTarget: entity.POL(1), Status: "active"},
+		// Зачислено больше, чем донатов на желание в истории
+		{UUID: "donated", OnchainID: 4, StreamerUUID: "streamer", PolTarget: entity.POL(10), PolAmount: entity.POL(5), Status: "active"},
+	}}
+	historyRepo := &fakeHistoryRepo{history: []*entity.History{
+		{ID: "payment-1", StreamerUUID: "streamer", Type: "donate", Amount: entity.POL(2), WishUUID: &wishUUID},
+		{ID: "payment-2", StreamerUUID: "streamer", Type: "donate", Amount: entity.POL(1), NetAmount: entity.POLFromFloat(0.95), Commission: entity.POLFromFloat(0.05)},
+	}}
+	userRepo := &fakeUserRepo{users: []*entity.User{{UUID: "streamer", PolygonWallet: streamerWallet.Hex()}}}
+	reportRepo := &fakeReportRepo{}
+
+	donates := contract.NewDonates(contractABI, common.HexToAddress("0xc0ffee"))
+	r := New(client, donates, userRepo, wishRepo, historyRepo, fakeBlockchainRepo{}, reportRepo, Config{AutoRepair: autoRepair})
+	return &fixture{reconciler: r, client: client, wishRepo: wishRepo, reportRepo: reportRepo}
+}
+
+func driftsByWish(report *entity.ReconciliationReport, kind string) map[string]entity.ReconciliationDrift {
+	drifts := make(map[string]entity.ReconciliationDrift)
+	for _, drift := range report.Drifts {
+		if drift.Kind == kind {
+			drifts[drift.WishUUID] = drift
+		}
+	}
+	return drifts
+}
+
+func TestReconcileReportsDrift(t *testing.T) {
+	f := newFixture(t, false)
+
+	report, err := f.reconciler.Reconcile(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.reportRepo.reports) != 1 || report.UsersChecked != 1 || report.WishesChecked != 4 {
+		t.Fatalf("отчёт не сохранён или заполнен неверно: %+v", report)
+	}
+
+	statuses := driftsByWish(report, entity.DriftWishStatus)
+	expected := map[string]string{"added": "active", "completed": "complete", "deleted": "deleted"}
+	if len(statuses) != len(expected) {
+		t.Fatalf("найдено %d расхождений статуса, ожидалось %d: %+v", len(statuses), len(expected), report.Drifts)
+	}
+	for uuid, status := range expected {
+		if drift := statuses[uuid]; drift.Onchain != status || drift.Repaired {
+			t.Fatalf("расхождение статуса желания %s: %+v, ожидался статус %s без исправления", uuid, drift, status)
+		}
+	}
+	if drift, ok := driftsByWish(report, entity.DriftWishAmount)["donated"]; !ok || drift.Onchain != "2" || drift.Stored != "5" {
+		t.Fatalf("расхождение накопленной суммы не найдено: %+v", report.Drifts)
+	}
+	if len(driftsByWish(report, entity.DriftBalance)) != 0 {
+		t.Fatalf("баланс с учётом комиссии отмечен как расхождение: %+v", report.Drifts)
+	}
+	if status := f.wishRepo.get("added").Status; status != "pending" {
+		t.Fatalf("без автоисправления статус изменён на %s", status)
+	}
+
+	// Баланс в контракте меньше, чем по истории: пропущен вывод
+	f.client.balances[streamerWallet] = entity.POL(1).Wei()
+	report, err = f.reconciler.Reconcile(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if drift, ok := driftsByWish(report, entity.DriftBalance)[""]; !ok || drift.Onchain != "1" || drift.Stored != "2.85" {
+		t.Fatalf("расхождение баланса не найдено: %+v", report.Drifts)
+	}
+}
+
+func TestReconcileRepairsPersistentDrift(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, true)
+
+	// Первая сверка только запоминает расхождения: индексатор мог ещё не записать свежие события
+	report, err := f.reconciler.Reconcile(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, drift := range report.Drifts {
+		if drift.Repaired {
+			t.Fatalf("расхождение исправлено с первой сверки: %+v", drift)
+		}
+	}
+
+	report, err = f.reconciler.Reconcile(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, drift := range report.Drifts {
+		if !drift.Repaired {
+			t.Fatalf("повторное расхождение не исправлено: %+v", drift)
+		}
+	}
+	for uuid, status := range map[string]string{"added": "active", "completed": "complete", "deleted": "deleted", "donated": "active"} {
+		if wish := f.wishRepo.get(uuid); wish.Status != status {
+			t.Fatalf("желание %s в статусе %s, ожидался %s", uuid, wish.Status, status)
+		}
+	}
+	if wish := f.wishRepo.get("donated"); wish.PolAmount.Cmp(entity.POL(2)) != 0 || len(wish.CreditedPayments) != 1 {
+		t.Fatalf("накопленная сумма не исправлена по истории: %s, платежи %v", wish.PolAmount, wish.CreditedPayments)
+	}
+
+	report, err = f.reconciler.Reconcile(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Drifts) != 0 {
+		t.Fatalf("после исправления остались расхождения: %+v", report.Drifts)
+	}
+}
@@ -0,0 +1,686 @@
+package relayer
+
+import (
+	"backend/internal/contract"
+	"backend/internal/entity"
+	"backend/internal/repo"
+	"backend/pkg/ethrpc"
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/google/uuid"
+)
+
+const (
+	// registrationName имя, под которым адрес релеера регистрируется в контракте
+	registrationName = "relayer"
+	// cancelGasLimit газ перевода без данных, которым отменяется зависшая транзакция
+	cancelGasLimit = 21000
+)
+
+// errRejected помечает вызовы, которые нельзя выполнить без изменения данных; такие транзакции сразу помечаются как failed
+var errRejected = errors.New("вызов отклонён")
+
+// Config параметры релеера
+type Config struct {
+	// UUID под которым адрес релеера регистрируется в контракте. События WishCompleted и WishDeleted
+	// для желаний, добавленных релеером, несут хеш этого UUID
+	UUID string
+	// PollInterval интервал проверки очереди и квитанций
+	PollInterval time.Duration
+	// BumpAfter через сколько после отправки транзакция без квитанции считается зависшей
+	BumpAfter time.Duration
+	// MaxBumps сколько раз можно повысить комиссию одной транзакции; столько же попыток даётся её отмене
+	MaxBumps int
+}
+
+// Relayer подписывает ключом бэкенда и отправляет вызовы контракта из очереди:
+// распределяет nonce, оценивает газ, повышает комиссию зависших транзакций и отслеживает квитанции.
+// Должен работать в единственном экземпляре, поэтому запускается на реплике-лидере индексатора
+type Relayer struct {
+	client      ethrpc.Client
+	key         *ecdsa.PrivateKey
+	from        common.Address
+	contract    *contract.Donates
+	relayerRepo repo.RelayerRepository
+	wishRepo    repo.WishRepository
+	userRepo    repo.UserRepository
+	config      Config
+
+	signer      types.Signer
+	nonce       uint64
+	nonceLoaded bool
+	registered  bool
+}
+
+func New(
+	client ethrpc.Client,
+	privateKey string,
+	donates *contract.Donates,
+	relayerRepo repo.RelayerRepository,
+	wishRepo repo.WishRepository,
+	userRepo repo.UserRepository,
+	config Config,
+) (*Relayer, error) {
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(privateKey, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка разбора приватного ключа: %w", err)
+	}
+	if config.UUID == "" {
+		return nil, errors.New("не задан UUID релеера")
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = 5 * time.Second
+	}
+	if config.BumpAfter <= 0 {
+		config.BumpAfter = time.Minute
+	}
+	if config.MaxBumps <= 0 {
+		config.MaxBumps = 5
+	}
+	return &Relayer{
+		client:      client,
+		key:         key,
+		from:        crypto.PubkeyToAddress(key.PublicKey),
+		contract:    donates,
+		relayerRepo: relayerRepo,
+		wishRepo:    wishRepo,
+		userRepo:    userRepo,
+		config:      config,
+	}, nil
+}
+
+// Address возвращает адрес, с которого релеер отправляет транзакции
+func (r *Relayer) Address() common.Address {
+	return r.from
+}
+
+// Run обрабатывает очередь до отмены ctx
+func (r *Relayer) Run(ctx context.Context) error {
+	chainID, err := r.client.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка получения Chain ID: %w", err)
+	}
+	r.signer = types.LatestSignerForChainID(chainID)
+	r.nonceLoaded = false
+	r.registered = false
+
+	log.Printf("Релеер запущен, адрес %s", r.from.Hex())
+
+	// Транзакции, сохранённые перед падением, могли не дойти до сети
+	if err := r.rebroadcastPending(ctx); err != nil {
+		log.Printf("Ошибка повторной отправки транзакций: %v", err)
+	}
+
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Релеер остановлен")
+			return nil
+		case <-ticker.C:
+			if err := r.trackPending(ctx); err != nil {
+				log.Printf("Ошибка проверки отправленных транзакций: %v", err)
+			}
+			if err := r.ensureRegistered(ctx); err != nil {
+				log.Printf("Ошибка проверки регистрации релеера: %v", err)
+			}
+			if err := r.submitQueued(ctx); err != nil {
+				log.Printf("Ошибка отправки транзакций из очереди: %v", err)
+			}
+		}
+	}
+}
+
+// ensureRegistered проверяет, что адрес релеера зарегистрирован в контракте с UUID из конфигурации,
+// и ставит регистрацию в очередь, если её ещё нет. Пока регистрация не включена в блок,
+// из очереди отправляется только она
+func (r *Relayer) ensureRegistered(ctx context.Context) error {
+	if r.registered {
+		return nil
+	}
+	user, err := r.contract.ReadUser(ctx, r.client, r.from, nil)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case user.UUID == r.config.UUID:
+		r.registered = true
+		log.Printf("Адрес релеера зарегистрирован в контракте с UUID %s", user.UUID)
+		return nil
+	case user.UUID != "":
+		return fmt.Errorf("адрес релеера зарегистрирован с UUID %s, а в конфигурации указан %s", user.UUID, r.config.UUID)
+	case len(user.Wishes) > 0:
+		// registerUser перезаписывает пользователя целиком, вместе с массивом желаний
+		return fmt.Errorf("адрес релеера хранит %d желаний без регистрации, регистрация удалит их из контракта", len(user.Wishes))
+	}
+
+	// Служебные вызовы без желания — это регистрации релеера, последняя идёт первой
+	txs, err := r.relayerRepo.GetByWishUUID(ctx, "")
+	if err != nil {
+		return err
+	}
+	for _, tx := range txs {
+		if tx.Action != entity.RelayActionRegister {
+			continue
+		}
+		switch tx.Status {
+		case entity.RelayStatusQueued, entity.RelayStatusPending:
+			return nil
+		case entity.RelayStatusFailed:
+			return fmt.Errorf("регистрация релеера не выполнена: %s", tx.Error)
+		}
+		break
+	}
+	log.Printf("Ставим в очередь регистрацию адреса релеера с UUID %s", r.config.UUID)
+	return r.enqueue(ctx, entity.RelayActionRegister, "", "")
+}
+
+// enqueue добавляет в очередь служебный вызов релеера
+func (r *Relayer) enqueue(ctx context.Context, action, wishUUID, streamerUUID string) error {
+	now := time.Now()
+	return r.relayerRepo.Add(ctx, &entity.RelayedTransaction{
+		ID:           uuid.New().String(),
+		WishUUID:     wishUUID,
+		StreamerUUID: streamerUUID,
+		Action:       action,
+		Status:       entity.RelayStatusQueued,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	})
+}
+
+// submitQueued подписывает и отправляет транзакции из очереди
+func (r *Relayer) submitQueued(ctx context.Context) error {
+	txs, err := r.relayerRepo.GetByStatus(ctx, entity.RelayStatusQueued)
+	if err != nil {
+		return err
+	}
+	for _, tx := range txs {
+		if !r.registered && tx.Action != entity.RelayActionRegister {
+			continue
+		}
+		if err := r.submit(ctx, tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// submit отправляет транзакцию из очереди. Ошибка возвращается только для временных проблем,
+// при которых транзакция остаётся в очереди; отклонённые вызовы помечаются как failed.
+// Подписанная транзакция сохраняется до отправки, чтобы после падения процесса nonce не был выдан повторно
+func (r *Relayer) submit(ctx context.Context, tx *entity.RelayedTransaction) error {
+	data, err := r.callData(ctx, tx)
+	if err != nil {
+		if errors.Is(err, errRejected) {
+			return r.fail(ctx, tx, err)
+		}
+		return err
+	}
+
+	to := r.contract.Address()
+	gas, err := r.client.EstimateGas(ctx, ethereum.CallMsg{From: r.from, To: &to, Data: data})
+	if err != nil {
+		// Ответ узла с ошибкой означает revert вызова, остальные ошибки — сбой связи, транзакция остаётся в очереди
+		var rpcErr rpc.Error
+		if errors.As(err, &rpcErr) {
+			return r.fail(ctx, tx, fmt.Errorf("ошибка оценки газа: %w", err))
+		}
+		return fmt.Errorf("ошибка оценки газа: %w", err)
+	}
+	gasLimit := gas * 12 / 10
+
+	if err := r.loadNonce(ctx); err != nil {
+		return err
+	}
+	tipCap, feeCap, err := r.suggestFees(ctx)
+	if err != nil {
+		return err
+	}
+
+	nonce := r.nonce
+	signed, err := r.sign(nonce, gasLimit, tipCap, feeCap, &to, data)
+	if err != nil {
+		return err
+	}
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации транзакции: %w", err)
+	}
+
+	queued := *tx
+	tx.Status = entity.RelayStatusPending
+	tx.From = r.from.Hex()
+	tx.Nonce = &nonce
+	tx.Data = hexutil.Encode(data)
+	tx.RawTx = hexutil.Encode(raw)
+	tx.TxHash = signed.Hash().Hex()
+	tx.TxHashes = []string{tx.TxHash}
+	tx.GasLimit = gasLimit
+	tx.GasTipCap = tipCap.String()
+	tx.GasFeeCap = feeCap.String()
+	tx.Attempts = 1
+	tx.Error = ""
+	tx.SubmittedAt = time.Now()
+	if err := r.relayerRepo.Update(ctx, tx); err != nil {
+		return err
+	}
+	r.nonce++
+
+	if err := r.client.SendTransaction(ctx, signed); err != nil && !isAlreadyKnown(err) {
+		// Без ответа узла неизвестно, принята ли транзакция: повторная подпись того же вызова могла бы
+		// включить его в блок дважды. Транзакция остаётся pending, rebroadcastPending отправит её снова,
+		// а trackPending найдёт квитанцию или заменит её
+		var rpcErr rpc.Error
+		if !errors.As(err, &rpcErr) {
+			return fmt.Errorf("ошибка отправки транзакции с nonce %d, повторная отправка сохранённой версии: %w", nonce, err)
+		}
+		// Узел отклонил транзакцию: возвращаем её в очередь и перечитываем nonce перед следующей отправкой
+		r.nonceLoaded = false
+		*tx = queued
+		tx.Error = err.Error()
+		if updateErr := r.relayerRepo.Update(ctx, tx); updateErr != nil {
+			return fmt.Errorf("ошибка возврата транзакции в очередь: %w", updateErr)
+		}
+		return fmt.Errorf("ошибка отправки транзакции с nonce %d: %w", nonce, err)
+	}
+
+	log.Printf("Релеер отправил %s для желания %s: %s (nonce %d)", tx.Action, tx.WishUUID, tx.TxHash, nonce)
+	return nil
+}
+
+// rebroadcastPending повторно отправляет последние сохранённые версии транзакций без квитанций
+func (r *Relayer) rebroadcastPending(ctx context.Context) error {
+	txs, err := r.relayerRepo.GetByStatus(ctx, entity.RelayStatusPending)
+	if err != nil {
+		return err
+	}
+	for _, tx := range txs {
+		if tx.RawTx == "" {
+			continue
+		}
+		raw, err := hexutil.Decode(tx.RawTx)
+		if err != nil {
+			log.Printf("Ошибка декодирования сохранённой транзакции %s: %v", tx.ID, err)
+			continue
+		}
+		var signed types.Transaction
+		if err := signed.UnmarshalBinary(raw); err != nil {
+			log.Printf("Ошибка декодирования сохранённой транзакции %s: %v", tx.ID, err)
+			continue
+		}
+		if err := r.client.SendTransaction(ctx, &signed); err != nil && !isAlreadyKnown(err) && !isNonceTooLow(err) {
+			log.Printf("Ошибка повторной отправки транзакции %s: %v", signed.Hash().Hex(), err)
+		}
+	}
+	return nil
+}
+
+// trackPending проверяет квитанции отправленных транзакций, повышает комиссию зависших,
+// а после MaxBumps повышений заменяет их переводом 0 на свой адрес, чтобы освободить nonce
+func (r *Relayer) trackPending(ctx context.Context) error {
+	txs, err := r.relayerRepo.GetByStatus(ctx, entity.RelayStatusPending)
+	if err != nil {
+		return err
+	}
+	for _, tx := range txs {
+		mined, err := r.checkReceipts(ctx, tx)
+		if err != nil {
+			return err
+		}
+		if mined || time.Since(tx.SubmittedAt) < r.config.BumpAfter {
+			continue
+		}
+
+		cancel := tx.Attempts > r.config.MaxBumps
+		if cancel && len(tx.CancelHashes) >= r.config.MaxBumps {
+			log.Printf("⚠️ Ни транзакция %s (nonce %d), ни её отмена не включены в блок", tx.TxHash, *tx.Nonce)
+			r.nonceLoaded = false
+			if err := r.fail(ctx, tx, errors.New("транзакция и её отмена не включены в блок")); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := r.bump(ctx, tx, cancel); err != nil {
+			log.Printf("Ошибка повышения комиссии транзакции %s: %v", tx.TxHash, err)
+		}
+	}
+	return nil
+}
+
+// checkReceipts ищет квитанцию для любой из отправленных версий транзакции и её отмены
+func (r *Relayer) checkReceipts(ctx context.Context, tx *entity.RelayedTransaction) (bool, error) {
+	for _, cancelHash := range tx.CancelHashes {
+		receipt, err := r.receipt(ctx, cancelHash)
+		if err != nil {
+			return false, err
+		}
+		if receipt == nil {
+			continue
+		}
+		tx.TxHash = cancelHash
+		tx.BlockNumber = receipt.BlockNumber.Uint64()
+		tx.Status = entity.RelayStatusFailed
+		tx.Error = fmt.Sprintf("транзакция отменена после %d повышений комиссии", r.config.MaxBumps)
+		log.Printf("⚠️ Транзакция релеера с nonce %d отменена в блоке %d", *tx.Nonce, tx.BlockNumber)
+		return true, r.relayerRepo.Update(ctx, tx)
+	}
+
+	for i := len(tx.TxHashes) - 1; i >= 0; i-- {
+		receipt, err := r.receipt(ctx, tx.TxHashes[i])
+		if err != nil {
+			return false, err
+		}
+		if receipt == nil {
+			continue
+		}
+
+		tx.TxHash = tx.TxHashes[i]
+		tx.BlockNumber = receipt.BlockNumber.Uint64()
+		if receipt.Status == types.ReceiptStatusSuccessful {
+			// Последствия применяются до сохранения статуса: после падения квитанция будет найдена снова
+			if err := r.applyMined(ctx, tx); err != nil {
+				return false, err
+			}
+			tx.Status = entity.RelayStatusMined
+			log.Printf("Транзакция релеера %s включена в блок %d", tx.TxHash, tx.BlockNumber)
+		} else {
+			tx.Status = entity.RelayStatusFailed
+			tx.Error = "вызов контракта отменён (revert)"
+			log.Printf("⚠️ Транзакция релеера %s отменена контрактом в блоке %d", tx.TxHash, tx.BlockNumber)
+		}
+		return true, r.relayerRepo.Update(ctx, tx)
+	}
+	return false, nil
+}
+
+func (r *Relayer) receipt(ctx context.Context, hash string) (*types.Receipt, error) {
+	receipt, err := r.client.TransactionReceipt(ctx, common.HexToHash(hash))
+	if err != nil {
+		if errors.Is(err, ethereum.NotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка получения квитанции %s: %w", hash, err)
+	}
+	return receipt, nil
+}
+
+// applyMined отмечает, какие желания хранятся под адресом релеера. Завершённое желание
+// сразу удаляется из массива релеера, иначе каждый следующий addWish перебирает его в контракте
+func (r *Relayer) applyMined(ctx context.Context, tx *entity.RelayedTransaction) error {
+	switch tx.Action {
+	case entity.RelayActionAdd:
+		return r.wishRepo.SetRelayHolder(ctx, tx.WishUUID, tx.From)
+	case entity.RelayActionRemove, entity.RelayActionPrune:
+		wish, err := r.wishRepo.GetByUUID(ctx, tx.WishUUID)
+		if err != nil {
+			return err
+		}
+		if wish.RelayedBy == "" {
+			return nil
+		}
+		return r.wishRepo.ReleaseRelayHolder(ctx, tx.WishUUID)
+	case entity.RelayActionComplete:
+		wish, err := r.wishRepo.GetByUUID(ctx, tx.WishUUID)
+		if err != nil {
+			return err
+		}
+		if wish.RelayedBy == "" || wish.RelayReleased {
+			return nil
+		}
+		existing, err := r.relayerRepo.GetByWishUUID(ctx, tx.WishUUID)
+		if err != nil {
+			return err
+		}
+		for _, other := range existing {
+			if other.Action == entity.RelayActionPrune && other.Status != entity.RelayStatusFailed {
+				return nil
+			}
+		}
+		return r.enqueue(ctx, entity.RelayActionPrune, wish.UUID, wish.StreamerUUID)
+	}
+	return nil
+}
+
+// bump переподписывает транзакцию с тем же nonce и повышенной комиссией.
+// При cancel вместо вызова контракта подписывается перевод 0 на свой адрес
+func (r *Relayer) bump(ctx context.Context, tx *entity.RelayedTransaction, cancel bool) error {
+	oldTip, _ := new(big.Int).SetString(tx.GasTipCap, 10)
+	oldFeeCap, _ := new(big.Int).SetString(tx.GasFeeCap, 10)
+	if oldTip == nil || oldFeeCap == nil {
+		return fmt.Errorf("не сохранена комиссия транзакции")
+	}
+
+	tipCap, feeCap, err := r.suggestFees(ctx)
+	if err != nil {
+		return err
+	}
+	// Узлы принимают замену только при повышении обеих комиссий не менее чем на 10%
+	tipCap = maxBig(tipCap, bumpFee(oldTip))
+	feeCap = maxBig(feeCap, bumpFee(oldFeeCap))
+	if feeCap.Cmp(tipCap) < 0 {
+		feeCap = new(big.Int).Set(tipCap)
+	}
+
+	var signed *types.Transaction
+	if cancel {
+		signed, err = r.sign(*tx.Nonce, cancelGasLimit, tipCap, feeCap, &r.from, nil)
+	} else {
+		data, decodeErr := hexutil.Decode(tx.Data)
+		if decodeErr != nil {
+			return fmt.Errorf("ошибка декодирования calldata: %w", decodeErr)
+		}
+		to := r.contract.Address()
+		signed, err = r.sign(*tx.Nonce, tx.GasLimit, tipCap, feeCap, &to, data)
+	}
+	if err != nil {
+		return err
+	}
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации транзакции: %w", err)
+	}
+
+	previous := *tx
+	hash := signed.Hash().Hex()
+	if cancel {
+		tx.CancelHashes = append(append([]string(nil), tx.CancelHashes...), hash)
+	} else {
+		tx.TxHash = hash
+		tx.TxHashes = append(append([]string(nil), tx.TxHashes...), hash)
+	}
+	tx.RawTx = hexutil.Encode(raw)
+	tx.GasTipCap = tipCap.String()
+	tx.GasFeeCap = feeCap.String()
+	tx.Attempts++
+	tx.SubmittedAt = time.Now()
+	if err := r.relayerRepo.Update(ctx, tx); err != nil {
+		return err
+	}
+
+	if err := r.client.SendTransaction(ctx, signed); err != nil && !isAlreadyKnown(err) {
+		if isNonceTooLow(err) {
+			// Одна из предыдущих версий уже включена в блок, квитанция будет найдена на следующей итерации
+			return nil
+		}
+		*tx = previous
+		if updateErr := r.relayerRepo.Update(ctx, tx); updateErr != nil {
+			return fmt.Errorf("ошибка отката версии транзакции: %w", updateErr)
+		}
+		return err
+	}
+
+	if cancel {
+		log.Printf("⚠️ Транзакция с nonce %d заменяется отменой %s", *tx.Nonce, hash)
+	} else {
+		log.Printf("Комиссия транзакции с nonce %d повышена, новая версия %s", *tx.Nonce, hash)
+	}
+	return nil
+}
+
+// callData кодирует вызов контракта для действия из очереди
+func (r *Relayer) callData(ctx context.Context, tx *entity.RelayedTransaction) ([]byte, error) {
+	if tx.Action == entity.RelayActionRegister {
+		return r.contract.PackRegisterUser(registrationName, r.config.UUID, nil)
+	}
+
+	wish, err := r.wishRepo.GetByUUID(ctx, tx.WishUUID)
+	if err != nil {
+		if errors.Is(err, repo.ErrWishNotFound) {
+			return nil, fmt.Errorf("%w: желание %s не найдено", errRejected, tx.WishUUID)
+		}
+		return nil, fmt.Errorf("ошибка получения желания %s: %w", tx.WishUUID, err)
+	}
+
+	switch tx.Action {
+	case entity.RelayActionAdd:
+		args := contract.WishArgs{
+			UserUUID:  wish.StreamerUUID,
+			WishUUID:  wish.UUID,
+			OnchainID: wish.OnchainID,
+			Price:     wish.PolTarget.Wei(),
+			Name:      wish.Name,
+		}
+		if wish.WishURL != nil {
+			args.Link = *wish.WishURL
+		}
+		if wish.Description != nil {
+			args.Description = *wish.Description
+		}
+		return r.contract.PackAddWish(args)
+	case entity.RelayActionComplete, entity.RelayActionRemove, entity.RelayActionPrune:
+		owner, err := r.wishOwnerAddress(ctx, wish)
+		if err != nil {
+			return nil, err
+		}
+		return r.contract.PackCompleteOrRemoveWish(owner, wish.UUID, wish.OnchainID, tx.Action != entity.RelayActionComplete)
+	default:
+		return nil, fmt.Errorf("%w: неизвестное действие %s", errRejected, tx.Action)
+	}
+}
+
+// wishOwnerAddress возвращает адрес, под которым желание хранится в контракте:
+// адрес релеера, если желание добавил он, иначе кошелёк стримера
+func (r *Relayer) wishOwnerAddress(ctx context.Context, wish *entity.Wish) (common.Address, error) {
+	if wish.RelayedBy != "" {
+		return common.HexToAddress(wish.RelayedBy), nil
+	}
+
+	user, err := r.userRepo.GetByUUID(ctx, wish.StreamerUUID)
+	if err != nil {
+		if errors.Is(err, repo.ErrUserNotFound) {
+			return common.Address{}, fmt.Errorf("%w: стример %s не найден", errRejected, wish.StreamerUUID)
+		}
+		return common.Address{}, fmt.Errorf("ошибка получения стримера %s: %w", wish.StreamerUUID, err)
+	}
+	if !common.IsHexAddress(user.PolygonWallet) {
+		return common.Address{}, fmt.Errorf("%w: у стримера %s не указан кошелёк", errRejected, user.UUID)
+	}
+	return common.HexToAddress(user.PolygonWallet), nil
+}
+
+// loadNonce читает nonce адреса релеера: следующий после уже отправленных релеером или из сети, если он больше
+func (r *Relayer) loadNonce(ctx context.Context) error {
+	if r.nonceLoaded {
+		return nil
+	}
+	nonce, err := r.client.PendingNonceAt(ctx, r.from)
+	if err != nil {
+		return fmt.Errorf("ошибка получения nonce: %w", err)
+	}
+	stored, err := r.relayerRepo.GetMaxNonce(ctx, r.from.Hex())
+	if err != nil {
+		return fmt.Errorf("ошибка получения сохранённого nonce: %w", err)
+	}
+	if stored != nil && *stored+1 > nonce {
+		nonce = *stored + 1
+	}
+	r.nonce = nonce
+	r.nonceLoaded = true
+	return nil
+}
+
+// suggestFees возвращает комиссии EIP-1559: чаевые по оценке узла и потолок с запасом на рост базовой комиссии
+func (r *Relayer) suggestFees(ctx context.Context) (*big.Int, *big.Int, error) {
+	tipCap, err := r.client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка оценки чаевых: %w", err)
+	}
+	head, err := r.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка получения текущего блока: %w", err)
+	}
+	if head.BaseFee == nil {
+		gasPrice, err := r.client.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("ошибка оценки цены газа: %w", err)
+		}
+		return gasPrice, gasPrice, nil
+	}
+	feeCap := new(big.Int).Add(new(big.Int).Mul(head.BaseFee, big.NewInt(2)), tipCap)
+	return tipCap, feeCap, nil
+}
+
+func (r *Relayer) sign(nonce, gasLimit uint64, tipCap, feeCap *big.Int, to *common.Address, data []byte) (*types.Transaction, error) {
+	tx := types.NewTx(&types.DynamicFeeTx{
+		Nonce:     nonce,
+		GasTipCap: tipCap,
+		GasFeeCap: feeCap,
+		Gas:       gasLimit,
+		To:        to,
+		Data:      data,
+	})
+	signed, err := types.SignTx(tx, r.signer, r.key)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка подписи транзакции: %w", err)
+	}
+	return signed, nil
+}
+
+// fail помечает транзакцию как отклонённую
+func (r *Relayer) fail(ctx context.Context, tx *entity.RelayedTransaction, cause error) error {
+	log.Printf("⚠️ Вызов %s для желания %s отклонён: %v", tx.Action, tx.WishUUID, cause)
+	tx.Status = entity.RelayStatusFailed
+	tx.Error = cause.Error()
+	return r.relayerRepo.Update(ctx, tx)
+}
+
+func isNonceTooLow(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "nonce too low")
+}
+
+// isAlreadyKnown сообщает, что узел уже получил эту транзакцию, например при повторной отправке после сбоя
+func isAlreadyKnown(err error) bool {
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, "already known") || strings.Contains(message, "known transaction")
+}
+
+// bumpFee повышает комиссию на 12.5%
+func bumpFee(fee *big.Int) *big.Int {
+	bumped := new(big.Int).Mul(fee, big.NewInt(1125))
+	bumped.Div(bumped, big.NewInt(1000))
+	return bumped.Add(bumped, big.NewInt(1))
+}
+
+func maxBig(a, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return b
+}
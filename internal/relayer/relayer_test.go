@@ -0,0 +1,346 @@
+package relayer
+
+import (
+	"backend/internal/abi"
+	"backend/internal/contract"
+	"backend/internal/entity"
+	"backend/internal/repo"
+	"backend/pkg/ethrpc"
+	"context"
+	"errors"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	ethabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const testKey = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+
+// revertError ответ узла с кодом ошибки, как при revert во время eth_estimateGas
+type revertError struct{}
+
+func (revertError) Error() string  { return "execution reverted" }
+func (revertError) ErrorCode() int { return 3 }
+
+// fakeClient узел, которому можно задать ошибки оценки газа и отправки. Остальные методы ethrpc.Client не используются
+type fakeClient struct {
+	ethrpc.Client
+	estimateErr error
+	sendErr     error
+	sent        []*types.Transaction
+	receipts    map[common.Hash]*types.Receipt
+	// onSend вызывается перед отправкой, чтобы проверить, что успело сохраниться
+	onSend func(tx *types.Transaction)
+}
+
+func (c *fakeClient) EstimateGas(context.Context, ethereum.CallMsg) (uint64, error) {
+	return 100000, c.estimateErr
+}
+
+func (c *fakeClient) PendingNonceAt(context.Context, common.Address) (uint64, error) {
+	return 3, nil
+}
+
+func (c *fakeClient) SuggestGasTipCap(context.Context) (*big.Int, error) {
+	return big.NewInt(1e9), nil
+}
+
+func (c *fakeClient) HeaderByNumber(context.Context, *big.Int) (*types.Header, error) {
+	return &types.Header{Number: big.NewInt(100), BaseFee: big.NewInt(1e9)}, nil
+}
+
+func (c *fakeClient) SendTransaction(_ context.Context, tx *types.Transaction) error {
+	if c.onSend != nil {
+		c.onSend(tx)
+	}
+	if c.sendErr != nil {
+		return c.sendErr
+	}
+	c.sent = append(c.sent, tx)
+	return nil
+}
+
+func (c *fakeClient) TransactionReceipt(_ context.Context, hash common.Hash) (*types.Receipt, error) {
+	if receipt, ok := c.receipts[hash]; ok {
+		return receipt, nil
+	}
+	return nil, ethereum.NotFound
+}
+
+type fakeRelayerRepo struct {
+	mu  sync.Mutex
+	txs map[string]*entity.RelayedTransaction
+}
+
+func newFakeRelayerRepo(txs ...*entity.RelayedTransaction) *fakeRelayerRepo {
+	r := &fakeRelayerRepo{txs: make(map[string]*entity.RelayedTransaction)}
+	for _, tx := range txs {
+		r.txs[tx.ID] = tx
+	}
+	return r
+}
+
+func (r *fakeRelayerRepo) get(id string) entity.RelayedTransaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return *r.txs[id]
+}
+
+func (r *fakeRelayerRepo) Add(_ context.Context, tx *entity.RelayedTransaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *tx
+	r.txs[tx.ID] = &stored
+	return nil
+}
+
+func (r *fakeRelayerRepo) Update(_ context.Context, tx *entity.RelayedTransaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.txs[tx.ID]; !ok {
+		return repo.ErrRelayedTransactionNotFound
+	}
+	stored := *tx
+	r.txs[tx.ID] = &stored
+	return nil
+}
+
+func (r *fakeRelayerRepo) GetByWishUUID(_ context.Context, wishUUID string) ([]*entity.RelayedTransaction, error) {
+	return r.filter(func(tx *entity.RelayedTransaction) bool { return tx.WishUUID == wishUUID }), nil
+}
+
+func (r *fakeRelayerRepo) GetByStatus(_ context.Context, status string) ([]*entity.RelayedTransaction, error) {
+	return r.filter(func(tx *entity.RelayedTransaction) bool { return tx.Status == status }), nil
+}
+
+func (r *fakeRelayerRepo) GetMaxNonce(_ context.Context, from string) (*uint64, error) {
+	var max *uint64
+	for _, tx := range r.filter(func(tx *entity.RelayedTransaction) bool { return tx.From == from && tx.Nonce != nil }) {
+		if max == nil || *tx.Nonce > *max {
+			max = tx.Nonce
+		}
+	}
+	return max, nil
+}
+
+func (r *fakeRelayerRepo) filter(match func(tx *entity.RelayedTransaction) bool) []*entity.RelayedTransaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*entity.RelayedTransaction
+	for _, tx := range r.txs {
+		if match(tx) {
+			copied := *tx
+			result = append(result, &copied)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+	return result
+}
+
+// fakeWishRepo реализует только чтение желания и отметки релеера
+type fakeWishRepo struct {
+	repo.WishRepository
+	wishes map[string]*entity.Wish
+}
+
+func (r *fakeWishRepo) GetByUUID(_ context.Context, uuid string) (*entity.Wish, error) {
+	wish, ok := r.wishes[uuid]
+	if !ok {
+		return nil, repo.ErrWishNotFound
+	}
+	copied := *wish
+	return &copied, nil
+}
+
+func (r *fakeWishRepo) SetRelayHolder(_ context.Context, uuid, relayer string) error {
+	r.wishes[uuid].RelayedBy = relayer
+	return nil
+}
+
+func newTestRelayer(t *testing.T, client *fakeClient, relayerRepo *fakeRelayerRepo) *Relayer {
+	t.Helper()
+	contractABI, err := ethabi.JSON(strings.NewReader(abi.DonatesABI))
+	if err != nil {
+		t.Fatalf("ошибка разбора ABI: %v", err)
+	}
+	wishRepo := &fakeWishRepo{wishes: map[string]*entity.Wish{
+		"wish-1": {UUID: "wish-1", OnchainID: 1, StreamerUUID: "streamer", Name: "Микрофон", PolTarget: entity.POL(10), Status: "pending"},
+	}}
+	r, err := New(client, testKey, contract.NewDonates(contractABI, common.HexToAddress("0xc0ffee")), relayerRepo, wishRepo, nil, Config{UUID: "relayer", MaxBumps: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.signer = types.LatestSignerForChainID(big.NewInt(80002))
+	r.registered = true
+	return r
+}
+
+func queuedAdd() *entity.RelayedTransaction {
+	return &entity.RelayedTransaction{ID: "tx-1", WishUUID: "wish-1", StreamerUUID: "streamer", Action: entity.RelayActionAdd, Status: entity.RelayStatusQueued}
+}
+
+func TestSubmitSavesTransactionBeforeSending(t *testing.T) {
+	ctx := context.Background()
+	relayerRepo := newFakeRelayerRepo(queuedAdd())
+	client := &fakeClient{}
+	r := newTestRelayer(t, client, relayerRepo)
+
+	client.onSend = func(signed *types.Transaction) {
+		stored := relayerRepo.get("tx-1")
+		if stored.Status != entity.RelayStatusPending || stored.Nonce == nil || *stored.Nonce != signed.Nonce() || stored.RawTx == "" {
+			t.Fatalf("транзакция не сохранена до отправки: %+v", stored)
+		}
+	}
+	if err := r.submitQueued(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.sent) != 1 || client.sent[0].Nonce() != 3 {
+		t.Fatalf("ожидалась одна транзакция с nonce 3, отправлено %d", len(client.sent))
+	}
+}
+
+// rejectedError ответ узла, отклонившего транзакцию
+type rejectedError struct{}
+
+func (rejectedError) Error() string  { return "insufficient funds for gas * price + value" }
+func (rejectedError) ErrorCode() int { return -32000 }
+
+func TestSubmitReturnsTransactionToQueueOnRejection(t *testing.T) {
+	ctx := context.Background()
+	relayerRepo := newFakeRelayerRepo(queuedAdd())
+	client := &fakeClient{sendErr: rejectedError{}}
+	r := newTestRelayer(t, client, relayerRepo)
+
+	if err := r.submitQueued(ctx); err == nil {
+		t.Fatal("ошибка отправки не возвращена")
+	}
+	stored := relayerRepo.get("tx-1")
+	if stored.Status != entity.RelayStatusQueued || stored.Nonce != nil || stored.RawTx != "" {
+		t.Fatalf("транзакция не возвращена в очередь: %+v", stored)
+	}
+	if r.nonceLoaded {
+		t.Fatal("nonce не будет перечитан после ошибки отправки")
+	}
+
+	// Узел уже получил транзакцию при предыдущей попытке — это не ошибка
+	client.sendErr = errors.New("already known")
+	if err := r.submitQueued(ctx); err != nil {
+		t.Fatal(err)
+	}
+	stored = relayerRepo.get("tx-1")
+	if stored.Status != entity.RelayStatusPending || *stored.Nonce != 3 {
+		t.Fatalf("транзакция не отмечена отправленной с nonce 3: %+v", stored)
+	}
+}
+
+func TestSubmitKeepsTransactionPendingOnAmbiguousError(t *testing.T) {
+	ctx := context.Background()
+	relayerRepo := newFakeRelayerRepo(queuedAdd())
+	client := &fakeClient{sendErr: errors.New("context deadline exceeded")}
+	r := newTestRelayer(t, client, relayerRepo)
+
+	if err := r.submitQueued(ctx); err == nil {
+		t.Fatal("ошибка отправки не возвращена")
+	}
+	stored := relayerRepo.get("tx-1")
+	if stored.Status != entity.RelayStatusPending || stored.Nonce == nil || *stored.Nonce != 3 || stored.RawTx == "" {
+		t.Fatalf("после ошибки связи транзакция должна остаться pending: %+v", stored)
+	}
+	if !r.nonceLoaded || r.nonce != 4 {
+		t.Fatalf("nonce 3 занят отправленной транзакцией, следующий %d", r.nonce)
+	}
+
+	// Следующий цикл не подписывает вызов заново, а отправляет сохранённую транзакцию
+	client.sendErr = nil
+	if err := r.submitQueued(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.sent) != 0 {
+		t.Fatalf("вызов подписан повторно: отправлено %d", len(client.sent))
+	}
+	if err := r.rebroadcastPending(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.sent) != 1 || client.sent[0].Hash().Hex() != stored.TxHash {
+		t.Fatalf("сохранённая транзакция %s не отправлена повторно", stored.TxHash)
+	}
+}
+
+func TestSubmitFailsOnlyOnRevert(t *testing.T) {
+	ctx := context.Background()
+	relayerRepo := newFakeRelayerRepo(queuedAdd())
+	client := &fakeClient{estimateErr: errors.New("dial tcp: i/o timeout")}
+	r := newTestRelayer(t, client, relayerRepo)
+
+	if err := r.submitQueued(ctx); err == nil {
+		t.Fatal("ошибка связи при оценке газа не возвращена")
+	}
+	if status := relayerRepo.get("tx-1").Status; status != entity.RelayStatusQueued {
+		t.Fatalf("после ошибки связи статус %s, ожидался queued", status)
+	}
+
+	client.estimateErr = revertError{}
+	if err := r.submitQueued(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if status := relayerRepo.get("tx-1").Status; status != entity.RelayStatusFailed {
+		t.Fatalf("после revert статус %s, ожидался failed", status)
+	}
+}
+
+func TestTrackPendingCancelsAfterMaxBumps(t *testing.T) {
+	ctx := context.Background()
+	nonce := uint64(7)
+	key, _ := crypto.HexToECDSA(testKey)
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	stuck := &entity.RelayedTransaction{
+		ID:          "tx-1",
+		WishUUID:    "wish-1",
+		Action:      entity.RelayActionAdd,
+		Status:      entity.RelayStatusPending,
+		From:        from.Hex(),
+		Nonce:       &nonce,
+		TxHash:      "0x01",
+		TxHashes:    []string{"0x01"},
+		Data:        "0x",
+		GasLimit:    100000,
+		GasTipCap:   "1000000000",
+		GasFeeCap:   "3000000000",
+		Attempts:    3,
+		SubmittedAt: time.Now().Add(-time.Hour),
+	}
+	relayerRepo := newFakeRelayerRepo(stuck)
+	client := &fakeClient{receipts: make(map[common.Hash]*types.Receipt)}
+	r := newTestRelayer(t, client, relayerRepo)
+
+	if err := r.trackPending(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.sent) != 1 {
+		t.Fatalf("отправлено %d транзакций, ожидалась отмена", len(client.sent))
+	}
+	cancel := client.sent[0]
+	if cancel.Nonce() != nonce || *cancel.To() != from || cancel.Value().Sign() != 0 || len(cancel.Data()) != 0 {
+		t.Fatalf("отмена должна быть переводом 0 на свой адрес с nonce %d", nonce)
+	}
+	stored := relayerRepo.get("tx-1")
+	if len(stored.CancelHashes) != 1 || stored.Status != entity.RelayStatusPending {
+		t.Fatalf("отмена не сохранена: %+v", stored)
+	}
+
+	client.receipts[cancel.Hash()] = &types.Receipt{Status: types.ReceiptStatusSuccessful, BlockNumber: big.NewInt(101)}
+	if err := r.trackPending(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if stored := relayerRepo.get("tx-1"); stored.Status != entity.RelayStatusFailed || stored.BlockNumber != 101 {
+		t.Fatalf("включённая в блок отмена не завершила транзакцию: %+v", stored)
+	}
+}
@@ -3,11 +3,28 @@ package repo
 import (
 	"backend/internal/entity"
 	"context"
+	"errors"
 )
 
+var ErrEventAlreadyExists = errors.New("blockchain event already exists")
+
 type BlockchainRepository interface {
 	GetLastProcessedBlock(ctx context.Context) (uint64, error)
+	// GetCheckpoint возвращает последний обработанный индексатором блок деплоя контракта
+	GetCheckpoint(ctx context.Context, contractAddress string) (uint64, error)
 	SaveLastProcessedBlock(ctx context.Context, blockNumber uint64) error
+	GetState(ctx context.Context) (*entity.BlockchainState, error)
+	SaveState(ctx context.Context, state *entity.BlockchainState) error
+	// GetBackfillState возвращает прогресс исторической загрузки или nil, если загрузка не запущена
+	GetBackfillState(ctx context.Context) (*entity.BlockchainState, error)
+	SaveBackfillState(ctx context.Context, state *entity.BlockchainState) error
+	DeleteBackfillState(ctx context.Context) error
 	SaveEvent(ctx context.Context, event *entity.BlockchainEvent) error
+	EventExists(ctx context.Context, id string) (bool, error)
 	GetEvents(ctx context.Context, fromBlock, toBlock uint64) ([]*entity.BlockchainEvent, error)
+	// GetWishEvents возвращает применённые события WishAdded, WishCompleted и WishDeleted указанных желаний в порядке блоков
+	GetWishEvents(ctx context.Context, wishUUIDs []string) ([]*entity.BlockchainEvent, error)
+	// DeleteEventsFromBlock удаляет события начиная с указанного блока (используется при откате реорганизации)
+	DeleteEventsFromBlock(ctx context.Context, fromBlock uint64) error
+	SaveAnomaly(ctx context.Context, anomaly *entity.BlockchainAnomaly) error
 }
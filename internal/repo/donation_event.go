@@ -8,6 +8,7 @@ import (
 // DonationEventRepo описывает методы для отправки событий о донате
 // в брокере сообщений (например, Redis).
 type DonationEventRepo interface {
+	// SendDonationEvent отправляет событие доната. Повторная отправка события с тем же UUID игнорируется
 	SendDonationEvent(ctx context.Context, event entity.DonationEvent) error
 	// SubscribeDonationEvents возвращает канал событий доната для указанного стримера
 	SubscribeDonationEvents(ctx context.Context, streamerUUID string, lastID string) (<-chan entity.DonationEvent, <-chan error)
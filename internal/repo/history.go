@@ -3,9 +3,23 @@ package repo
 import (
 	"backend/internal/entity"
 	"context"
+	"errors"
+	"time"
 )
 
+var ErrHistoryAlreadyExists = errors.New("history already exists")
+
 type HistoryRepository interface {
 	Add(ctx context.Context, history *entity.History) error
 	GetByStreamerUUID(ctx context.Context, streamerUUID string, page int, pageSize int) ([]*entity.History, error)
+	GetFromBlock(ctx context.Context, fromBlock uint64) ([]*entity.History, error)
+	// GetByWishUUID возвращает донаты на желание
+	GetByWishUUID(ctx context.Context, wishUUID string) ([]*entity.History, error)
+	// GetTotals считает суммы донатов и выводов стримера
+	GetTotals(ctx context.Context, streamerUUID string) (*entity.HistoryTotals, error)
+	// GetRevenueByDay суммирует донаты и комиссию платформы по дням (UTC) за период [from, to)
+	GetRevenueByDay(ctx context.Context, from, to time.Time) ([]entity.RevenueEntry, error)
+	// GetRevenueByStreamer суммирует донаты и комиссию платформы по стримерам за период [from, to)
+	GetRevenueByStreamer(ctx context.Context, from, to time.Time) ([]entity.RevenueEntry, error)
+	DeleteFromBlock(ctx context.Context, fromBlock uint64) error
 }
@@ -13,18 +13,23 @@ import (
 )
 
 type blockchainRepository struct {
-	stateCol  *mongo.Collection
-	eventsCol *mongo.Collection
+	stateCol     *mongo.Collection
+	eventsCol    *mongo.Collection
+	anomaliesCol *mongo.Collection
 }
 
 func NewBlockchainRepository(db *mongo.Database) repo.BlockchainRepository {
 	return &blockchainRepository{
-		stateCol:  db.Collection("blockchain_state"),
-		eventsCol: db.Collection("blockchain_events"),
+		stateCol:     db.Collection("blockchain_state"),
+		eventsCol:    db.Collection("blockchain_events"),
+		anomaliesCol: db.Collection("blockchain_anomalies"),
 	}
 }
 
-const lastProcessedBlockID = "last_processed_block"
+const (
+	lastProcessedBlockID = "last_processed_block"
+	backfillStateID      = "backfill"
+)
 
 func (r *blockchainRepository) GetLastProcessedBlock(ctx context.Context) (uint64, error) {
 	filter := bson.M{"_id": lastProcessedBlockID}
@@ -40,6 +45,14 @@ func (r *blockchainRepository) GetLastProcessedBlock(ctx context.Context) (uint6
 	return state.LastProcessedBlock, nil
 }
 
+func (r *blockchainRepository) GetCheckpoint(ctx context.Context, contractAddress string) (uint64, error) {
+	state, err := r.GetState(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return state.Checkpoint(contractAddress), nil
+}
+
 func (r *blockchainRepository) SaveLastProcessedBlock(ctx context.Context, blockNumber uint64) error {
 	filter := bson.M{"_id": lastProcessedBlockID}
 	update := bson.M{
@@ -53,10 +66,73 @@ func (r *blockchainRepository) SaveLastProcessedBlock(ctx context.Context, block
 	return err
 }
 
+func (r *blockchainRepository) GetState(ctx context.Context) (*entity.BlockchainState, error) {
+	filter := bson.M{"_id": lastProcessedBlockID}
+	var state entity.BlockchainState
+	err := r.stateCol.FindOne(ctx, filter).Decode(&state)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return &entity.BlockchainState{ID: lastProcessedBlockID}, nil
+		}
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (r *blockchainRepository) SaveState(ctx context.Context, state *entity.BlockchainState) error {
+	state.ID = lastProcessedBlockID
+	state.UpdatedAt = time.Now()
+	filter := bson.M{"_id": lastProcessedBlockID}
+	opts := options.Replace().SetUpsert(true)
+	_, err := r.stateCol.ReplaceOne(ctx, filter, state, opts)
+	return err
+}
+
+func (r *blockchainRepository) GetBackfillState(ctx context.Context) (*entity.BlockchainState, error) {
+	filter := bson.M{"_id": backfillStateID}
+	var state entity.BlockchainState
+	err := r.stateCol.FindOne(ctx, filter).Decode(&state)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (r *blockchainRepository) SaveBackfillState(ctx context.Context, state *entity.BlockchainState) error {
+	state.ID = backfillStateID
+	state.UpdatedAt = time.Now()
+	filter := bson.M{"_id": backfillStateID}
+	opts := options.Replace().SetUpsert(true)
+	_, err := r.stateCol.ReplaceOne(ctx, filter, state, opts)
+	return err
+}
+
+func (r *blockchainRepository) DeleteBackfillState(ctx context.Context) error {
+	_, err := r.stateCol.DeleteOne(ctx, bson.M{"_id": backfillStateID})
+	return err
+}
+
 func (r *blockchainRepository) SaveEvent(ctx context.Context, event *entity.BlockchainEvent) error {
 	event.ProcessedAt = time.Now()
 	_, err := r.eventsCol.InsertOne(ctx, event)
-	return err
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return errors.Join(repo.ErrEventAlreadyExists, err)
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *blockchainRepository) EventExists(ctx context.Context, id string) (bool, error) {
+	count, err := r.eventsCol.CountDocuments(ctx, bson.M{"_id": id}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
 }
 
 func (r *blockchainRepository) GetEvents(ctx context.Context, fromBlock, toBlock uint64) ([]*entity.BlockchainEvent, error) {
@@ -66,7 +142,7 @@ func (r *blockchainRepository) GetEvents(ctx context.Context, fromBlock, toBlock
 			"$lte": toBlock,
 		},
 	}
-	findOptions := options.Find().SetSort(bson.M{"block_number": 1, "processed_at": 1})
+	findOptions := options.Find().SetSort(bson.D{{Key: "block_number", Value: 1}, {Key: "processed_at", Value: 1}})
 	cursor, err := r.eventsCol.Find(ctx, filter, findOptions)
 	if err != nil {
 		return nil, err
@@ -86,3 +162,37 @@ func (r *blockchainRepository) GetEvents(ctx context.Context, fromBlock, toBlock
 	}
 	return events, nil
 }
+
+func (r *blockchainRepository) GetWishEvents(ctx context.Context, wishUUIDs []string) ([]*entity.BlockchainEvent, error) {
+	filter := bson.M{
+		"wish_uuid":  bson.M{"$in": wishUUIDs},
+		"event_type": bson.M{"$in": bson.A{"WishAdded", "WishCompleted", "WishDeleted"}},
+		"applied":    true,
+	}
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "block_number", Value: 1}, {Key: "log_index", Value: 1}}).
+		SetProjection(bson.M{"payload": 0})
+	cursor, err := r.eventsCol.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	var events []*entity.BlockchainEvent
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *blockchainRepository) DeleteEventsFromBlock(ctx context.Context, fromBlock uint64) error {
+	filter := bson.M{"block_number": bson.M{"$gte": fromBlock}}
+	_, err := r.eventsCol.DeleteMany(ctx, filter)
+	return err
+}
+
+func (r *blockchainRepository) SaveAnomaly(ctx context.Context, anomaly *entity.BlockchainAnomaly) error {
+	anomaly.DetectedAt = time.Now()
+	filter := bson.M{"_id": anomaly.ID}
+	opts := options.Replace().SetUpsert(true)
+	_, err := r.anomaliesCol.ReplaceOne(ctx, filter, anomaly, opts)
+	return err
+}
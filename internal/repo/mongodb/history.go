@@ -4,6 +4,9 @@ import (
 	"backend/internal/entity"
 	"backend/internal/repo"
 	"context"
+	"errors"
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -21,7 +24,13 @@ func NewHistoryRepository(db *mongo.Database) repo.HistoryRepository {
 
 func (r *historyRepository) Add(ctx context.Context, history *entity.History) error {
 	_, err := r.col.InsertOne(ctx, history)
-	return err
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return errors.Join(repo.ErrHistoryAlreadyExists, err)
+		}
+		return err
+	}
+	return nil
 }
 
 func (r *historyRepository) GetByStreamerUUID(ctx context.Context, streamerUUID string, page int, pageSize int) ([]*entity.History, error) {
@@ -46,3 +55,158 @@ func (r *historyRepository) GetByStreamerUUID(ctx context.Context, streamerUUID
 	}
 	return historyList, nil
 }
+
+func (r *historyRepository) GetFromBlock(ctx context.Context, fromBlock uint64) ([]*entity.History, error) {
+	filter := bson.M{"block_number": bson.M{"$gte": fromBlock}}
+	findOptions := options.Find().SetSort(bson.M{"block_number": 1})
+	cursor, err := r.col.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = cursor.Close(ctx) }()
+
+	var historyList []*entity.History
+	for cursor.Next(ctx) {
+		var h entity.History
+		if err := cursor.Decode(&h); err != nil {
+			return nil, err
+		}
+		historyList = append(historyList, &h)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	return historyList, nil
+}
+
+func (r *historyRepository) GetByWishUUID(ctx context.Context, wishUUID string) ([]*entity.History, error) {
+	filter := bson.M{"wish_uuid": wishUUID, "type": "donate"}
+	cursor, err := r.col.Find(ctx, filter, options.Find().SetSort(bson.M{"block_number": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = cursor.Close(ctx) }()
+
+	var historyList []*entity.History
+	if err := cursor.All(ctx, &historyList); err != nil {
+		return nil, err
+	}
+	return historyList, nil
+}
+
+func (r *historyRepository) GetTotals(ctx context.Context, streamerUUID string) (*entity.HistoryTotals, error) {
+	// Суммы хранятся в Decimal128, поэтому $sum складывает их без потери точности
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"streamer_uuid": streamerUUID}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":          "$type",
+			"total":        bson.M{"$sum": "$amount"},
+			"net":          bson.M{"$sum": "$net_amount"},
+			"commission":   bson.M{"$sum": "$commission"},
+			"count":        bson.M{"$sum": 1},
+			"legacy_total": bson.M{"$sum": bson.M{"$cond": bson.A{legacyPayment, "$amount", 0}}},
+			"legacy_count": bson.M{"$sum": bson.M{"$cond": bson.A{legacyPayment, 1, 0}}},
+		}}},
+	}
+	cursor, err := r.col.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = cursor.Close(ctx) }()
+
+	totals := &entity.HistoryTotals{}
+	for cursor.Next(ctx) {
+		var group struct {
+			Type        string        `bson:"_id"`
+			Total       entity.Amount `bson:"total"`
+			Net         entity.Amount `bson:"net"`
+			Commission  entity.Amount `bson:"commission"`
+			Count       int64         `bson:"count"`
+			LegacyTotal entity.Amount `bson:"legacy_total"`
+			LegacyCount int64         `bson:"legacy_count"`
+		}
+		if err := cursor.Decode(&group); err != nil {
+			return nil, err
+		}
+		switch group.Type {
+		case "donate":
+			totals.Donated = group.Total
+			totals.DonatedNet = group.Net
+			totals.Commission = group.Commission
+			totals.Donations = group.Count
+			totals.LegacyDonated = group.LegacyTotal
+			totals.LegacyDonations = group.LegacyCount
+		case "withdraw":
+			totals.Withdrawn = group.Total
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+func (r *historyRepository) GetRevenueByDay(ctx context.Context, from, to time.Time) ([]entity.RevenueEntry, error) {
+	day := bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$datetime", "timezone": "UTC"}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: revenueFilter(from, to)}},
+		{{Key: "$group", Value: revenueGroup(day)}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+		{{Key: "$addFields", Value: bson.M{"day": "$_id"}}},
+	}
+	return r.aggregateRevenue(ctx, pipeline)
+}
+
+func (r *historyRepository) GetRevenueByStreamer(ctx context.Context, from, to time.Time) ([]entity.RevenueEntry, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: revenueFilter(from, to)}},
+		{{Key: "$group", Value: revenueGroup("$streamer_uuid")}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+		{{Key: "$lookup", Value: bson.M{"from": "users", "localField": "_id", "foreignField": "uuid", "as": "user"}}},
+		{{Key: "$addFields", Value: bson.M{
+			"streamer_uuid": "$_id",
+			"streamer_name": bson.M{"$first": "$user.name"},
+		}}},
+	}
+	return r.aggregateRevenue(ctx, pipeline)
+}
+
+func (r *historyRepository) aggregateRevenue(ctx context.Context, pipeline mongo.Pipeline) ([]entity.RevenueEntry, error) {
+	cursor, err := r.col.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = cursor.Close(ctx) }()
+
+	entries := []entity.RevenueEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// legacyPayment условие для записей, проиндексированных до сохранения комиссии
+var legacyPayment = bson.M{"$eq": bson.A{bson.M{"$type": "$net_amount"}, "missing"}}
+
+// revenueFilter выбирает донаты за период [from, to)
+func revenueFilter(from, to time.Time) bson.M {
+	return bson.M{"type": "donate", "datetime": bson.M{"$gte": from, "$lt": to}}
+}
+
+// revenueGroup суммирует донаты и комиссию по ключу группировки
+func revenueGroup(key interface{}) bson.M {
+	return bson.M{
+		"_id":              key,
+		"donated":          bson.M{"$sum": "$amount"},
+		"commission":       bson.M{"$sum": "$commission"},
+		"donations":        bson.M{"$sum": 1},
+		"legacy_donated":   bson.M{"$sum": bson.M{"$cond": bson.A{legacyPayment, "$amount", 0}}},
+		"legacy_donations": bson.M{"$sum": bson.M{"$cond": bson.A{legacyPayment, 1, 0}}},
+	}
+}
+
+func (r *historyRepository) DeleteFromBlock(ctx context.Context, fromBlock uint64) error {
+	filter := bson.M{"block_number": bson.M{"$gte": fromBlock}}
+	_, err := r.col.DeleteMany(ctx, filter)
+	return err
+}
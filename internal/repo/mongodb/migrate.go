@@ -0,0 +1,301 @@
+package mongodb
+
+import (
+	"backend/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// migration изменение схемы существующих документов. Применённые миграции записываются в коллекцию migrations
+// и больше не выполняются, поэтому каждая миграция должна быть безопасна при повторе после прерывания
+type migration struct {
+	id string
+	up func(ctx context.Context, db *mongo.Database) error
+}
+
+var migrations = []migration{
+	{id: "001_wish_onchain_ids", up: migrateWishOnchainIDs},
+	{id: "002_wish_relayed_by", up: migrateWishRelayedBy},
+	{id: "003_amounts_to_wei", up: migrateAmountsToWei},
+	{id: "004_withdraw_net_amount", up: migrateWithdrawNetAmount},
+	{id: "005_blockchain_events_wish_uuid", up: migrateBlockchainEventsWishIndex},
+	{id: "006_wish_positions", up: migrateWishPositions},
+	{id: "007_wish_orders", up: migrateWishOrders},
+}
+
+// Migrate применяет ещё не выполненные миграции по порядку
+func Migrate(ctx context.Context, db *mongo.Database) error {
+	col := db.Collection("migrations")
+	for _, m := range migrations {
+		count, err := col.CountDocuments(ctx, bson.M{"_id": m.id}, options.Count().SetLimit(1))
+		if err != nil {
+			return fmt.Errorf("ошибка проверки миграции %s: %w", m.id, err)
+		}
+		if count > 0 {
+			continue
+		}
+		log.Printf("Применяем миграцию %s", m.id)
+		if err := m.up(ctx, db); err != nil {
+			return fmt.Errorf("ошибка миграции %s: %w", m.id, err)
+		}
+		opts := options.Update().SetUpsert(true)
+		if _, err := col.UpdateOne(ctx, bson.M{"_id": m.id}, bson.M{"$set": bson.M{"applied_at": time.Now()}}, opts); err != nil {
+			return fmt.Errorf("ошибка сохранения миграции %s: %w", m.id, err)
+		}
+	}
+	return nil
+}
+
+// migrateWishOnchainIDs нумерует желания, созданные до появления onchain_id, и создаёт уникальный индекс,
+// чтобы донат с PaymentInfo.wishId зачислялся ровно одному желанию
+func migrateWishOnchainIDs(ctx context.Context, db *mongo.Database) error {
+	col := db.Collection("wishes")
+	countersCol := db.Collection("counters")
+
+	missing := bson.M{"$or": bson.A{
+		bson.M{"onchain_id": bson.M{"$exists": false}},
+		bson.M{"onchain_id": 0},
+	}}
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}).SetProjection(bson.M{"uuid": 1})
+	cursor, err := col.Find(ctx, missing, findOptions)
+	if err != nil {
+		return err
+	}
+	var wishes []struct {
+		UUID string `bson:"uuid"`
+	}
+	if err := cursor.All(ctx, &wishes); err != nil {
+		return err
+	}
+
+	for _, wish := range wishes {
+		onchainID, err := nextSequence(ctx, countersCol, wishOnchainIDCounter)
+		if err != nil {
+			return err
+		}
+		// Условие на отсутствующий номер не даёт перенумеровать желание, если миграция выполняется параллельно
+		filter := bson.M{"uuid": wish.UUID, "$or": missing["$or"]}
+		if _, err := col.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"onchain_id": onchainID}}); err != nil {
+			return err
+		}
+	}
+	if len(wishes) > 0 {
+		log.Printf("Присвоены onchain ID для %d желаний", len(wishes))
+	}
+
+	_, err = col.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "onchain_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// migrateWishRelayedBy переносит адрес релеера из включённых в блок вызовов addWish в сами желания,
+// чтобы индексатор и релеер знали, под каким адресом желание хранится в контракте
+func migrateWishRelayedBy(ctx context.Context, db *mongo.Database) error {
+	wishesCol := db.Collection("wishes")
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := db.Collection("relayed_transactions").Find(ctx, bson.M{"status": "mined"}, findOptions)
+	if err != nil {
+		return err
+	}
+	var txs []struct {
+		WishUUID string `bson:"wish_uuid"`
+		Action   string `bson:"action"`
+		From     string `bson:"from"`
+	}
+	if err := cursor.All(ctx, &txs); err != nil {
+		return err
+	}
+
+	for _, tx := range txs {
+		var update bson.M
+		switch tx.Action {
+		case "add":
+			update = bson.M{"$set": bson.M{"relayed_by": tx.From}, "$unset": bson.M{"relay_released": ""}}
+		case "remove":
+			update = bson.M{"$set": bson.M{"relay_released": true}}
+		default:
+			continue
+		}
+		if _, err := wishesCol.UpdateOne(ctx, bson.M{"uuid": tx.WishUUID}, update); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateAmountsToWei переводит суммы, сохранённые как double в POL, в Decimal128 с целым числом wei
+func migrateAmountsToWei(ctx context.Context, db *mongo.Database) error {
+	if err := convertAmountFields(ctx, db.Collection("wishes"), "pol_target", "pol_amount"); err != nil {
+		return err
+	}
+	return convertAmountFields(ctx, db.Collection("history"), "amount")
+}
+
+// convertAmountFields перезаписывает числовые поля fields через entity.Amount, который читает double как сумму в POL
+func convertAmountFields(ctx context.Context, col *mongo.Collection, fields ...string) error {
+	numeric := bson.M{"$type": bson.A{"double", "int", "long"}}
+	for _, field := range fields {
+		cursor, err := col.Find(ctx, bson.M{field: numeric}, options.Find().SetProjection(bson.M{field: 1}))
+		if err != nil {
+			return err
+		}
+		var docs []bson.Raw
+		if err := cursor.All(ctx, &docs); err != nil {
+			return err
+		}
+
+		for _, doc := range docs {
+			var amount entity.Amount
+			if err := doc.Lookup(field).Unmarshal(&amount); err != nil {
+				return fmt.Errorf("ошибка чтения %s в %s: %w", field, col.Name(), err)
+			}
+			filter := bson.M{"_id": doc.Lookup("_id"), field: numeric}
+			if _, err := col.UpdateOne(ctx, filter, bson.M{"$set": bson.M{field: amount}}); err != nil {
+				return err
+			}
+		}
+		if len(docs) > 0 {
+			log.Printf("Поле %s переведено в wei в %d документах %s", field, len(docs), col.Name())
+		}
+	}
+	return nil
+}
+
+// migrateWithdrawNetAmount заполняет зачисленную сумму и комиссию для выводов, записанных до их сохранения:
+// с выводов комиссия не удерживается. Для старых донатов комиссия известна только после исторической загрузки
+func migrateWithdrawNetAmount(ctx context.Context, db *mongo.Database) error {
+	filter := bson.M{"type": "withdraw", "net_amount": bson.M{"$exists": false}}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"net_amount": "$amount",
+		"commission": entity.Amount{},
+	}}}}
+	result, err := db.Collection("history").UpdateMany(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.ModifiedCount > 0 {
+		log.Printf("Зачисленная сумма заполнена для %d выводов", result.ModifiedCount)
+	}
+	return nil
+}
+
+// migrateBlockchainEventsWishIndex создаёт индекс событий по желанию для списка желаний стримера с хешами транзакций
+func migrateBlockchainEventsWishIndex(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("blockchain_events").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "wish_uuid", Value: 1}, {Key: "block_number", Value: 1}},
+	})
+	return err
+}
+
+// migrateWishPositions нумерует желания, созданные до появления ручного порядка, в прежнем порядке списка:
+// приоритетные сначала, затем от новых к старым. Желания, уже получившие позицию, не перенумеровываются
+func migrateWishPositions(ctx context.Context, db *mongo.Database) error {
+	col := db.Collection("wishes")
+	missing := bson.M{"position": bson.M{"$exists": false}}
+	streamers, err := col.Distinct(ctx, "streamer_uuid", missing)
+	if err != nil {
+		return err
+	}
+
+	for _, streamer := range streamers {
+		next := 0
+		var last struct {
+			Position int `bson:"position"`
+		}
+		lastOptions := options.FindOne().SetSort(bson.D{{Key: "position", Value: -1}}).SetProjection(bson.M{"position": 1})
+		err := col.FindOne(ctx, bson.M{"streamer_uuid": streamer, "position": bson.M{"$exists": true}}, lastOptions).Decode(&last)
+		switch {
+		case err == nil:
+			next = last.Position + 1
+		case !errors.Is(err, mongo.ErrNoDocuments):
+			return err
+		}
+
+		findOptions := options.Find().
+			SetSort(bson.D{{Key: "is_priority", Value: -1}, {Key: "created_at", Value: -1}}).
+			SetProjection(bson.M{"uuid": 1})
+		cursor, err := col.Find(ctx, bson.M{"streamer_uuid": streamer, "position": missing["position"]}, findOptions)
+		if err != nil {
+			return err
+		}
+		var wishes []struct {
+			UUID string `bson:"uuid"`
+		}
+		if err := cursor.All(ctx, &wishes); err != nil {
+			return err
+		}
+		for _, wish := range wishes {
+			filter := bson.M{"uuid": wish.UUID, "position": missing["position"]}
+			if _, err := col.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"position": next}}); err != nil {
+				return err
+			}
+			next++
+		}
+	}
+
+	_, err = col.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "streamer_uuid", Value: 1}, {Key: "position", Value: 1}},
+	})
+	return err
+}
+
+// migrateWishOrders переносит порядок желаний из позиций в документах желаний в один документ на стримера
+// в коллекции wish_orders: обновление одного документа атомарно, а позиции в разных документах менялись по одному
+func migrateWishOrders(ctx context.Context, db *mongo.Database) error {
+	col := db.Collection("wishes")
+	ordersCol := db.Collection("wish_orders")
+	streamers, err := col.Distinct(ctx, "streamer_uuid", bson.M{})
+	if err != nil {
+		return err
+	}
+
+	for _, streamer := range streamers {
+		findOptions := options.Find().SetSort(wishOrder).SetProjection(bson.M{"uuid": 1})
+		cursor, err := col.Find(ctx, bson.M{"streamer_uuid": streamer}, findOptions)
+		if err != nil {
+			return err
+		}
+		var wishes []struct {
+			UUID string `bson:"uuid"`
+		}
+		if err := cursor.All(ctx, &wishes); err != nil {
+			return err
+		}
+		uuids := make([]string, 0, len(wishes))
+		for _, wish := range wishes {
+			uuids = append(uuids, wish.UUID)
+		}
+		// Порядок, уже сохранённый при прерванном запуске миграции, не перезаписывается
+		update := bson.M{"$setOnInsert": bson.M{"wish_uuids": uuids, "version": int64(0)}}
+		if _, err := ordersCol.UpdateOne(ctx, bson.M{"_id": streamer}, update, options.Update().SetUpsert(true)); err != nil {
+			return err
+		}
+	}
+
+	if _, err := col.UpdateMany(ctx, bson.M{"position": bson.M{"$exists": true}}, bson.M{"$unset": bson.M{"position": ""}}); err != nil {
+		return err
+	}
+	// Позиция вычисляется при чтении, поэтому индексу нужен только стример; created_at упорядочивает желания вне порядка
+	if _, err := col.Indexes().DropOne(ctx, "streamer_uuid_1_position_1"); err != nil && !isIndexNotFound(err) {
+		return err
+	}
+	_, err = col.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "streamer_uuid", Value: 1}, {Key: "created_at", Value: 1}},
+	})
+	return err
+}
+
+// isIndexNotFound сообщает, что удаляемого индекса уже нет
+func isIndexNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && (cmdErr.Code == 27 || cmdErr.Code == 26)
+}
@@ -0,0 +1,24 @@
+package mongodb
+
+import (
+	"backend/internal/entity"
+	"backend/internal/repo"
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type reconciliationRepository struct {
+	col *mongo.Collection
+}
+
+func NewReconciliationRepository(db *mongo.Database) repo.ReconciliationRepository {
+	return &reconciliationRepository{
+		col: db.Collection("reconciliation_reports"),
+	}
+}
+
+func (r *reconciliationRepository) Add(ctx context.Context, report *entity.ReconciliationReport) error {
+	_, err := r.col.InsertOne(ctx, report)
+	return err
+}
@@ -0,0 +1,85 @@
+package mongodb
+
+import (
+	"backend/internal/entity"
+	"backend/internal/repo"
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type relayerRepository struct {
+	col *mongo.Collection
+}
+
+func NewRelayerRepository(db *mongo.Database) repo.RelayerRepository {
+	return &relayerRepository{
+		col: db.Collection("relayed_transactions"),
+	}
+}
+
+func (r *relayerRepository) Add(ctx context.Context, tx *entity.RelayedTransaction) error {
+	_, err := r.col.InsertOne(ctx, tx)
+	return err
+}
+
+func (r *relayerRepository) Update(ctx context.Context, tx *entity.RelayedTransaction) error {
+	tx.UpdatedAt = time.Now()
+	result, err := r.col.ReplaceOne(ctx, bson.M{"_id": tx.ID}, tx)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return repo.ErrRelayedTransactionNotFound
+	}
+	return nil
+}
+
+func (r *relayerRepository) GetByWishUUID(ctx context.Context, wishUUID string) ([]*entity.RelayedTransaction, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	return r.find(ctx, bson.M{"wish_uuid": wishUUID}, findOptions)
+}
+
+func (r *relayerRepository) GetByStatus(ctx context.Context, status string) ([]*entity.RelayedTransaction, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	return r.find(ctx, bson.M{"status": status}, findOptions)
+}
+
+func (r *relayerRepository) GetMaxNonce(ctx context.Context, from string) (*uint64, error) {
+	filter := bson.M{"from": from, "nonce": bson.M{"$exists": true}}
+	findOptions := options.FindOne().SetSort(bson.D{{Key: "nonce", Value: -1}})
+	var tx entity.RelayedTransaction
+	err := r.col.FindOne(ctx, filter, findOptions).Decode(&tx)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return tx.Nonce, nil
+}
+
+func (r *relayerRepository) find(ctx context.Context, filter bson.M, findOptions *options.FindOptions) ([]*entity.RelayedTransaction, error) {
+	cursor, err := r.col.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = cursor.Close(ctx) }()
+
+	var txs []*entity.RelayedTransaction
+	for cursor.Next(ctx) {
+		var tx entity.RelayedTransaction
+		if err := cursor.Decode(&tx); err != nil {
+			return nil, err
+		}
+		txs = append(txs, &tx)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	return txs, nil
+}
@@ -29,6 +29,10 @@ func NewUserRepository(db *mongo.Database) repo.UserRepository {
 			Keys:    bson.D{{Key: "polygon_wallet", Value: 1}},
 			Options: options.Index().SetUnique(true).SetSparse(true),
 		},
+		{
+			Keys:    bson.D{{Key: "uuid_hash", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
 	})
 	return &userRepository{
 		col: col,
@@ -52,7 +56,30 @@ func (r *userRepository) Register(ctx context.Context, user *entity.User) (strin
 func (r *userRepository) Update(ctx context.Context, user *entity.User) error {
 	user.UpdatedAt = time.Now()
 	filter := bson.M{"uuid": user.UUID}
-	update := bson.M{"$set": user}
+	fields, err := toBSONMap(user)
+	if err != nil {
+		return err
+	}
+	// Отметку регистрации в контракте пишет только индексатор, профиль её не перезаписывает
+	delete(fields, "onchain_registered")
+	delete(fields, "registration_block")
+	update := bson.M{"$set": fields}
+	res, err := r.col.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return repo.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) ConfirmRegistration(ctx context.Context, uuid string, blockNumber uint64) error {
+	filter := bson.M{"uuid": uuid}
+	update := bson.M{
+		"$set": bson.M{"onchain_registered": true},
+		"$min": bson.M{"registration_block": blockNumber},
+	}
 	res, err := r.col.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return err
@@ -63,6 +90,16 @@ func (r *userRepository) Update(ctx context.Context, user *entity.User) error {
 	return nil
 }
 
+func (r *userRepository) ResetRegistrations(ctx context.Context, fromBlock uint64) error {
+	filter := bson.M{"registration_block": bson.M{"$gte": fromBlock}}
+	update := bson.M{
+		"$set":   bson.M{"onchain_registered": false},
+		"$unset": bson.M{"registration_block": ""},
+	}
+	_, err := r.col.UpdateMany(ctx, filter, update)
+	return err
+}
+
 func (r *userRepository) GetByUUID(ctx context.Context, uuid string) (*entity.User, error) {
 	filter := bson.M{"uuid": uuid}
 	var user entity.User
@@ -88,3 +125,62 @@ func (r *userRepository) GetByTelegramID(ctx context.Context, telegramID string)
 	}
 	return &user, nil
 }
+
+func (r *userRepository) GetByUUIDHash(ctx context.Context, uuidHash string) (*entity.User, error) {
+	filter := bson.M{"uuid_hash": uuidHash}
+	var user entity.User
+	err := r.col.FindOne(ctx, filter).Decode(&user)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, repo.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *userRepository) GetWithoutUUIDHash(ctx context.Context) ([]*entity.User, error) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"uuid_hash": bson.M{"$exists": false}},
+		bson.M{"uuid_hash": ""},
+	}}
+	return r.find(ctx, filter)
+}
+
+func (r *userRepository) GetWithWallet(ctx context.Context) ([]*entity.User, error) {
+	return r.find(ctx, bson.M{"polygon_wallet": bson.M{"$nin": bson.A{"", nil}}})
+}
+
+func (r *userRepository) find(ctx context.Context, filter bson.M) ([]*entity.User, error) {
+	cursor, err := r.col.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = cursor.Close(ctx) }()
+
+	var users []*entity.User
+	for cursor.Next(ctx) {
+		var u entity.User
+		if err := cursor.Decode(&u); err != nil {
+			return nil, err
+		}
+		users = append(users, &u)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// toBSONMap кодирует документ в bson.M по его bson-тегам
+func toBSONMap(v interface{}) (bson.M, error) {
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields bson.M
+	if err := bson.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
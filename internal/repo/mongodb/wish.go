@@ -12,18 +12,43 @@ import (
 )
 
 type wishRepository struct {
-	col *mongo.Collection
+	col         *mongo.Collection
+	countersCol *mongo.Collection
+	ordersCol   *mongo.Collection
 }
 
 func NewWishRepository(db *mongo.Database) repo.WishRepository {
 	return &wishRepository{
-		col: db.Collection("wishes"),
+		col:         db.Collection("wishes"),
+		countersCol: db.Collection("counters"),
+		ordersCol:   db.Collection("wish_orders"),
 	}
 }
 
+const wishOnchainIDCounter = "wish_onchain_id"
+
+// wishProjection исключает служебный список зачисленных платежей при чтении желаний
+var wishProjection = bson.M{"credited_payments": 0}
+
+// wishOrder порядок желаний в списке стримера после вычисления позиций. Желания, которых нет в WishOrder,
+// получают одну позицию в конце списка, и среди них раньше идёт созданное раньше
+var wishOrder = bson.D{{Key: "position", Value: 1}, {Key: "created_at", Value: 1}}
+
 func (r *wishRepository) Add(ctx context.Context, wish *entity.Wish) (string, error) {
 	wish.CreatedAt = time.Now()
 	wish.UpdatedAt = wish.CreatedAt
+	if wish.OnchainID == 0 {
+		onchainID, err := r.nextOnchainID(ctx)
+		if err != nil {
+			return "", err
+		}
+		wish.OnchainID = onchainID
+	}
+	// UUID попадает в порядок до вставки: $push атомарен, а версия порядка меняется, и одновременный Reorder
+	// со старым списком не сохранится. UUID без желания при чтении списка ни с чем не совпадёт
+	if err := r.appendToOrder(ctx, wish.StreamerUUID, wish.UUID); err != nil {
+		return "", err
+	}
 	_, err := r.col.InsertOne(ctx, wish)
 	if err != nil {
 		return "", err
@@ -34,7 +59,12 @@ func (r *wishRepository) Add(ctx context.Context, wish *entity.Wish) (string, er
 func (r *wishRepository) Update(ctx context.Context, wish *entity.Wish) error {
 	wish.UpdatedAt = time.Now()
 	filter := bson.M{"uuid": wish.UUID}
-	update := bson.M{"$set": wish}
+	// Статус и накопленную сумму меняет только индексатор, поэтому они здесь не перезаписываются
+	update := bson.M{"$set": bson.M{
+		"image":       wish.Image,
+		"is_priority": wish.IsPriority,
+		"updated_at":  wish.UpdatedAt,
+	}}
 	res, err := r.col.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return err
@@ -45,10 +75,201 @@ func (r *wishRepository) Update(ctx context.Context, wish *entity.Wish) error {
 	return nil
 }
 
+func (r *wishRepository) UpdateDetails(ctx context.Context, wish *entity.Wish, status string) error {
+	wish.UpdatedAt = time.Now()
+	// Условие по статусу не даёт сменить целевую сумму, если индексатор успел активировать желание
+	filter := bson.M{"uuid": wish.UUID, "status": status}
+	set := bson.M{
+		"name":        wish.Name,
+		"pol_target":  wish.PolTarget,
+		"image":       wish.Image,
+		"is_priority": wish.IsPriority,
+		"updated_at":  wish.UpdatedAt,
+	}
+	unset := bson.M{}
+	if wish.Description != nil {
+		set["description"] = *wish.Description
+	} else {
+		unset["description"] = ""
+	}
+	if wish.WishURL != nil {
+		set["wish_url"] = *wish.WishURL
+	} else {
+		unset["wish_url"] = ""
+	}
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	res, err := r.col.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return r.notMatched(ctx, wish.UUID, repo.ErrWishStatusChanged)
+	}
+	return nil
+}
+
+func (r *wishRepository) UpdateStatus(ctx context.Context, uuid, from, to string) error {
+	filter := bson.M{"uuid": uuid, "status": from}
+	update := bson.M{"$set": bson.M{
+		"status":     to,
+		"updated_at": time.Now(),
+	}}
+	res, err := r.col.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return r.notMatched(ctx, uuid, repo.ErrWishStatusChanged)
+	}
+	return nil
+}
+
+func (r *wishRepository) MarkPriceMismatch(ctx context.Context, uuid string, onchainPrice entity.Amount) error {
+	filter := bson.M{"uuid": uuid, "status": "pending"}
+	update := bson.M{"$set": bson.M{
+		"status":        "mismatch",
+		"onchain_price": onchainPrice,
+		"updated_at":    time.Now(),
+	}}
+	res, err := r.col.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return r.notMatched(ctx, uuid, repo.ErrWishStatusChanged)
+	}
+	return nil
+}
+
+func (r *wishRepository) AcceptOnchainPrice(ctx context.Context, uuid string) error {
+	filter := bson.M{"uuid": uuid, "status": "mismatch", "onchain_price": bson.M{"$exists": true}}
+	// Конвейер обновления копирует цену из контракта в целевую сумму одной операцией
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"pol_target": "$onchain_price",
+			"status":     "active",
+			"updated_at": time.Now(),
+		}}},
+		{{Key: "$unset", Value: "onchain_price"}},
+	}
+	res, err := r.col.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return r.notMatched(ctx, uuid, repo.ErrWishStatusChanged)
+	}
+	return nil
+}
+
+func (r *wishRepository) RepairCredits(ctx context.Context, uuid string, stored entity.Amount, paymentIDs []string, amount entity.Amount) error {
+	if paymentIDs == nil {
+		paymentIDs = []string{}
+	}
+	update := bson.M{"$set": bson.M{
+		"credited_payments": paymentIDs,
+		"pol_amount":        amount,
+		"updated_at":        time.Now(),
+	}}
+	res, err := r.col.UpdateOne(ctx, bson.M{"uuid": uuid, "pol_amount": stored}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return r.notMatched(ctx, uuid, repo.ErrWishStatusChanged)
+	}
+	return nil
+}
+
+func (r *wishRepository) GetOrder(ctx context.Context, streamerUUID string) (*entity.WishOrder, error) {
+	var order entity.WishOrder
+	err := r.ordersCol.FindOne(ctx, bson.M{"_id": streamerUUID}).Decode(&order)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return &entity.WishOrder{StreamerUUID: streamerUUID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r *wishRepository) Reorder(ctx context.Context, streamerUUID string, uuids []string, version int64) error {
+	// Весь порядок хранится в одном документе, поэтому читатели видят либо старый, либо новый список целиком
+	filter := bson.M{"_id": streamerUUID, "version": version}
+	update := bson.M{
+		"$set": bson.M{"wish_uuids": uuids},
+		"$inc": bson.M{"version": int64(1)},
+	}
+	// Порядок, который ещё не сохранялся, создаётся с версией 1. Если документ уже есть с другой версией,
+	// upsert пытается вставить второй документ с тем же _id и получает ошибку дубликата ключа
+	res, err := r.ordersCol.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return repo.ErrWishOrderChanged
+		}
+		return err
+	}
+	if res.MatchedCount == 0 && res.UpsertedCount == 0 {
+		return repo.ErrWishOrderChanged
+	}
+	return nil
+}
+
+// appendToOrder добавляет желание в конец порядка стримера
+func (r *wishRepository) appendToOrder(ctx context.Context, streamerUUID, uuid string) error {
+	update := bson.M{
+		"$push": bson.M{"wish_uuids": uuid},
+		"$inc":  bson.M{"version": int64(1)},
+	}
+	_, err := r.ordersCol.UpdateOne(ctx, bson.M{"_id": streamerUUID}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// findOrdered возвращает желания стримера по filter в порядке из WishOrder. Позиция вычисляется в запросе
+// из списка UUID, поэтому порядок и позиции в ответе всегда соответствуют одной версии порядка
+func (r *wishRepository) findOrdered(ctx context.Context, streamerUUID string, filter bson.M, skip, limit int64) ([]*entity.Wish, error) {
+	order, err := r.GetOrder(ctx, streamerUUID)
+	if err != nil {
+		return nil, err
+	}
+	uuids := order.WishUUIDs
+	if uuids == nil {
+		uuids = []string{}
+	}
+	position := bson.M{"$let": bson.M{
+		"vars": bson.M{"index": bson.M{"$indexOfArray": bson.A{uuids, "$uuid"}}},
+		"in":   bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$$index", -1}}, len(uuids), "$$index"}},
+	}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$project", Value: wishProjection}},
+		{{Key: "$set", Value: bson.M{"position": position}}},
+		{{Key: "$sort", Value: wishOrder}},
+	}
+	if skip > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$skip", Value: skip}})
+	}
+	if limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: limit}})
+	}
+	cursor, err := r.col.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var wishes []*entity.Wish
+	if err := cursor.All(ctx, &wishes); err != nil {
+		return nil, err
+	}
+	return wishes, nil
+}
+
 func (r *wishRepository) GetByUUID(ctx context.Context, uuid string) (*entity.Wish, error) {
 	filter := bson.M{"uuid": uuid}
 	var wish entity.Wish
-	err := r.col.FindOne(ctx, filter).Decode(&wish)
+	err := r.col.FindOne(ctx, filter, options.FindOne().SetProjection(wishProjection)).Decode(&wish)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, repo.ErrWishNotFound
@@ -59,24 +280,164 @@ func (r *wishRepository) GetByUUID(ctx context.Context, uuid string) (*entity.Wi
 }
 
 func (r *wishRepository) GetByStreamerUUID(ctx context.Context, streamerUUID string) ([]*entity.Wish, error) {
+	return r.findOrdered(ctx, streamerUUID, bson.M{"streamer_uuid": streamerUUID}, 0, 0)
+}
+
+func (r *wishRepository) GetPageByStreamerUUID(ctx context.Context, streamerUUID string, statuses []string, page, pageSize int) ([]*entity.Wish, int64, error) {
 	filter := bson.M{"streamer_uuid": streamerUUID}
-	findOptions := options.Find().SetSort(bson.D{{"is_priority", -1}, {"created_at", -1}})
-	cursor, err := r.col.Find(ctx, filter, findOptions)
+	if len(statuses) > 0 {
+		filter["status"] = bson.M{"$in": statuses}
+	}
+	total, err := r.col.CountDocuments(ctx, filter)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	defer func() { _ = cursor.Close(ctx) }()
+	wishes, err := r.findOrdered(ctx, streamerUUID, filter, int64((page-1)*pageSize), int64(pageSize))
+	if err != nil {
+		return nil, 0, err
+	}
+	return wishes, total, nil
+}
 
-	var wishes []*entity.Wish
-	for cursor.Next(ctx) {
-		var w entity.Wish
-		if err := cursor.Decode(&w); err != nil {
-			return nil, err
+func (r *wishRepository) GetByOnchainID(ctx context.Context, onchainID uint64) (*entity.Wish, error) {
+	filter := bson.M{"onchain_id": onchainID}
+	var wish entity.Wish
+	err := r.col.FindOne(ctx, filter, options.FindOne().SetProjection(wishProjection)).Decode(&wish)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, repo.ErrWishNotFound
 		}
-		wishes = append(wishes, &w)
-	}
-	if err := cursor.Err(); err != nil {
 		return nil, err
 	}
-	return wishes, nil
+	return &wish, nil
+}
+
+func (r *wishRepository) CreditPayment(ctx context.Context, uuid, paymentID string, amount entity.Amount) error {
+	// Сумма и ID платежа меняются одной операцией над документом, поэтому повторное зачисление невозможно
+	filter := bson.M{"uuid": uuid, "credited_payments": bson.M{"$ne": paymentID}}
+	update := bson.M{
+		"$inc":  bson.M{"pol_amount": amount},
+		"$push": bson.M{"credited_payments": paymentID},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+	res, err := r.col.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return r.notMatched(ctx, uuid, nil)
+	}
+	return nil
+}
+
+func (r *wishRepository) RevertPayment(ctx context.Context, uuid, paymentID string, amount entity.Amount) error {
+	filter := bson.M{"uuid": uuid, "credited_payments": paymentID}
+	update := bson.M{
+		"$inc":  bson.M{"pol_amount": amount.Neg()},
+		"$pull": bson.M{"credited_payments": paymentID},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+	res, err := r.col.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return r.notMatched(ctx, uuid, nil)
+	}
+	return nil
+}
+
+func (r *wishRepository) SetRelayHolder(ctx context.Context, uuid, relayer string) error {
+	update := bson.M{
+		"$set":   bson.M{"relayed_by": relayer, "updated_at": time.Now()},
+		"$unset": bson.M{"relay_released": ""},
+	}
+	res, err := r.col.UpdateOne(ctx, bson.M{"uuid": uuid}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return repo.ErrWishNotFound
+	}
+	return nil
+}
+
+func (r *wishRepository) ReleaseRelayHolder(ctx context.Context, uuid string) error {
+	update := bson.M{"$set": bson.M{"relay_released": true, "updated_at": time.Now()}}
+	res, err := r.col.UpdateOne(ctx, bson.M{"uuid": uuid}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return repo.ErrWishNotFound
+	}
+	return nil
+}
+
+func (r *wishRepository) CountRelayHeld(ctx context.Context) (int64, error) {
+	filter := bson.M{
+		"relayed_by":     bson.M{"$exists": true},
+		"relay_released": bson.M{"$ne": true},
+	}
+	return r.col.CountDocuments(ctx, filter)
+}
+
+func (r *wishRepository) SetContract(ctx context.Context, uuid, contractAddress string) error {
+	update := bson.M{"$set": bson.M{"contract_address": contractAddress, "updated_at": time.Now()}}
+	if contractAddress == "" {
+		update = bson.M{"$set": bson.M{"updated_at": time.Now()}, "$unset": bson.M{"contract_address": ""}}
+	}
+	res, err := r.col.UpdateOne(ctx, bson.M{"uuid": uuid}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return repo.ErrWishNotFound
+	}
+	return nil
+}
+
+func (r *wishRepository) ResetChainState(ctx context.Context) error {
+	update := bson.M{
+		"$set": bson.M{
+			"status":     "pending",
+			"pol_amount": entity.Amount{},
+			"updated_at": time.Now(),
+		},
+		"$unset": bson.M{"credited_payments": "", "onchain_price": "", "contract_address": ""},
+	}
+	_, err := r.col.UpdateMany(ctx, bson.M{}, update)
+	return err
+}
+
+// notMatched различает отсутствующее желание и невыполненное условие обновления
+func (r *wishRepository) notMatched(ctx context.Context, uuid string, conditionErr error) error {
+	count, err := r.col.CountDocuments(ctx, bson.M{"uuid": uuid}, options.Count().SetLimit(1))
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return repo.ErrWishNotFound
+	}
+	return conditionErr
+}
+
+// nextOnchainID выдаёт следующий числовой идентификатор желания.
+// Нумерация начинается с 1, так как wishId = 0 в контракте означает донат без привязки к желанию
+func (r *wishRepository) nextOnchainID(ctx context.Context) (uint64, error) {
+	return nextSequence(ctx, r.countersCol, wishOnchainIDCounter)
+}
+
+// nextSequence атомарно увеличивает счётчик name в коллекции counters и возвращает новое значение
+func nextSequence(ctx context.Context, countersCol *mongo.Collection, name string) (uint64, error) {
+	filter := bson.M{"_id": name}
+	update := bson.M{"$inc": bson.M{"seq": int64(1)}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	if err := countersCol.FindOneAndUpdate(ctx, filter, update, opts).Decode(&counter); err != nil {
+		return 0, err
+	}
+	return uint64(counter.Seq), nil
 }
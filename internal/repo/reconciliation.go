@@ -0,0 +1,10 @@
+package repo
+
+import (
+	"backend/internal/entity"
+	"context"
+)
+
+type ReconciliationRepository interface {
+	Add(ctx context.Context, report *entity.ReconciliationReport) error
+}
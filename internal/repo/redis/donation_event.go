@@ -7,8 +7,21 @@ import (
 	"errors"
 	"fmt"
 	"github.com/redis/go-redis/v9"
+	"time"
 )
 
+// sentEventTTL время, в течение которого помним отправленные события для защиты от повторной отправки
+const sentEventTTL = 7 * 24 * time.Hour
+
+// sendOnceScript атомарно помечает событие как отправленное и добавляет его в стрим.
+// Если событие с таким UUID уже отправлялось, стрим не изменяется
+var sendOnceScript = redis.NewScript(`
+if redis.call('SET', KEYS[2], 1, 'NX', 'PX', ARGV[2]) then
+	return redis.call('XADD', KEYS[1], '*', 'event', ARGV[1])
+end
+return false
+`)
+
 type DonationEventRepo struct {
 	client    *redis.Client
 	streamKey string
@@ -24,13 +37,21 @@ func (r *DonationEventRepo) SendDonationEvent(ctx context.Context, event entity.
 	if err != nil {
 		return fmt.Errorf("failed to marshal donation event: %w", err)
 	}
-	res := r.client.XAdd(ctx, &redis.XAddArgs{
-		Stream: r.streamKey,
-		Values: map[string]interface{}{
-			"event": data,
-		},
-	})
-	return res.Err()
+	if event.UUID == "" {
+		res := r.client.XAdd(ctx, &redis.XAddArgs{
+			Stream: r.streamKey,
+			Values: map[string]interface{}{
+				"event": data,
+			},
+		})
+		return res.Err()
+	}
+	sentKey := r.streamKey + ":sent:" + event.UUID
+	err = sendOnceScript.Run(ctx, r.client, []string{r.streamKey, sentKey}, data, sentEventTTL.Milliseconds()).Err()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+	return nil
 }
 
 func (r *DonationEventRepo) SubscribeDonationEvents(ctx context.Context, streamerUUID string, lastID string) (<-chan entity.DonationEvent, <-chan error) {
@@ -0,0 +1,19 @@
+package repo
+
+import (
+	"backend/internal/entity"
+	"context"
+	"errors"
+)
+
+var ErrRelayedTransactionNotFound = errors.New("relayed transaction not found")
+
+type RelayerRepository interface {
+	Add(ctx context.Context, tx *entity.RelayedTransaction) error
+	Update(ctx context.Context, tx *entity.RelayedTransaction) error
+	GetByWishUUID(ctx context.Context, wishUUID string) ([]*entity.RelayedTransaction, error)
+	// GetByStatus возвращает транзакции в указанном статусе в порядке создания
+	GetByStatus(ctx context.Context, status string) ([]*entity.RelayedTransaction, error)
+	// GetMaxNonce возвращает наибольший nonce среди отправленных транзакций адреса или nil, если их нет
+	GetMaxNonce(ctx context.Context, from string) (*uint64, error)
+}
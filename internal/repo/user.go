@@ -14,4 +14,13 @@ type UserRepository interface {
 	Update(ctx context.Context, user *entity.User) error
 	GetByUUID(ctx context.Context, uuid string) (*entity.User, error)
 	GetByTelegramID(ctx context.Context, telegramID string) (*entity.User, error)
+	// GetByUUIDHash ищет пользователя по keccak256 хешу UUID из индексированного топика события
+	GetByUUIDHash(ctx context.Context, uuidHash string) (*entity.User, error)
+	GetWithoutUUIDHash(ctx context.Context) ([]*entity.User, error)
+	// ConfirmRegistration отмечает регистрацию пользователя в контракте. Сохраняется блок самого раннего подтверждения
+	ConfirmRegistration(ctx context.Context, uuid string, blockNumber uint64) error
+	// ResetRegistrations снимает отметки регистрации, подтверждённые начиная с блока fromBlock
+	ResetRegistrations(ctx context.Context, fromBlock uint64) error
+	// GetWithWallet возвращает пользователей с привязанным кошельком
+	GetWithWallet(ctx context.Context) ([]*entity.User, error)
 }
@@ -7,12 +7,56 @@ import (
 )
 
 var (
-	ErrWishNotFound = errors.New("wish not found")
+	ErrWishNotFound      = errors.New("wish not found")
+	ErrWishStatusChanged = errors.New("wish status changed")
+	// ErrWishOrderChanged порядок желаний изменился после чтения: добавлено желание или сохранён другой порядок
+	ErrWishOrderChanged = errors.New("wish order changed")
 )
 
 type WishRepository interface {
+	// Add сохраняет новое желание в конец списка стримера
 	Add(ctx context.Context, wish *entity.Wish) (string, error)
+	// Update сохраняет поля желания, которые стример может менять через API
 	Update(ctx context.Context, wish *entity.Wish) error
+	// UpdateDetails сохраняет название, описание, ссылку, целевую сумму, изображение и приоритет желания.
+	// Если желание уже не в статусе status, возвращает ErrWishStatusChanged
+	UpdateDetails(ctx context.Context, wish *entity.Wish, status string) error
+	// UpdateStatus переводит желание из статуса from в статус to.
+	// Если желание уже не в статусе from, возвращает ErrWishStatusChanged
+	UpdateStatus(ctx context.Context, uuid, from, to string) error
+	// MarkPriceMismatch переводит желание из pending в mismatch и сохраняет цену из контракта
+	MarkPriceMismatch(ctx context.Context, uuid string, onchainPrice entity.Amount) error
+	// AcceptOnchainPrice принимает цену из контракта как целевую сумму и переводит желание из mismatch в active
+	AcceptOnchainPrice(ctx context.Context, uuid string) error
+	// RepairCredits заменяет список зачисленных платежей и накопленную сумму желания значениями, посчитанными по истории.
+	// Если накопленная сумма уже не равна stored, возвращает ErrWishStatusChanged
+	RepairCredits(ctx context.Context, uuid string, stored entity.Amount, paymentIDs []string, amount entity.Amount) error
+	// GetOrder возвращает порядок желаний стримера. Если порядок ещё не сохранялся, возвращает пустой с версией 0
+	GetOrder(ctx context.Context, streamerUUID string) (*entity.WishOrder, error)
+	// Reorder заменяет порядок желаний стримера на uuids, если его версия всё ещё равна version.
+	// Иначе возвращает ErrWishOrderChanged
+	Reorder(ctx context.Context, streamerUUID string, uuids []string, version int64) error
 	GetByUUID(ctx context.Context, uuid string) (*entity.Wish, error)
+	// GetByStreamerUUID возвращает желания стримера в порядке, заданном стримером
 	GetByStreamerUUID(ctx context.Context, streamerUUID string) ([]*entity.Wish, error)
+	// GetPageByStreamerUUID возвращает страницу желаний стримера в статусах statuses (пустой список — любые статусы)
+	// и общее число таких желаний
+	GetPageByStreamerUUID(ctx context.Context, streamerUUID string, statuses []string, page, pageSize int) ([]*entity.Wish, int64, error)
+	GetByOnchainID(ctx context.Context, onchainID uint64) (*entity.Wish, error)
+	// CreditPayment увеличивает накопленную сумму желания на платёж paymentID.
+	// Повторный вызов для того же платежа ничего не меняет
+	CreditPayment(ctx context.Context, uuid, paymentID string, amount entity.Amount) error
+	// RevertPayment отменяет зачисление платежа paymentID, если оно было
+	RevertPayment(ctx context.Context, uuid, paymentID string, amount entity.Amount) error
+	// SetRelayHolder запоминает адрес релеера, под которым желание добавлено в контракт
+	SetRelayHolder(ctx context.Context, uuid, relayer string) error
+	// ReleaseRelayHolder отмечает, что желание удалено из массива желаний релеера
+	ReleaseRelayHolder(ctx context.Context, uuid string) error
+	// SetContract запоминает адрес деплоя контракта, в который добавлено желание. Пустой адрес сбрасывает привязку
+	SetContract(ctx context.Context, uuid, contractAddress string) error
+	// CountRelayHeld возвращает число желаний, которые хранятся в контракте под адресом релеера
+	CountRelayHeld(ctx context.Context) (int64, error)
+	// ResetChainState возвращает все желания в статус pending с нулевой накопленной суммой
+	// перед повторной загрузкой состояния из блокчейна
+	ResetChainState(ctx context.Context) error
 }
@@ -0,0 +1,25 @@
+package usecase
+
+import (
+	"backend/internal/entity"
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotAdmin пользователь не входит в список администраторов платформы
+	ErrNotAdmin             = errors.New("admin access required")
+	ErrInvalidRevenuePeriod = errors.New("invalid revenue period")
+)
+
+type AdminUsecase interface {
+	// IsAdmin проверяет, что Telegram ID пользователя указан в admin_telegram_ids
+	IsAdmin(ctx context.Context, userUUID string) (bool, error)
+	// GetRevenueByDay возвращает донаты и комиссию платформы по дням за период [from, to]
+	GetRevenueByDay(ctx context.Context, from, to time.Time) (*entity.RevenueResponse, error)
+	// GetRevenueByStreamer возвращает донаты и комиссию платформы по стримерам за период [from, to]
+	GetRevenueByStreamer(ctx context.Context, from, to time.Time) (*entity.RevenueResponse, error)
+	// GetOwnerBalance читает ownerBalance контракта
+	GetOwnerBalance(ctx context.Context) (*entity.OwnerBalanceResponse, error)
+}
@@ -0,0 +1,11 @@
+package usecase
+
+import (
+	"backend/internal/entity"
+	"context"
+)
+
+type BalanceUsecase interface {
+	// GetBalance возвращает баланс стримера в контракте и итоги его донатов и выводов
+	GetBalance(ctx context.Context, userUUID string) (*entity.BalanceResponse, error)
+}
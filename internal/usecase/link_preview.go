@@ -0,0 +1,22 @@
+package usecase
+
+import (
+	"backend/internal/entity"
+	"context"
+	"errors"
+)
+
+var (
+	ErrLinkPreviewInvalidURL = errors.New("invalid link")
+	// ErrLinkPreviewForbidden ссылка ведёт во внутреннюю сеть
+	ErrLinkPreviewForbidden = errors.New("link address is not allowed")
+	// ErrLinkPreviewUnavailable страница не загрузилась за отведённое время или вернула ошибку
+	ErrLinkPreviewUnavailable = errors.New("link is unavailable")
+	// ErrLinkPreviewUnsupported по ссылке не HTML страница или она слишком большая
+	ErrLinkPreviewUnsupported = errors.New("link content is not supported")
+)
+
+type LinkPreviewUsecase interface {
+	// Preview загружает страницу товара и возвращает черновик желания
+	Preview(ctx context.Context, req entity.LinkPreviewRequest) (*entity.LinkPreviewResponse, error)
+}
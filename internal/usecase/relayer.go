@@ -0,0 +1,20 @@
+package usecase
+
+import (
+	"backend/internal/entity"
+	"context"
+	"errors"
+)
+
+var (
+	ErrRelayerDisabled     = errors.New("relayer disabled")
+	ErrInvalidRelayAction  = errors.New("invalid relay action")
+	ErrRelayAlreadyQueued  = errors.New("relay transaction already queued")
+	ErrWishStatusForAction = errors.New("wish status does not allow this action")
+	ErrRelayerCapacity     = errors.New("relayer wish capacity reached")
+)
+
+type RelayerUsecase interface {
+	RelayWish(ctx context.Context, req entity.RelayWishRequest) (*entity.RelayedTransactionResponse, error)
+	GetWishTransactions(ctx context.Context, userUUID, wishUUID string) ([]entity.RelayedTransactionResponse, error)
+}
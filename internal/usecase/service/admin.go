@@ -0,0 +1,133 @@
+package service
+
+import (
+	"backend/internal/contract"
+	"backend/internal/entity"
+	"backend/internal/repo"
+	"backend/internal/usecase"
+	"backend/pkg/ethrpc"
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// maxRevenuePeriod ограничивает период отчёта о доходах, чтобы агрегация не проходила всю историю
+const maxRevenuePeriod = 366 * 24 * time.Hour
+
+// AdminService отчёты о доходах платформы для администраторов
+type AdminService struct {
+	userRepo       repo.UserRepository
+	historyRepo    repo.HistoryRepository
+	client         ethrpc.Client
+	donates        *contract.Donates
+	commissionRate *commissionRate
+	adminIDs       []string
+}
+
+func NewAdminService(
+	userRepo repo.UserRepository,
+	historyRepo repo.HistoryRepository,
+	client ethrpc.Client,
+	donates *contract.Donates,
+	adminTelegramIDs []string,
+) *AdminService {
+	return &AdminService{
+		userRepo:       userRepo,
+		historyRepo:    historyRepo,
+		client:         client,
+		donates:        donates,
+		commissionRate: &commissionRate{client: client, donates: donates},
+		adminIDs:       adminTelegramIDs,
+	}
+}
+
+func (s *AdminService) IsAdmin(ctx context.Context, userUUID string) (bool, error) {
+	user, err := s.userRepo.GetByUUID(ctx, userUUID)
+	if err != nil {
+		if errors.Is(err, repo.ErrUserNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return user.TelegramID != "" && slices.Contains(s.adminIDs, user.TelegramID), nil
+}
+
+func (s *AdminService) GetRevenueByDay(ctx context.Context, from, to time.Time) (*entity.RevenueResponse, error) {
+	if err := validateRevenuePeriod(from, to); err != nil {
+		return nil, err
+	}
+	entries, err := s.historyRepo.GetRevenueByDay(ctx, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	return s.buildRevenue(ctx, from, to, entries)
+}
+
+func (s *AdminService) GetRevenueByStreamer(ctx context.Context, from, to time.Time) (*entity.RevenueResponse, error) {
+	if err := validateRevenuePeriod(from, to); err != nil {
+		return nil, err
+	}
+	entries, err := s.historyRepo.GetRevenueByStreamer(ctx, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	response, err := s.buildRevenue(ctx, from, to, entries)
+	if err != nil {
+		return nil, err
+	}
+	// Сортируем после оценки комиссии со старых донатов, иначе порядок не совпадал бы с суммами в ответе
+	slices.SortStableFunc(response.Entries, func(a, b entity.RevenueEntry) int {
+		return b.Commission.Cmp(a.Commission)
+	})
+	return response, nil
+}
+
+// buildRevenue дополняет комиссию оценкой по K для донатов без сохранённой комиссии и считает итог за период
+func (s *AdminService) buildRevenue(ctx context.Context, from, to time.Time, entries []entity.RevenueEntry) (*entity.RevenueResponse, error) {
+	response := &entity.RevenueResponse{
+		From:    from.Format(time.DateOnly),
+		To:      to.Format(time.DateOnly),
+		Entries: entries,
+	}
+	for i := range entries {
+		legacyCommission, err := s.commissionRate.estimate(ctx, entries[i].LegacyDonated)
+		if err != nil {
+			return nil, err
+		}
+		entries[i].Commission = entries[i].Commission.Add(legacyCommission)
+		response.Donated = response.Donated.Add(entries[i].Donated)
+		response.Commission = response.Commission.Add(entries[i].Commission)
+		response.Donations += entries[i].Donations
+	}
+	return response, nil
+}
+
+func (s *AdminService) GetOwnerBalance(ctx context.Context) (*entity.OwnerBalanceResponse, error) {
+	if s.donates.Address() == (common.Address{}) {
+		return nil, usecase.ErrContractNotConfigured
+	}
+	balance, err := s.donates.ReadOwnerBalance(ctx, s.client, nil)
+	if err != nil {
+		return nil, err
+	}
+	rate, err := s.commissionRate.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.OwnerBalanceResponse{
+		ContractAddress: s.donates.Address().Hex(),
+		OwnerBalance:    entity.NewAmount(balance),
+		CommissionRate:  rate.Uint64(),
+		UpdatedAt:       time.Now(),
+	}, nil
+}
+
+func validateRevenuePeriod(from, to time.Time) error {
+	if to.Before(from) || to.Sub(from) > maxRevenuePeriod {
+		return usecase.ErrInvalidRevenuePeriod
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"backend/internal/contract"
+	"backend/internal/entity"
+	"backend/internal/repo"
+	"backend/internal/usecase"
+	"backend/pkg/ethrpc"
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// balanceCacheTTL сколько баланс из контракта отдаётся из кеша: мини-приложение опрашивает эндпоинт,
+// а новый блок в Polygon появляется раз в пару секунд
+const balanceCacheTTL = 10 * time.Second
+
+type cachedBalance struct {
+	balance *big.Int
+	readAt  time.Time
+}
+
+// BalanceService читает баланс стримера из контракта и считает итоги платежей по истории
+type BalanceService struct {
+	userRepo    repo.UserRepository
+	historyRepo repo.HistoryRepository
+	client      ethrpc.Client
+	donates     *contract.Donates
+	// commissionRate нужна для оценки комиссии с донатов, проиндексированных до её сохранения
+	commissionRate *commissionRate
+
+	mu       sync.Mutex
+	balances map[common.Address]cachedBalance
+}
+
+func NewBalanceService(
+	userRepo repo.UserRepository,
+	historyRepo repo.HistoryRepository,
+	client ethrpc.Client,
+	donates *contract.Donates,
+) *BalanceService {
+	return &BalanceService{
+		userRepo:       userRepo,
+		historyRepo:    historyRepo,
+		client:         client,
+		donates:        donates,
+		balances:       make(map[common.Address]cachedBalance),
+		commissionRate: &commissionRate{client: client, donates: donates},
+	}
+}
+
+func (s *BalanceService) GetBalance(ctx context.Context, userUUID string) (*entity.BalanceResponse, error) {
+	user, err := s.userRepo.GetByUUID(ctx, userUUID)
+	if err != nil {
+		if errors.Is(err, repo.ErrUserNotFound) {
+			return nil, usecase.ErrUserNotFound
+		}
+		return nil, err
+	}
+	wallet, err := userWallet(user)
+	if err != nil {
+		return nil, err
+	}
+	if s.donates.Address() == (common.Address{}) {
+		return nil, usecase.ErrContractNotConfigured
+	}
+
+	balance, err := s.onchainBalance(ctx, wallet)
+	if err != nil {
+		return nil, err
+	}
+	totals, err := s.historyRepo.GetTotals(ctx, user.UUID)
+	if err != nil {
+		return nil, err
+	}
+	legacyCommission, err := s.commissionRate.estimate(ctx, totals.LegacyDonated)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.BalanceResponse{
+		PolygonWallet: wallet.Hex(),
+		Withdrawable:  entity.NewAmount(balance.balance),
+		Donated:       totals.Donated,
+		Withdrawn:     totals.Withdrawn,
+		Commission:    totals.Commission.Add(legacyCommission),
+		UpdatedAt:     balance.readAt,
+	}, nil
+}
+
+// onchainBalance возвращает currentBalance адреса из контракта, обращаясь к узлу не чаще раза в balanceCacheTTL
+func (s *BalanceService) onchainBalance(ctx context.Context, wallet common.Address) (cachedBalance, error) {
+	s.mu.Lock()
+	cached, ok := s.balances[wallet]
+	s.mu.Unlock()
+	if ok && time.Since(cached.readAt) < balanceCacheTTL {
+		return cached, nil
+	}
+
+	user, err := s.donates.ReadUser(ctx, s.client, wallet, nil)
+	if err != nil {
+		return cachedBalance{}, err
+	}
+
+	cached = cachedBalance{balance: user.Balance, readAt: time.Now()}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Устаревшие записи удаляем здесь же, чтобы кеш не рос с числом стримеров
+	for addr, entry := range s.balances {
+		if time.Since(entry.readAt) >= balanceCacheTTL {
+			delete(s.balances, addr)
+		}
+	}
+	s.balances[wallet] = cached
+	return cached, nil
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"backend/internal/contract"
+	"backend/internal/entity"
+	"backend/pkg/ethrpc"
+	"context"
+	"math/big"
+	"sync"
+)
+
+// commissionRate читает K контракта один раз: комиссия не меняется после деплоя.
+// Неудачный запрос повторяется при следующем вызове
+type commissionRate struct {
+	client  ethrpc.Client
+	donates *contract.Donates
+
+	mu   sync.Mutex
+	rate *big.Int
+}
+
+func (c *commissionRate) get(ctx context.Context) (*big.Int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rate != nil {
+		return c.rate, nil
+	}
+	rate, err := c.donates.ReadCommissionRate(ctx, c.client, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.rate = rate
+	return rate, nil
+}
+
+// estimate оценивает комиссию с донатов, проиндексированных до сохранения комиссии. Контракт округляет
+// комиссию вниз по каждому донату, поэтому оценка может превышать фактическую на несколько wei
+func (c *commissionRate) estimate(ctx context.Context, legacyDonated entity.Amount) (entity.Amount, error) {
+	if legacyDonated.IsZero() {
+		return entity.Amount{}, nil
+	}
+	rate, err := c.get(ctx)
+	if err != nil {
+		return entity.Amount{}, err
+	}
+	return entity.NewAmount(new(big.Int).Div(new(big.Int).Mul(legacyDonated.Wei(), rate), big.NewInt(1000))), nil
+}
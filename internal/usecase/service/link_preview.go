@@ -0,0 +1,119 @@
+package service
+
+import (
+	"backend/internal/entity"
+	"backend/internal/linkpreview"
+	"backend/internal/usecase"
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"unicode/utf8"
+)
+
+// Черновик обрезается до ограничений, с которыми желание примет AddWish
+const (
+	maxWishNameLength        = 100
+	maxWishDescriptionLength = 500
+	// maxWishURLLength ограничивает ссылку, чтобы не загружать и не сохранять заведомо мусорные URL
+	maxWishURLLength = 2048
+)
+
+// LinkPreviewService заполняет черновик желания по странице товара
+type LinkPreviewService struct {
+	fetcher  *linkpreview.Fetcher
+	staticUC usecase.StaticUsecase
+}
+
+func NewLinkPreviewService(fetcher *linkpreview.Fetcher, staticUC usecase.StaticUsecase) *LinkPreviewService {
+	return &LinkPreviewService{
+		fetcher:  fetcher,
+		staticUC: staticUC,
+	}
+}
+
+func (s *LinkPreviewService) Preview(ctx context.Context, req entity.LinkPreviewRequest) (*entity.LinkPreviewResponse, error) {
+	if len(req.URL) > maxWishURLLength {
+		return nil, usecase.ErrLinkPreviewInvalidURL
+	}
+	body, finalURL, err := s.fetcher.FetchPage(ctx, req.URL)
+	if err != nil {
+		return nil, linkPreviewError(err)
+	}
+	preview := linkpreview.Parse(body, finalURL)
+
+	wishURL := preview.URL
+	if len(wishURL) > maxWishURLLength {
+		wishURL = finalURL.String()
+	}
+	resp := &entity.LinkPreviewResponse{
+		Draft: entity.AddWishRequest{
+			WishURL: &wishURL,
+			Name:    truncateUTF8(preview.Title, maxWishNameLength),
+		},
+		SiteName: preview.SiteName,
+		ImageURL: preview.Image,
+		Price:    preview.Price,
+		Currency: preview.Currency,
+	}
+	if preview.Description != "" {
+		description := truncateUTF8(preview.Description, maxWishDescriptionLength)
+		resp.Draft.Description = &description
+	}
+	if preview.Currency == "POL" {
+		if target, err := entity.ParsePOL(preview.Price); err == nil {
+			resp.Draft.PolTarget = target
+		}
+	}
+
+	// Изображение необязательно для черновика, поэтому ошибка его загрузки возвращается в ответе
+	if req.ImportImage && preview.Image != "" {
+		imageID, err := s.importImage(ctx, preview.Image, req.UserUUID)
+		if err != nil {
+			log.Printf("Не удалось загрузить изображение %s: %v", preview.Image, err)
+			resp.ImageImportError = err.Error()
+		} else {
+			resp.Draft.Image = imageID
+		}
+	}
+	return resp, nil
+}
+
+// importImage загружает изображение товара и сохраняет его через StaticUsecase как изображение желания
+func (s *LinkPreviewService) importImage(ctx context.Context, imageURL, userUUID string) (string, error) {
+	data, contentType, err := s.fetcher.FetchImage(ctx, imageURL)
+	if err != nil {
+		return "", linkPreviewError(err)
+	}
+	return s.staticUC.Upload(ctx, "wish", bytes.NewReader(data), contentType, userUUID)
+}
+
+// linkPreviewError переводит ошибки загрузки страницы в ошибки usecase
+func linkPreviewError(err error) error {
+	switch {
+	case errors.Is(err, linkpreview.ErrInvalidURL):
+		return usecase.ErrLinkPreviewInvalidURL
+	case errors.Is(err, linkpreview.ErrForbiddenAddress):
+		return usecase.ErrLinkPreviewForbidden
+	case errors.Is(err, linkpreview.ErrUnsupportedContent), errors.Is(err, linkpreview.ErrTooLarge):
+		return usecase.ErrLinkPreviewUnsupported
+	case errors.Is(err, context.Canceled):
+		return err
+	default:
+		log.Printf("Ошибка загрузки ссылки: %v", err)
+		return usecase.ErrLinkPreviewUnavailable
+	}
+}
+
+// truncateUTF8 обрезает строку до maxBytes байт по границе символа: длина полей желания проверяется в байтах
+func truncateUTF8(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	s = s[:maxBytes]
+	for len(s) > 0 && !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"backend/internal/entity"
+	"backend/internal/repo"
+	"backend/internal/usecase"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RelayerService ставит вызовы контракта в очередь релеера. Подписывает и отправляет их
+// процесс индексатора, поэтому nonce адреса бэкенда распределяется в одном месте
+type RelayerService struct {
+	relayerRepo repo.RelayerRepository
+	wishRepo    repo.WishRepository
+	// contractAddress действующий деплой контракта, в который релеер отправляет вызовы
+	contractAddress string
+	enabled         bool
+	// maxWishes сколько желаний может одновременно храниться под адресом релеера:
+	// addWish перебирает массив желаний отправителя, и его газ растёт с каждым желанием
+	maxWishes int64
+}
+
+func NewRelayerService(
+	relayerRepo repo.RelayerRepository,
+	wishRepo repo.WishRepository,
+	contractAddress string,
+	enabled bool,
+	maxWishes int64,
+) *RelayerService {
+	return &RelayerService{
+		relayerRepo:     relayerRepo,
+		wishRepo:        wishRepo,
+		contractAddress: contractAddress,
+		enabled:         enabled,
+		maxWishes:       maxWishes,
+	}
+}
+
+func (s *RelayerService) RelayWish(ctx context.Context, req entity.RelayWishRequest) (*entity.RelayedTransactionResponse, error) {
+	if !s.enabled {
+		return nil, usecase.ErrRelayerDisabled
+	}
+	wish, err := s.wishRepo.GetByUUID(ctx, req.WishUUID)
+	if err != nil {
+		return nil, usecase.ErrWishNotFound
+	}
+	if wish.StreamerUUID != req.UserUUID {
+		return nil, usecase.ErrWishNotFound
+	}
+	// Вызовы completeOrRemoveWish для желаний из выведенных деплоев релеер отправил бы не в тот контракт
+	if wish.ContractAddress != "" && wish.ContractAddress != s.contractAddress {
+		return nil, usecase.ErrWishOnRetiredContract
+	}
+
+	switch req.Action {
+	case entity.RelayActionAdd:
+		if wish.Status != "pending" {
+			return nil, usecase.ErrWishStatusForAction
+		}
+	case entity.RelayActionComplete:
+		if wish.Status != "active" && wish.Status != "funded" {
+			return nil, usecase.ErrWishStatusForAction
+		}
+	case entity.RelayActionRemove:
+		// Желание с расхождением цены уже есть в контракте, поэтому его тоже можно удалить
+		if wish.Status != "active" && wish.Status != "mismatch" && wish.Status != "funded" {
+			return nil, usecase.ErrWishStatusForAction
+		}
+	default:
+		return nil, usecase.ErrInvalidRelayAction
+	}
+
+	if err := s.checkNotQueued(ctx, wish.UUID); err != nil {
+		return nil, err
+	}
+	if req.Action == entity.RelayActionAdd {
+		if err := s.checkCapacity(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return s.enqueue(ctx, wish, req.Action)
+}
+
+// RemoveDeletedWish ставит в очередь удаление из контракта желания, которое стример удалил в приложении,
+// пока его addWish ещё не был проиндексирован. Вызывается индексатором, поэтому статус deleted здесь допустим
+func (s *RelayerService) RemoveDeletedWish(ctx context.Context, wish *entity.Wish) error {
+	if !s.enabled {
+		return usecase.ErrRelayerDisabled
+	}
+	if wish.Status != "deleted" {
+		return usecase.ErrWishStatusForAction
+	}
+	if wish.ContractAddress != s.contractAddress {
+		return usecase.ErrWishOnRetiredContract
+	}
+	if err := s.checkNotQueued(ctx, wish.UUID); err != nil {
+		return err
+	}
+	_, err := s.enqueue(ctx, wish, entity.RelayActionRemove)
+	return err
+}
+
+// checkNotQueued не даёт поставить в очередь повторный вызов, пока предыдущий не завершён
+func (s *RelayerService) checkNotQueued(ctx context.Context, wishUUID string) error {
+	existing, err := s.relayerRepo.GetByWishUUID(ctx, wishUUID)
+	if err != nil {
+		return err
+	}
+	for _, tx := range existing {
+		if tx.Status == entity.RelayStatusQueued || tx.Status == entity.RelayStatusPending {
+			return usecase.ErrRelayAlreadyQueued
+		}
+	}
+	return nil
+}
+
+func (s *RelayerService) enqueue(ctx context.Context, wish *entity.Wish, action string) (*entity.RelayedTransactionResponse, error) {
+	now := time.Now()
+	tx := &entity.RelayedTransaction{
+		ID:           uuid.New().String(),
+		WishUUID:     wish.UUID,
+		StreamerUUID: wish.StreamerUUID,
+		Action:       action,
+		Status:       entity.RelayStatusQueued,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := s.relayerRepo.Add(ctx, tx); err != nil {
+		return nil, err
+	}
+	response := toRelayedTransactionResponse(tx)
+	return &response, nil
+}
+
+// checkCapacity учитывает желания под адресом релеера и ещё не включённые в блок вызовы addWish
+func (s *RelayerService) checkCapacity(ctx context.Context) error {
+	if s.maxWishes <= 0 {
+		return nil
+	}
+	held, err := s.wishRepo.CountRelayHeld(ctx)
+	if err != nil {
+		return err
+	}
+	for _, status := range []string{entity.RelayStatusQueued, entity.RelayStatusPending} {
+		txs, err := s.relayerRepo.GetByStatus(ctx, status)
+		if err != nil {
+			return err
+		}
+		for _, tx := range txs {
+			if tx.Action == entity.RelayActionAdd {
+				held++
+			}
+		}
+	}
+	if held >= s.maxWishes {
+		return usecase.ErrRelayerCapacity
+	}
+	return nil
+}
+
+func (s *RelayerService) GetWishTransactions(ctx context.Context, userUUID, wishUUID string) ([]entity.RelayedTransactionResponse, error) {
+	wish, err := s.wishRepo.GetByUUID(ctx, wishUUID)
+	if err != nil {
+		return nil, usecase.ErrWishNotFound
+	}
+	if wish.StreamerUUID != userUUID {
+		return nil, usecase.ErrWishNotFound
+	}
+	txs, err := s.relayerRepo.GetByWishUUID(ctx, wishUUID)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]entity.RelayedTransactionResponse, 0, len(txs))
+	for _, tx := range txs {
+		responses = append(responses, toRelayedTransactionResponse(tx))
+	}
+	return responses, nil
+}
+
+func toRelayedTransactionResponse(tx *entity.RelayedTransaction) entity.RelayedTransactionResponse {
+	return entity.RelayedTransactionResponse{
+		ID:          tx.ID,
+		Action:      tx.Action,
+		Status:      tx.Status,
+		TxHash:      tx.TxHash,
+		Attempts:    tx.Attempts,
+		BlockNumber: tx.BlockNumber,
+		Error:       tx.Error,
+		CreatedAt:   tx.CreatedAt,
+		UpdatedAt:   tx.UpdatedAt,
+	}
+}
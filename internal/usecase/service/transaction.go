@@ -0,0 +1,274 @@
+package service
+
+import (
+	"backend/internal/contract"
+	"backend/internal/entity"
+	"backend/internal/repo"
+	"backend/internal/usecase"
+	"backend/pkg/ethrpc"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/google/uuid"
+)
+
+// minimalTransferCost минимальная сумма доната в wei (MINIMAL_TRANSFER_COST в контракте)
+var minimalTransferCost = big.NewInt(1_000_000_000_000)
+
+// TransactionService собирает неподписанные транзакции вызовов контракта для кошелька в мини-приложении
+type TransactionService struct {
+	userRepo repo.UserRepository
+	wishRepo repo.WishRepository
+	client   ethrpc.Client
+	donates  *contract.Donates
+
+	mu      sync.Mutex
+	chainID *big.Int
+}
+
+func NewTransactionService(
+	userRepo repo.UserRepository,
+	wishRepo repo.WishRepository,
+	client ethrpc.Client,
+	donates *contract.Donates,
+) *TransactionService {
+	return &TransactionService{
+		userRepo: userRepo,
+		wishRepo: wishRepo,
+		client:   client,
+		donates:  donates,
+	}
+}
+
+func (s *TransactionService) BuildRegisterUser(ctx context.Context, userUUID string) (*entity.UnsignedTransaction, error) {
+	user, err := s.userRepo.GetByUUID(ctx, userUUID)
+	if err != nil {
+		return nil, usecase.ErrUserNotFound
+	}
+	from, err := userWallet(user)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.donates.PackRegisterUser(user.Name, user.UUID, user.Topics)
+	if err != nil {
+		return nil, err
+	}
+	return s.build(ctx, &from, data, big.NewInt(0))
+}
+
+func (s *TransactionService) BuildAddWish(ctx context.Context, userUUID, wishUUID string) (*entity.UnsignedTransaction, error) {
+	wish, err := s.wishRepo.GetByUUID(ctx, wishUUID)
+	if err != nil {
+		return nil, usecase.ErrWishNotFound
+	}
+	if wish.StreamerUUID != userUUID {
+		return nil, usecase.ErrWishNotFound
+	}
+	if wish.Status != "pending" {
+		return nil, usecase.ErrInvalidWish
+	}
+	user, err := s.userRepo.GetByUUID(ctx, userUUID)
+	if err != nil {
+		return nil, usecase.ErrUserNotFound
+	}
+	from, err := userWallet(user)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.donates.PackAddWish(wishArgs(wish))
+	if err != nil {
+		return nil, err
+	}
+	return s.build(ctx, &from, data, big.NewInt(0))
+}
+
+// BuildRemoveWish собирает completeOrRemoveWish с удалением желания. Желание, добавленное релеером,
+// хранится в контракте под его адресом, и удалить его стример может так же, как своё
+func (s *TransactionService) BuildRemoveWish(ctx context.Context, userUUID, wishUUID string) (*entity.UnsignedTransaction, error) {
+	wish, err := s.wishRepo.GetByUUID(ctx, wishUUID)
+	if err != nil {
+		return nil, usecase.ErrWishNotFound
+	}
+	if wish.StreamerUUID != userUUID {
+		return nil, usecase.ErrWishNotFound
+	}
+	if wish.Status != "active" && wish.Status != "mismatch" && wish.Status != "funded" {
+		return nil, usecase.ErrInvalidWish
+	}
+	if wish.ContractAddress != "" && wish.ContractAddress != s.donates.Address().Hex() {
+		return nil, usecase.ErrWishOnRetiredContract
+	}
+	user, err := s.userRepo.GetByUUID(ctx, userUUID)
+	if err != nil {
+		return nil, usecase.ErrUserNotFound
+	}
+	from, err := userWallet(user)
+	if err != nil {
+		return nil, err
+	}
+	owner := from
+	if wish.RelayedBy != "" {
+		owner = common.HexToAddress(wish.RelayedBy)
+	}
+	data, err := s.donates.PackCompleteOrRemoveWish(owner, wish.UUID, wish.OnchainID, true)
+	if err != nil {
+		return nil, err
+	}
+	return s.build(ctx, &from, data, big.NewInt(0))
+}
+
+func (s *TransactionService) BuildDonate(ctx context.Context, req entity.BuildDonateTransactionRequest) (*entity.UnsignedTransaction, error) {
+	value := req.Amount.Wei()
+	if value.Cmp(minimalTransferCost) < 0 {
+		return nil, usecase.ErrInvalidTransactionRequest
+	}
+	if len(req.Username) > 100 || len(req.Message) > 500 {
+		return nil, usecase.ErrInvalidTransactionRequest
+	}
+	var from *common.Address
+	if req.From != "" {
+		if !common.IsHexAddress(req.From) {
+			return nil, usecase.ErrInvalidTransactionRequest
+		}
+		address := common.HexToAddress(req.From)
+		from = &address
+	}
+
+	streamer, err := s.userRepo.GetByUUID(ctx, req.StreamerUUID)
+	if err != nil {
+		return nil, usecase.ErrUserNotFound
+	}
+	toAddress, err := userWallet(streamer)
+	if err != nil {
+		return nil, err
+	}
+
+	args := contract.DonateArgs{
+		PaymentUUID: uuid.New().String(),
+		UserName:    req.Username,
+		MessageText: req.Message,
+		ToUUID:      streamer.UUID,
+		ToAddress:   toAddress,
+	}
+	if req.WishUUID != nil && *req.WishUUID != "" {
+		wish, err := s.wishRepo.GetByUUID(ctx, *req.WishUUID)
+		if err != nil || wish.StreamerUUID != streamer.UUID {
+			return nil, usecase.ErrWishNotFound
+		}
+		if wish.Status != "active" {
+			return nil, usecase.ErrInvalidWish
+		}
+		if wish.ContractAddress != "" && wish.ContractAddress != s.donates.Address().Hex() {
+			return nil, usecase.ErrWishOnRetiredContract
+		}
+		args.WishID = wish.OnchainID
+	}
+
+	data, err := s.donates.PackDonate(args)
+	if err != nil {
+		return nil, err
+	}
+	return s.build(ctx, from, data, value)
+}
+
+func (s *TransactionService) BuildWithdraw(ctx context.Context, req entity.BuildWithdrawTransactionRequest) (*entity.UnsignedTransaction, error) {
+	amount := req.Amount.Wei()
+	if amount.Sign() <= 0 {
+		return nil, usecase.ErrInvalidTransactionRequest
+	}
+	user, err := s.userRepo.GetByUUID(ctx, req.UserUUID)
+	if err != nil {
+		return nil, usecase.ErrUserNotFound
+	}
+	from, err := userWallet(user)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.donates.PackWithdraw(uuid.New().String(), user.UUID, amount)
+	if err != nil {
+		return nil, err
+	}
+	return s.build(ctx, &from, data, big.NewInt(0))
+}
+
+// build дополняет calldata адресом контракта, chain ID и оценкой газа
+func (s *TransactionService) build(ctx context.Context, from *common.Address, data []byte, value *big.Int) (*entity.UnsignedTransaction, error) {
+	if s.donates.Address() == (common.Address{}) {
+		return nil, usecase.ErrContractNotConfigured
+	}
+	chainID, err := s.getChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	to := s.donates.Address()
+	tx := &entity.UnsignedTransaction{
+		To:      to.Hex(),
+		Data:    hexutil.Encode(data),
+		Value:   value.String(),
+		ChainID: chainID.Int64(),
+	}
+	if from == nil {
+		return tx, nil
+	}
+
+	tx.From = from.Hex()
+	gas, err := s.client.EstimateGas(ctx, ethereum.CallMsg{From: *from, To: &to, Value: value, Data: data})
+	if err != nil {
+		// Ответ узла с ошибкой означает, что вызов будет отменён (revert, недостаточно средств)
+		var rpcErr rpc.Error
+		if errors.As(err, &rpcErr) {
+			return nil, errors.Join(usecase.ErrTransactionWouldRevert, err)
+		}
+		return nil, fmt.Errorf("ошибка оценки газа: %w", err)
+	}
+	tx.Gas = gas
+	return tx, nil
+}
+
+// getChainID запрашивает chain ID у узла один раз, неудачный запрос повторяется при следующем вызове
+func (s *TransactionService) getChainID(ctx context.Context) (*big.Int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.chainID != nil {
+		return s.chainID, nil
+	}
+	chainID, err := s.client.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения Chain ID: %w", err)
+	}
+	s.chainID = chainID
+	return chainID, nil
+}
+
+// wishArgs собирает аргументы addWish из желания
+func wishArgs(wish *entity.Wish) contract.WishArgs {
+	args := contract.WishArgs{
+		UserUUID:  wish.StreamerUUID,
+		WishUUID:  wish.UUID,
+		OnchainID: wish.OnchainID,
+		Price:     wish.PolTarget.Wei(),
+		Name:      wish.Name,
+	}
+	if wish.WishURL != nil {
+		args.Link = *wish.WishURL
+	}
+	if wish.Description != nil {
+		args.Description = *wish.Description
+	}
+	return args
+}
+
+// userWallet возвращает кошелёк пользователя в Polygon
+func userWallet(user *entity.User) (common.Address, error) {
+	if !common.IsHexAddress(user.PolygonWallet) {
+		return common.Address{}, usecase.ErrWalletNotSet
+	}
+	return common.HexToAddress(user.PolygonWallet), nil
+}
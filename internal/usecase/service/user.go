@@ -38,8 +38,10 @@ func (s *UserService) Register(ctx context.Context, req entity.RegisterUserReque
 	if err := s.validateRegisterRequest(req); err != nil {
 		return "", errors.Join(usecase.ErrInvalidRegisterRequest, err)
 	}
+	newUUID := uuid.New().String()
 	user := &entity.User{
-		UUID:                  uuid.New().String(),
+		UUID:                  newUUID,
+		UUIDHash:              entity.UUIDTopicHash(newUUID),
 		PolygonWallet:         req.PolygonWallet,
 		Name:                  req.Name,
 		Topics:                req.Topics,
@@ -115,6 +117,7 @@ func (s *UserService) GetProfile(ctx context.Context, uuid string) (*entity.User
 		Avatar:                s.buildImageURL(user.Avatar),
 		Topics:                user.Topics,
 		PolygonWallet:         user.PolygonWallet,
+		OnchainRegistered:     user.OnchainRegistered,
 	}
 	if user.BackgroundImage != nil && *user.BackgroundImage != "" {
 		backgroundImageURL := s.buildImageURL(*user.BackgroundImage)
@@ -7,64 +7,23 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
-	"math"
 	"math/big"
-	"strings"
+	"slices"
 	"time"
 
-	"github.com/ethereum/go-ethereum"
-	"github.com/ethereum/go-ethereum/accounts/abi"
-	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/google/uuid"
 )
 
+// wishStatuses статусы желания, по которым стример может фильтровать свой список
+var wishStatuses = []string{"pending", "active", "mismatch", "funded", "complete", "deleted"}
+
 type WishService struct {
 	wishRepo       repo.WishRepository
 	staticRepo     repo.StaticFileRepository
 	userRepo       repo.UserRepository
 	blockchainRepo repo.BlockchainRepository
+	relayerUC      usecase.RelayerUsecase
 	staticBaseURL  string
-
-	// Blockchain monitoring
-	client       *ethclient.Client
-	contractAddr common.Address
-	contractABI  abi.ABI
-	stopChan     chan struct{}
-	isRunning    bool
-	pollInterval time.Duration
-	lastBlock    uint64
-}
-
-// BlockchainEvent представляет событие блокчейна для сохранения в БД
-type BlockchainEvent struct {
-	BlockNumber uint64    `bson:"block_number" json:"block_number"`
-	TxHash      string    `bson:"tx_hash" json:"tx_hash"`
-	EventType   string    `bson:"event_type" json:"event_type"`
-	ProcessedAt time.Time `bson:"processed_at" json:"processed_at"`
-}
-
-// WishAddedEvent событие добавления желания в контракт
-type WishAddedEvent struct {
-	UserUUID string
-	WishUUID string
-	Price    *big.Int
-}
-
-// WishCompletedEvent событие завершения желания
-type WishCompletedEvent struct {
-	UserUUID string
-	WishUUID string
-	Price    *big.Int
-}
-
-// WishDeletedEvent событие удаления желания
-type WishDeletedEvent struct {
-	UserUUID          string
-	WishUUID          string
-	AccumulatedAmount *big.Int
 }
 
 func NewWishService(
@@ -72,68 +31,28 @@ func NewWishService(
 	staticRepo repo.StaticFileRepository,
 	userRepo repo.UserRepository,
 	blockchainRepo repo.BlockchainRepository,
+	relayerUC usecase.RelayerUsecase,
 	staticBaseURL string,
-	polygonClient *ethclient.Client,
-	contractAddr common.Address,
-	contractABI abi.ABI,
 ) *WishService {
 	return &WishService{
 		wishRepo:       wishRepo,
 		staticRepo:     staticRepo,
 		userRepo:       userRepo,
 		blockchainRepo: blockchainRepo,
+		relayerUC:      relayerUC,
 		staticBaseURL:  staticBaseURL,
-		client:         polygonClient,
-		contractAddr:   contractAddr,
-		contractABI:    contractABI,
-		stopChan:       make(chan struct{}),
-		pollInterval:   10 * time.Second, // опрос каждые 10 секунд
 	}
 }
 
-// StartBlockchainMonitoring запускает фоновое отслеживание событий блокчейна
-func (s *WishService) StartBlockchainMonitoring(ctx context.Context) error {
-	if s.isRunning {
-		return fmt.Errorf("мониторинг блокчейна уже запущен")
-	}
-
-	// Получаем последний обработанный блок из БД или текущий блок
-	lastProcessedBlock, err := s.getLastProcessedBlock(ctx)
-	if err != nil {
-		log.Printf("Ошибка получения последнего обработанного блока: %v", err)
-		// Если нет записи, начинаем с текущего блока
-		header, err := s.client.HeaderByNumber(ctx, nil)
-		if err != nil {
-			return fmt.Errorf("ошибка получения текущего блока: %w", err)
-		}
-		s.lastBlock = header.Number.Uint64()
-	} else {
-		s.lastBlock = lastProcessedBlock
-	}
-
-	s.isRunning = true
-	log.Printf("Запускаем мониторинг событий смарт-контракта с блока %d", s.lastBlock)
-
-	go s.blockchainEventLoop(ctx)
-	return nil
-}
-
-// StopBlockchainMonitoring останавливает фоновое отслеживание
-func (s *WishService) StopBlockchainMonitoring() {
-	if !s.isRunning {
-		return
-	}
-
-	close(s.stopChan)
-	s.isRunning = false
-	log.Println("Мониторинг событий блокчейна остановлен")
-}
-
 func (s *WishService) AddWish(ctx context.Context, req entity.AddWishRequest) (string, error) {
 	user, err := s.userRepo.GetByUUID(ctx, req.UserUUID)
 	if err != nil {
 		return "", usecase.ErrUserNotFound
 	}
+	// Желание нельзя отправить в контракт, пока кошелёк стримера в нём не зарегистрирован
+	if !user.OnchainRegistered {
+		return "", usecase.ErrUserNotRegistered
+	}
 	if err := s.validateAddWishRequest(req); err != nil {
 		return "", usecase.ErrInvalidWish
 	}
@@ -155,7 +74,6 @@ func (s *WishService) AddWish(ctx context.Context, req entity.AddWishRequest) (s
 		Description:  req.Description,
 		Image:        req.Image,
 		PolTarget:    req.PolTarget,
-		PolAmount:    0.0,
 		IsPriority:   req.IsPriority,
 		Status:       "pending",
 		CreatedAt:    time.Now(),
@@ -183,15 +101,8 @@ func (s *WishService) UpdateWish(ctx context.Context, req entity.UpdateWishReque
 	if wish.Status == "complete" || wish.Status == "deleted" {
 		return usecase.ErrInvalidWish
 	}
-	staticFile, err := s.staticRepo.GetByID(ctx, req.Image)
-	if err != nil {
-		return usecase.ErrStaticFileNotFound
-	}
-	if staticFile.Type != "wish" {
-		return usecase.ErrInvalidWish
-	}
-	if staticFile.UploaderUUID != req.UserUUID {
-		return usecase.ErrInvalidWish
+	if err := s.checkWishImage(ctx, req.Image, req.UserUUID); err != nil {
+		return err
 	}
 	wish.Image = req.Image
 	wish.IsPriority = req.IsPriority
@@ -206,349 +117,405 @@ func (s *WishService) UpdateWish(ctx context.Context, req entity.UpdateWishReque
 	return nil
 }
 
-func (s *WishService) GetWishes(ctx context.Context, streamerUUID string) ([]entity.WishResponse, error) {
-	wishes, err := s.wishRepo.GetByStreamerUUID(ctx, streamerUUID)
+// EditWish меняет поля желания. Название, описание и ссылку можно исправить в любом статусе до завершения:
+// в приложении показываются значения из базы, а копия в контракте остаётся прежней. Целевая сумма меняется
+// только до добавления в контракт, и пока релеер добавляет желание, поля из addWish не меняются
+func (s *WishService) EditWish(ctx context.Context, req entity.EditWishRequest) error {
+	wish, err := s.wishRepo.GetByUUID(ctx, req.WishUUID)
 	if err != nil {
-		return nil, err
+		return usecase.ErrWishNotFound
 	}
-	responses := make([]entity.WishResponse, 0, len(wishes))
-	for _, wish := range wishes {
-		if wish.Status != "active" {
-			continue
-		}
-		response := entity.WishResponse{
-			UUID:        wish.UUID,
-			WishURL:     wish.WishURL,
-			Name:        wish.Name,
-			Description: wish.Description,
-			Image:       s.buildImageURL(wish.Image),
-			PolTarget:   wish.PolTarget,
-			PolAmount:   wish.PolAmount,
-			IsPriority:  wish.IsPriority,
-		}
-		responses = append(responses, response)
+	if wish.StreamerUUID != req.UserUUID {
+		return usecase.ErrWishNotFound
 	}
-	return responses, nil
-}
-
-// validateAddWishRequest проверяет валидность запроса на добавление желания
-func (s *WishService) validateAddWishRequest(req entity.AddWishRequest) error {
-	if req.Name == "" {
-		return fmt.Errorf("название желания не может быть пустым")
+	if wish.Status == "complete" || wish.Status == "deleted" {
+		return usecase.ErrWishFieldLocked
 	}
 
-	if len(req.Name) > 100 {
-		return fmt.Errorf("название желания не может быть длиннее 100 символов")
+	status := wish.Status
+	onchainChanged := false
+	if req.Name != nil && *req.Name != wish.Name {
+		wish.Name = *req.Name
+		onchainChanged = true
 	}
-
-	if req.Description != nil && len(*req.Description) > 500 {
-		return fmt.Errorf("описание желания не может быть длиннее 500 символов")
+	if req.Description != nil && *req.Description != valueOf(wish.Description) {
+		wish.Description = optionalString(*req.Description)
+		onchainChanged = true
 	}
-
-	if req.PolTarget <= 0 {
-		return fmt.Errorf("целевая сумма должна быть больше нуля")
+	if req.WishURL != nil && *req.WishURL != valueOf(wish.WishURL) {
+		wish.WishURL = optionalString(*req.WishURL)
+		onchainChanged = true
 	}
-
-	if req.PolTarget > 1000000 {
-		return fmt.Errorf("целевая сумма не может превышать 1,000,000 POL")
+	if req.PolTarget != nil && req.PolTarget.Cmp(wish.PolTarget) != 0 {
+		// Донаты и набор цели считаются от цены в контракте, изменить её там нельзя
+		if status != "pending" {
+			return usecase.ErrWishFieldLocked
+		}
+		wish.PolTarget = *req.PolTarget
+		onchainChanged = true
+	}
+	if status == "pending" && onchainChanged {
+		if err := s.checkNoRelayInProgress(ctx, wish); err != nil {
+			return err
+		}
+	}
+	if req.Image != nil && *req.Image != wish.Image {
+		if err := s.checkWishImage(ctx, *req.Image, req.UserUUID); err != nil {
+			return err
+		}
+		wish.Image = *req.Image
+	}
+	if req.IsPriority != nil {
+		wish.IsPriority = *req.IsPriority
 	}
 
-	if req.Image == "" {
-		return fmt.Errorf("изображение обязательно")
+	if err := s.validateAddWishRequest(entity.AddWishRequest{
+		WishURL:     wish.WishURL,
+		Name:        wish.Name,
+		Description: wish.Description,
+		Image:       wish.Image,
+		PolTarget:   wish.PolTarget,
+	}); err != nil {
+		return usecase.ErrInvalidWish
 	}
 
+	if err := s.wishRepo.UpdateDetails(ctx, wish, status); err != nil {
+		switch {
+		case errors.Is(err, repo.ErrWishNotFound):
+			return usecase.ErrWishNotFound
+		case errors.Is(err, repo.ErrWishStatusChanged):
+			// Индексатор успел сменить статус, и правила редактирования могли измениться
+			return usecase.ErrWishFieldLocked
+		}
+		return err
+	}
 	return nil
 }
 
-// buildImageURL создает полный URL для изображения
-func (s *WishService) buildImageURL(imageID string) string {
-	return fmt.Sprintf("%s/static/%s", s.staticBaseURL, imageID)
-}
-
-// sortWishes сортирует желания: приоритетные сначала, затем по дате создания
-func (s *WishService) sortWishes(responses []entity.WishResponse, wishes []*entity.Wish) {
-	// Создаем карту для быстрого поиска даты создания
-	wishMap := make(map[string]time.Time)
+// ReorderWishes проверяет, что список содержит только желания стримера без повторов и все его открытые желания,
+// и сохраняет его как новый порядок. Версия порядка читается до желаний: если между проверкой и сохранением
+// стример добавил желание или сохранил другой порядок, версия изменится и список не сохранится
+func (s *WishService) ReorderWishes(ctx context.Context, req entity.ReorderWishesRequest) error {
+	if len(req.WishUUIDs) == 0 {
+		return usecase.ErrInvalidWishOrder
+	}
+	order, err := s.wishRepo.GetOrder(ctx, req.UserUUID)
+	if err != nil {
+		return err
+	}
+	wishes, err := s.wishRepo.GetByStreamerUUID(ctx, req.UserUUID)
+	if err != nil {
+		return err
+	}
+	owned := make(map[string]*entity.Wish, len(wishes))
 	for _, wish := range wishes {
-		if wish.Status == "active" {
-			wishMap[wish.UUID] = wish.CreatedAt
+		owned[wish.UUID] = wish
+	}
+	listed := make(map[string]bool, len(req.WishUUIDs))
+	for _, wishUUID := range req.WishUUIDs {
+		if owned[wishUUID] == nil || listed[wishUUID] {
+			return usecase.ErrInvalidWishOrder
 		}
+		listed[wishUUID] = true
 	}
-
-	// Простая сортировка пузырьком (для небольшого количества элементов)
-	for i := 0; i < len(responses)-1; i++ {
-		for j := 0; j < len(responses)-i-1; j++ {
-			// Сначала сравниваем по приоритету
-			if !responses[j].IsPriority && responses[j+1].IsPriority {
-				responses[j], responses[j+1] = responses[j+1], responses[j]
-				continue
-			}
-
-			// Если приоритет одинаковый, сравниваем по дате (новые сначала)
-			if responses[j].IsPriority == responses[j+1].IsPriority {
-				date1 := wishMap[responses[j].UUID]
-				date2 := wishMap[responses[j+1].UUID]
-				if date1.Before(date2) {
-					responses[j], responses[j+1] = responses[j+1], responses[j]
-				}
-			}
+	for _, wish := range wishes {
+		if !listed[wish.UUID] && wish.Status != "complete" && wish.Status != "deleted" {
+			return usecase.ErrInvalidWishOrder
 		}
 	}
-}
-
-// blockchainEventLoop основной цикл отслеживания событий блокчейна
-func (s *WishService) blockchainEventLoop(ctx context.Context) {
-	ticker := time.NewTicker(s.pollInterval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("Контекст отменен, останавливаем мониторинг блокчейна")
-			return
-		case <-s.stopChan:
-			log.Println("Получен сигнал остановки мониторинга")
-			return
-		case <-ticker.C:
-			if err := s.processNewBlocks(ctx); err != nil {
-				log.Printf("Ошибка обработки новых блоков: %v", err)
-			}
+	if err := s.wishRepo.Reorder(ctx, req.UserUUID, req.WishUUIDs, order.Version); err != nil {
+		if errors.Is(err, repo.ErrWishOrderChanged) {
+			return usecase.ErrWishOrderChanged
 		}
+		return err
 	}
+	return nil
 }
 
-// processNewBlocks обрабатывает новые блоки и извлекает события
-func (s *WishService) processNewBlocks(ctx context.Context) error {
-	header, err := s.client.HeaderByNumber(ctx, nil)
+// DeleteWish удаляет желание. Желание, которого ещё нет в контракте, сразу получает статус deleted.
+// Из контракта желание удаляет релеер вызовом completeOrRemoveWish, а статус меняет индексатор по событию WishDeleted
+func (s *WishService) DeleteWish(ctx context.Context, userUUID, wishUUID string) (*entity.DeleteWishResponse, error) {
+	wish, err := s.wishRepo.GetByUUID(ctx, wishUUID)
 	if err != nil {
-		return fmt.Errorf("ошибка получения текущего блока: %w", err)
+		return nil, usecase.ErrWishNotFound
 	}
-
-	currentBlock := header.Number.Uint64()
-	if currentBlock <= s.lastBlock {
-		return nil // Нет новых блоков
-	}
-
-	// Если lastBlock == 0 или 1, считаем, что только что задеплоили контракт и все старые блоки не нужны
-	if s.lastBlock == 0 || s.lastBlock == 1 {
-		if err := s.saveLastProcessedBlock(ctx, currentBlock); err != nil {
-			log.Printf("Ошибка сохранения последнего обработанного блока: %v", err)
-		}
-		s.lastBlock = currentBlock
-		log.Printf("Пропускаем обработку старых блоков, выставляем lastBlock = %d", currentBlock)
-		return nil
+	if wish.StreamerUUID != userUUID {
+		return nil, usecase.ErrWishNotFound
 	}
 
-	log.Printf("Обрабатываем блоки с %d по %d", s.lastBlock+1, currentBlock)
-
-	const maxBlockRange = 50000
-	fromBlock := s.lastBlock + 1
-	toBlock := currentBlock
-
-	for from := fromBlock; from <= toBlock; from += maxBlockRange {
-		end := from + maxBlockRange - 1
-		if end > toBlock {
-			end = toBlock
+	switch wish.Status {
+	case "pending":
+		// Добавляемое релеером желание появится в контракте, поэтому удалять его из базы нельзя
+		if err := s.checkNoRelayInProgress(ctx, wish); err != nil {
+			return nil, err
 		}
-		query := ethereum.FilterQuery{
-			FromBlock: big.NewInt(int64(from)),
-			ToBlock:   big.NewInt(int64(end)),
-			Addresses: []common.Address{s.contractAddr},
+		if err := s.wishRepo.UpdateStatus(ctx, wish.UUID, "pending", "deleted"); err != nil {
+			switch {
+			case errors.Is(err, repo.ErrWishNotFound):
+				return nil, usecase.ErrWishNotFound
+			case errors.Is(err, repo.ErrWishStatusChanged):
+				return nil, usecase.ErrWishStatusForAction
+			}
+			return nil, err
 		}
-		logs, err := s.client.FilterLogs(ctx, query)
+		return &entity.DeleteWishResponse{Status: "deleted"}, nil
+	case "active", "mismatch", "funded":
+		tx, err := s.relayerUC.RelayWish(ctx, entity.RelayWishRequest{
+			Action:   entity.RelayActionRemove,
+			WishUUID: wish.UUID,
+			UserUUID: userUUID,
+		})
 		if err != nil {
-			return fmt.Errorf("ошибка получения логов: %w", err)
-		}
-		for _, vLog := range logs {
-			if err := s.processBlockchainLog(ctx, vLog); err != nil {
-				log.Printf("Ошибка обработки лога: %v", err)
+			if errors.Is(err, usecase.ErrRelayerDisabled) {
+				return nil, usecase.ErrWishRemovalOnchain
 			}
+			return nil, err
 		}
-		// После каждого чанка сохраняем последний обработанный блок
-		if err := s.saveLastProcessedBlock(ctx, end); err != nil {
-			log.Printf("Ошибка сохранения последнего обработанного блока: %v", err)
+		return &entity.DeleteWishResponse{Status: wish.Status, Transaction: tx}, nil
+	default:
+		return nil, usecase.ErrWishStatusForAction
+	}
+}
+
+// checkNoRelayInProgress проверяет, что у желания в статусе pending нет незавершённой транзакции релеера.
+// Включённый в блок addWish тоже считается незавершённым, пока индексатор не дождался подтверждений
+// и не перевёл желание из pending
+func (s *WishService) checkNoRelayInProgress(ctx context.Context, wish *entity.Wish) error {
+	txs, err := s.relayerUC.GetWishTransactions(ctx, wish.StreamerUUID, wish.UUID)
+	if err != nil {
+		return err
+	}
+	for _, tx := range txs {
+		switch {
+		case tx.Status == entity.RelayStatusQueued, tx.Status == entity.RelayStatusPending:
+			return usecase.ErrRelayAlreadyQueued
+		case tx.Status == entity.RelayStatusMined && tx.Action == entity.RelayActionAdd:
+			return usecase.ErrRelayAlreadyQueued
 		}
-		s.lastBlock = end
 	}
 	return nil
 }
 
-// processBlockchainLog обрабатывает отдельный лог события
-func (s *WishService) processBlockchainLog(ctx context.Context, vLog types.Log) error {
-	if len(vLog.Topics) == 0 {
-		return nil
+// checkWishImage проверяет, что изображение загружено стримером для желания
+func (s *WishService) checkWishImage(ctx context.Context, imageID, userUUID string) error {
+	staticFile, err := s.staticRepo.GetByID(ctx, imageID)
+	if err != nil {
+		return usecase.ErrStaticFileNotFound
 	}
-
-	eventSignature := vLog.Topics[0].Hex()
-
-	switch eventSignature {
-	case s.getEventSignature("WishAdded"):
-		return s.handleWishAdded(ctx, vLog)
-	case s.getEventSignature("WishCompleted"):
-		return s.handleWishCompleted(ctx, vLog)
-	case s.getEventSignature("WishDeleted"):
-		return s.handleWishDeleted(ctx, vLog)
-	default:
-		// Неизвестное событие, игнорируем
-		return nil
+	if staticFile.Type != "wish" || staticFile.UploaderUUID != userUUID {
+		return usecase.ErrInvalidWish
 	}
+	return nil
 }
 
-// handleWishAdded обрабатывает событие добавления желания в контракт
-func (s *WishService) handleWishAdded(ctx context.Context, vLog types.Log) error {
-	var event WishAddedEvent
-	err := s.contractABI.UnpackIntoInterface(&event, "WishAdded", vLog.Data)
+func (s *WishService) GetWishes(ctx context.Context, streamerUUID string) ([]entity.WishResponse, error) {
+	wishes, err := s.wishRepo.GetByStreamerUUID(ctx, streamerUUID)
 	if err != nil {
-		return fmt.Errorf("ошибка декодирования WishAdded: %w", err)
+		return nil, err
 	}
-
-	// Извлекаем userUUID из индексированного топика
-	if len(vLog.Topics) > 1 {
-		event.UserUUID = strings.Trim(string(vLog.Topics[1][:]), "\x00")
+	responses := make([]entity.WishResponse, 0, len(wishes))
+	for _, wish := range wishes {
+		// Набравшее цель желание остаётся в списке до завершения в контракте, но донаты на него не принимаются
+		if wish.Status != "active" && wish.Status != "funded" {
+			continue
+		}
+		responses = append(responses, s.toWishResponse(wish))
 	}
+	return responses, nil
+}
 
-	log.Printf("Обнаружено новое желание в контракте: пользователь=%s, wishUUID=%s, цена=%s",
-		event.UserUUID, event.WishUUID, event.Price.String())
-
-	wish, err := s.wishRepo.GetByUUID(ctx, event.WishUUID)
+// GetOwnerWishes возвращает стримеру его желания во всех статусах с прогрессом сбора и транзакциями контракта
+func (s *WishService) GetOwnerWishes(ctx context.Context, userUUID string, statuses []string, page, pageSize int) (*entity.GetOwnerWishesResponse, error) {
+	for _, status := range statuses {
+		if !slices.Contains(wishStatuses, status) {
+			return nil, usecase.ErrInvalidWish
+		}
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	wishes, total, err := s.wishRepo.GetPageByStreamerUUID(ctx, userUUID, statuses, page, pageSize)
 	if err != nil {
-		log.Printf("Не найдено желание с UUID %s: %v", event.WishUUID, err)
-		return nil
+		return nil, err
 	}
 
-	// Проверяем, что желание в статусе pending
-	if wish.Status != "pending" {
-		log.Printf("Желание %s не в статусе pending (текущий статус: %s)", event.WishUUID, wish.Status)
-		return nil
+	responses := make([]entity.OwnerWishResponse, 0, len(wishes))
+	index := make(map[string]int, len(wishes))
+	uuids := make([]string, 0, len(wishes))
+	for _, wish := range wishes {
+		index[wish.UUID] = len(responses)
+		uuids = append(uuids, wish.UUID)
+		responses = append(responses, entity.OwnerWishResponse{
+			WishResponse: s.toWishResponse(wish),
+			Progress:     progressPercent(wish.PolAmount, wish.PolTarget),
+			Transactions: []entity.WishTransactionResponse{},
+			CreatedAt:    wish.CreatedAt,
+			UpdatedAt:    wish.UpdatedAt,
+		})
+	}
+
+	if len(uuids) > 0 {
+		events, err := s.blockchainRepo.GetWishEvents(ctx, uuids)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			i, ok := index[event.WishUUID]
+			if !ok {
+				continue
+			}
+			response := &responses[i]
+			processedAt := event.ProcessedAt
+			switch event.EventType {
+			case "WishAdded":
+				response.ActivatedAt = &processedAt
+			case "WishCompleted":
+				response.CompletedAt = &processedAt
+			case "WishDeleted":
+				response.DeletedAt = &processedAt
+			}
+			response.Transactions = append(response.Transactions, entity.WishTransactionResponse{
+				Event:       event.EventType,
+				TxHash:      event.TxHash,
+				BlockNumber: event.BlockNumber,
+				ProcessedAt: event.ProcessedAt,
+			})
+		}
 	}
 
-	// Переводим в статус active
-	wish.Status = "active"
-	wish.UpdatedAt = time.Now()
+	return &entity.GetOwnerWishesResponse{
+		Page:     page,
+		PageSize: pageSize,
+		Total:    total,
+		Wishes:   responses,
+	}, nil
+}
 
-	err = s.wishRepo.Update(ctx, wish)
-	if err != nil {
-		return fmt.Errorf("ошибка обновления статуса желания: %w", err)
+func (s *WishService) toWishResponse(wish *entity.Wish) entity.WishResponse {
+	return entity.WishResponse{
+		UUID:        wish.UUID,
+		OnchainID:   wish.OnchainID,
+		WishURL:     wish.WishURL,
+		Name:        wish.Name,
+		Description: wish.Description,
+		Image:       s.buildImageURL(wish.Image),
+		PolTarget:   wish.PolTarget,
+		PolAmount:   wish.PolAmount,
+		IsPriority:  wish.IsPriority,
+		Position:    wish.Position,
+		Status:      wish.Status,
 	}
+}
 
-	log.Printf("Желание %s переведено в статус 'active'", wish.UUID)
-	return nil
+// progressPercent возвращает набранную долю целевой суммы в процентах с точностью до сотых.
+// Донаты сверх цели не обрезаются, поэтому значение может быть больше 100. При крошечной цели доля
+// не помещается в int64, поэтому переводится в float64 через big.Float
+func progressPercent(amount, target entity.Amount) float64 {
+	if target.Sign() <= 0 {
+		return 0
+	}
+	basisPoints := new(big.Int).Mul(amount.Wei(), big.NewInt(10000))
+	basisPoints.Quo(basisPoints, target.Wei())
+	percent := new(big.Float).Quo(new(big.Float).SetInt(basisPoints), big.NewFloat(100))
+	value, _ := percent.Float64()
+	return value
 }
 
-// handleWishCompleted обрабатывает событие завершения желания
-func (s *WishService) handleWishCompleted(ctx context.Context, vLog types.Log) error {
-	var event WishCompletedEvent
-	err := s.contractABI.UnpackIntoInterface(&event, "WishCompleted", vLog.Data)
+func (s *WishService) GetPriceMismatches(ctx context.Context, userUUID string) ([]entity.PriceMismatchResponse, error) {
+	wishes, err := s.wishRepo.GetByStreamerUUID(ctx, userUUID)
 	if err != nil {
-		return fmt.Errorf("ошибка декодирования WishCompleted: %w", err)
+		return nil, err
 	}
-
-	if len(vLog.Topics) > 1 {
-		event.UserUUID = strings.Trim(string(vLog.Topics[1][:]), "\x00")
+	responses := make([]entity.PriceMismatchResponse, 0)
+	for _, wish := range wishes {
+		if wish.Status != "mismatch" || wish.OnchainPrice == nil {
+			continue
+		}
+		responses = append(responses, entity.PriceMismatchResponse{
+			UUID:         wish.UUID,
+			OnchainID:    wish.OnchainID,
+			Name:         wish.Name,
+			PolTarget:    wish.PolTarget,
+			OnchainPrice: *wish.OnchainPrice,
+		})
 	}
+	return responses, nil
+}
 
-	log.Printf("Желание завершено в контракте: пользователь=%s, wishUUID=%s",
-		event.UserUUID, event.WishUUID)
-
-	wish, err := s.wishRepo.GetByUUID(ctx, event.WishUUID)
+// AcceptOnchainPrice принимает цену из контракта. Желание в контракте уже есть, и донаты считаются от его цены,
+// поэтому целевая сумма приводится к ней. Чтобы оставить прежнюю сумму, желание удаляют и добавляют заново
+func (s *WishService) AcceptOnchainPrice(ctx context.Context, userUUID, wishUUID string) error {
+	wish, err := s.wishRepo.GetByUUID(ctx, wishUUID)
 	if err != nil {
-		log.Printf("Не найдено желание с UUID %s: %v", event.WishUUID, err)
-		return nil
+		return usecase.ErrWishNotFound
 	}
-
-	// Проверяем, что желание в статусе active
-	if wish.Status != "active" {
-		log.Printf("Желание %s не в статусе active (текущий статус: %s)", event.WishUUID, wish.Status)
-		return nil
+	if wish.StreamerUUID != userUUID {
+		return usecase.ErrWishNotFound
 	}
-
-	wish.Status = "complete"
-	wish.UpdatedAt = time.Now()
-
-	err = s.wishRepo.Update(ctx, wish)
-	if err != nil {
-		return fmt.Errorf("ошибка обновления статуса желания на complete: %w", err)
+	if wish.Status != "mismatch" {
+		return usecase.ErrWishNotMismatched
+	}
+	if err := s.wishRepo.AcceptOnchainPrice(ctx, wish.UUID); err != nil {
+		switch {
+		case errors.Is(err, repo.ErrWishNotFound):
+			return usecase.ErrWishNotFound
+		case errors.Is(err, repo.ErrWishStatusChanged):
+			return usecase.ErrWishNotMismatched
+		}
+		return err
 	}
-
-	log.Printf("Желание %s переведено в статус 'complete'", wish.UUID)
 	return nil
 }
 
-// handleWishDeleted обрабатывает событие удаления желания
-func (s *WishService) handleWishDeleted(ctx context.Context, vLog types.Log) error {
-	var event WishDeletedEvent
-	err := s.contractABI.UnpackIntoInterface(&event, "WishDeleted", vLog.Data)
-	if err != nil {
-		return fmt.Errorf("ошибка декодирования WishDeleted: %w", err)
+// validateAddWishRequest проверяет валидность запроса на добавление желания
+func (s *WishService) validateAddWishRequest(req entity.AddWishRequest) error {
+	if req.Name == "" {
+		return fmt.Errorf("название желания не может быть пустым")
 	}
 
-	if len(vLog.Topics) > 1 {
-		event.UserUUID = strings.Trim(string(vLog.Topics[1][:]), "\x00")
+	if len(req.Name) > 100 {
+		return fmt.Errorf("название желания не может быть длиннее 100 символов")
 	}
 
-	log.Printf("Желание удалено в контракте: пользователь=%s, wishUUID=%s",
-		event.UserUUID, event.WishUUID)
-
-	// Эффективно находим желание напрямую по UUID
-	wish, err := s.wishRepo.GetByUUID(ctx, event.WishUUID)
-	if err != nil {
-		log.Printf("Не найдено желание с UUID %s: %v", event.WishUUID, err)
-		return nil
+	if req.Description != nil && len(*req.Description) > 500 {
+		return fmt.Errorf("описание желания не может быть длиннее 500 символов")
 	}
 
-	// Проверяем, что желание в статусе active
-	if wish.Status != "active" {
-		log.Printf("Желание %s не в статусе active (текущий статус: %s)", event.WishUUID, wish.Status)
-		return nil
+	if req.PolTarget.Sign() <= 0 {
+		return fmt.Errorf("целевая сумма должна быть больше нуля")
 	}
 
-	wish.Status = "deleted"
-	wish.UpdatedAt = time.Now()
+	if req.PolTarget.Cmp(entity.POL(1000000)) > 0 {
+		return fmt.Errorf("целевая сумма не может превышать 1,000,000 POL")
+	}
 
-	err = s.wishRepo.Update(ctx, wish)
-	if err != nil {
-		return fmt.Errorf("ошибка обновления статуса желания на deleted: %w", err)
+	if req.Image == "" {
+		return fmt.Errorf("изображение обязательно")
 	}
 
-	log.Printf("Желание %s переведено в статус 'deleted'", wish.UUID)
 	return nil
 }
 
-// Вспомогательные методы для работы с блокчейном
-
-// getEventSignature возвращает хеш сигнатуры события
-func (s *WishService) getEventSignature(eventName string) string {
-	event, exists := s.contractABI.Events[eventName]
-	if !exists {
-		return ""
+// optionalString возвращает nil для пустой строки, чтобы необязательное поле удалялось из документа
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
 	}
-	return event.ID.Hex()
+	return &value
 }
 
-// comparePrices сравнивает цену в float64 с big.Int из контракта
-func (s *WishService) comparePrices(floatPrice float64, bigIntPrice *big.Int) bool {
-	// Конвертируем float64 в wei (умножаем на 10^18)
-	weiFloat := floatPrice * 1e18
-	weiBigInt := big.NewInt(int64(weiFloat))
-
-	return weiBigInt.Cmp(bigIntPrice) == 0
-}
-
-// weiToFloat конвертирует wei в float64
-func (s *WishService) weiToFloat(wei *big.Int) float64 {
-	fbalance := new(big.Float)
-	fbalance.SetString(wei.String())
-	polValue := new(big.Float).Quo(fbalance, big.NewFloat(math.Pow(10, 18)))
-	result, _ := polValue.Float64()
-	return result
-}
-
-// getLastProcessedBlock получает последний обработанный блок из БД
-func (s *WishService) getLastProcessedBlock(ctx context.Context) (uint64, error) {
-	return s.blockchainRepo.GetLastProcessedBlock(ctx)
+// valueOf возвращает значение необязательного поля или пустую строку
+func valueOf(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
 }
 
-// saveLastProcessedBlock сохраняет последний обработанный блок в БД
-func (s *WishService) saveLastProcessedBlock(ctx context.Context, blockNumber uint64) error {
-	return s.blockchainRepo.SaveLastProcessedBlock(ctx, blockNumber)
+// buildImageURL создает полный URL для изображения
+func (s *WishService) buildImageURL(imageID string) string {
+	return fmt.Sprintf("%s/static/%s", s.staticBaseURL, imageID)
 }
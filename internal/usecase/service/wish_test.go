@@ -0,0 +1,383 @@
+package service
+
+import (
+	"backend/internal/entity"
+	"backend/internal/repo"
+	"backend/internal/usecase"
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// fakeWishRepo хранит желания в памяти и реализует только методы, которые нужны редактированию и удалению
+type fakeWishRepo struct {
+	repo.WishRepository
+	wishes map[string]*entity.Wish
+	order  entity.WishOrder
+	// onList вызывается после чтения списка желаний, чтобы изменить данные между проверкой и сохранением
+	onList func()
+}
+
+func newFakeWishRepo(wishes ...*entity.Wish) *fakeWishRepo {
+	r := &fakeWishRepo{wishes: make(map[string]*entity.Wish)}
+	for _, wish := range wishes {
+		r.wishes[wish.UUID] = wish
+		r.order.WishUUIDs = append(r.order.WishUUIDs, wish.UUID)
+	}
+	return r
+}
+
+func (r *fakeWishRepo) GetOrder(context.Context, string) (*entity.WishOrder, error) {
+	order := r.order
+	return &order, nil
+}
+
+func (r *fakeWishRepo) Reorder(_ context.Context, _ string, uuids []string, version int64) error {
+	if r.order.Version != version {
+		return repo.ErrWishOrderChanged
+	}
+	r.order.WishUUIDs = uuids
+	r.order.Version++
+	return nil
+}
+
+func (r *fakeWishRepo) GetByStreamerUUID(_ context.Context, streamerUUID string) ([]*entity.Wish, error) {
+	var wishes []*entity.Wish
+	for _, uuid := range r.order.WishUUIDs {
+		if wish, ok := r.wishes[uuid]; ok && wish.StreamerUUID == streamerUUID {
+			copied := *wish
+			wishes = append(wishes, &copied)
+		}
+	}
+	if r.onList != nil {
+		r.onList()
+	}
+	return wishes, nil
+}
+
+func (r *fakeWishRepo) GetByUUID(_ context.Context, uuid string) (*entity.Wish, error) {
+	wish, ok := r.wishes[uuid]
+	if !ok {
+		return nil, repo.ErrWishNotFound
+	}
+	copied := *wish
+	return &copied, nil
+}
+
+func (r *fakeWishRepo) UpdateDetails(_ context.Context, wish *entity.Wish, status string) error {
+	stored, ok := r.wishes[wish.UUID]
+	if !ok {
+		return repo.ErrWishNotFound
+	}
+	if stored.Status != status {
+		return repo.ErrWishStatusChanged
+	}
+	copied := *wish
+	r.wishes[wish.UUID] = &copied
+	return nil
+}
+
+func (r *fakeWishRepo) UpdateStatus(_ context.Context, uuid, from, to string) error {
+	stored, ok := r.wishes[uuid]
+	if !ok {
+		return repo.ErrWishNotFound
+	}
+	if stored.Status != from {
+		return repo.ErrWishStatusChanged
+	}
+	stored.Status = to
+	return nil
+}
+
+type fakeStaticRepo struct {
+	repo.StaticFileRepository
+	files map[string]*entity.StaticFile
+}
+
+func (r *fakeStaticRepo) GetByID(_ context.Context, id string) (*entity.StaticFile, error) {
+	file, ok := r.files[id]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return file, nil
+}
+
+// fakeRelayerUC возвращает заданные транзакции желания и запоминает вызовы релеера
+type fakeRelayerUC struct {
+	txs      []entity.RelayedTransactionResponse
+	relayErr error
+	relayed  []entity.RelayWishRequest
+}
+
+func (r *fakeRelayerUC) RelayWish(_ context.Context, req entity.RelayWishRequest) (*entity.RelayedTransactionResponse, error) {
+	if r.relayErr != nil {
+		return nil, r.relayErr
+	}
+	r.relayed = append(r.relayed, req)
+	return &entity.RelayedTransactionResponse{ID: "tx-remove", Action: req.Action, Status: entity.RelayStatusQueued}, nil
+}
+
+func (r *fakeRelayerUC) GetWishTransactions(context.Context, string, string) ([]entity.RelayedTransactionResponse, error) {
+	return r.txs, nil
+}
+
+func newTestWishService(relayerUC *fakeRelayerUC, wishes ...*entity.Wish) (*WishService, *fakeWishRepo) {
+	wishRepo := newFakeWishRepo(wishes...)
+	staticRepo := &fakeStaticRepo{files: map[string]*entity.StaticFile{
+		"image-1": {ID: "image-1", Type: "wish", UploaderUUID: "streamer"},
+		"image-2": {ID: "image-2", Type: "wish", UploaderUUID: "streamer"},
+		"avatar":  {ID: "avatar", Type: "avatar", UploaderUUID: "streamer"},
+	}}
+	return NewWishService(wishRepo, staticRepo, nil, nil, relayerUC, ""), wishRepo
+}
+
+func testWish(status string) *entity.Wish {
+	return &entity.Wish{UUID: "wish-1", StreamerUUID: "streamer", Name: "Микрофон", Image: "image-1", PolTarget: entity.POL(10), Status: status}
+}
+
+func TestEditWishStatusRules(t *testing.T) {
+	ctx := context.Background()
+	name := "Новый микрофон"
+	target := entity.POL(20)
+	image := "image-2"
+	avatar := "avatar"
+
+	cases := []struct {
+		title   string
+		status  string
+		txs     []entity.RelayedTransactionResponse
+		req     entity.EditWishRequest
+		wantErr error
+	}{
+		{title: "pending меняет цель", status: "pending", req: entity.EditWishRequest{PolTarget: &target}},
+		{title: "active меняет название", status: "active", req: entity.EditWishRequest{Name: &name}},
+		{title: "funded меняет изображение", status: "funded", req: entity.EditWishRequest{Image: &image}},
+		{title: "active не меняет цель", status: "active", req: entity.EditWishRequest{PolTarget: &target}, wantErr: usecase.ErrWishFieldLocked},
+		{title: "mismatch не меняет цель", status: "mismatch", req: entity.EditWishRequest{PolTarget: &target}, wantErr: usecase.ErrWishFieldLocked},
+		{title: "complete не редактируется", status: "complete", req: entity.EditWishRequest{Name: &name}, wantErr: usecase.ErrWishFieldLocked},
+		{title: "deleted не редактируется", status: "deleted", req: entity.EditWishRequest{Name: &name}, wantErr: usecase.ErrWishFieldLocked},
+		{title: "изображение другого типа", status: "active", req: entity.EditWishRequest{Image: &avatar}, wantErr: usecase.ErrInvalidWish},
+		{
+			title:   "релеер добавляет желание",
+			status:  "pending",
+			txs:     []entity.RelayedTransactionResponse{{Action: entity.RelayActionAdd, Status: entity.RelayStatusPending}},
+			req:     entity.EditWishRequest{Name: &name},
+			wantErr: usecase.ErrRelayAlreadyQueued,
+		},
+		{
+			title:   "addWish включён в блок, но ещё не проиндексирован",
+			status:  "pending",
+			txs:     []entity.RelayedTransactionResponse{{Action: entity.RelayActionAdd, Status: entity.RelayStatusMined}},
+			req:     entity.EditWishRequest{PolTarget: &target},
+			wantErr: usecase.ErrRelayAlreadyQueued,
+		},
+		{
+			title:  "изображения нет в контракте",
+			status: "pending",
+			txs:    []entity.RelayedTransactionResponse{{Action: entity.RelayActionAdd, Status: entity.RelayStatusMined}},
+			req:    entity.EditWishRequest{Image: &image},
+		},
+		{
+			title:  "addWish релеера отклонён",
+			status: "pending",
+			txs:    []entity.RelayedTransactionResponse{{Action: entity.RelayActionAdd, Status: entity.RelayStatusFailed}},
+			req:    entity.EditWishRequest{Name: &name},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			s, wishRepo := newTestWishService(&fakeRelayerUC{txs: tc.txs}, testWish(tc.status))
+			tc.req.WishUUID = "wish-1"
+			tc.req.UserUUID = "streamer"
+			err := s.EditWish(ctx, tc.req)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("получена ошибка %v, ожидалась %v", err, tc.wantErr)
+			}
+			wish := wishRepo.wishes["wish-1"]
+			if tc.wantErr != nil {
+				if wish.Name != "Микрофон" || wish.PolTarget.Cmp(entity.POL(10)) != 0 || wish.Image != "image-1" {
+					t.Fatalf("желание изменено несмотря на ошибку: %+v", wish)
+				}
+				return
+			}
+			if tc.req.Name != nil && wish.Name != *tc.req.Name {
+				t.Fatalf("название %q не сохранено", wish.Name)
+			}
+			if tc.req.PolTarget != nil && wish.PolTarget.Cmp(*tc.req.PolTarget) != 0 {
+				t.Fatalf("цель %s не сохранена", wish.PolTarget)
+			}
+		})
+	}
+}
+
+func TestEditWishRejectsForeignAndChangedWish(t *testing.T) {
+	ctx := context.Background()
+	name := "Новый микрофон"
+	s, wishRepo := newTestWishService(&fakeRelayerUC{}, testWish("active"))
+
+	err := s.EditWish(ctx, entity.EditWishRequest{WishUUID: "wish-1", UserUUID: "other", Name: &name})
+	if !errors.Is(err, usecase.ErrWishNotFound) {
+		t.Fatalf("чужое желание: %v", err)
+	}
+
+	// Индексатор завершил желание между чтением и сохранением
+	s.wishRepo = &statusChangingRepo{fakeWishRepo: wishRepo, status: "complete"}
+	err = s.EditWish(ctx, entity.EditWishRequest{WishUUID: "wish-1", UserUUID: "streamer", Name: &name})
+	if !errors.Is(err, usecase.ErrWishFieldLocked) {
+		t.Fatalf("после смены статуса: %v", err)
+	}
+}
+
+// statusChangingRepo меняет статус желания сразу после чтения, как индексатор, обработавший событие
+type statusChangingRepo struct {
+	*fakeWishRepo
+	status string
+}
+
+func (r *statusChangingRepo) GetByUUID(ctx context.Context, uuid string) (*entity.Wish, error) {
+	wish, err := r.fakeWishRepo.GetByUUID(ctx, uuid)
+	if err == nil {
+		r.wishes[uuid].Status = r.status
+	}
+	return wish, err
+}
+
+func TestDeleteWishStatusRules(t *testing.T) {
+	ctx := context.Background()
+
+	cases := []struct {
+		title      string
+		status     string
+		txs        []entity.RelayedTransactionResponse
+		relayErr   error
+		wantErr    error
+		wantStatus string
+		wantRelay  bool
+	}{
+		{title: "pending удаляется сразу", status: "pending", wantStatus: "deleted"},
+		{
+			title:      "addWish релеера отклонён",
+			status:     "pending",
+			txs:        []entity.RelayedTransactionResponse{{Action: entity.RelayActionAdd, Status: entity.RelayStatusFailed}},
+			wantStatus: "deleted",
+		},
+		{
+			title:      "релеер добавляет желание",
+			status:     "pending",
+			txs:        []entity.RelayedTransactionResponse{{Action: entity.RelayActionAdd, Status: entity.RelayStatusQueued}},
+			wantErr:    usecase.ErrRelayAlreadyQueued,
+			wantStatus: "pending",
+		},
+		{
+			title:      "addWish включён в блок, но ещё не проиндексирован",
+			status:     "pending",
+			txs:        []entity.RelayedTransactionResponse{{Action: entity.RelayActionAdd, Status: entity.RelayStatusMined}},
+			wantErr:    usecase.ErrRelayAlreadyQueued,
+			wantStatus: "pending",
+		},
+		{title: "active удаляет релеер", status: "active", wantStatus: "active", wantRelay: true},
+		{title: "mismatch удаляет релеер", status: "mismatch", wantStatus: "mismatch", wantRelay: true},
+		{title: "funded удаляет релеер", status: "funded", wantStatus: "funded", wantRelay: true},
+		{title: "релеер выключен", status: "active", relayErr: usecase.ErrRelayerDisabled, wantErr: usecase.ErrWishRemovalOnchain, wantStatus: "active"},
+		{title: "complete не удаляется", status: "complete", wantErr: usecase.ErrWishStatusForAction, wantStatus: "complete"},
+		{title: "deleted не удаляется повторно", status: "deleted", wantErr: usecase.ErrWishStatusForAction, wantStatus: "deleted"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			relayerUC := &fakeRelayerUC{txs: tc.txs, relayErr: tc.relayErr}
+			s, wishRepo := newTestWishService(relayerUC, testWish(tc.status))
+			resp, err := s.DeleteWish(ctx, "streamer", "wish-1")
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("получена ошибка %v, ожидалась %v", err, tc.wantErr)
+			}
+			if status := wishRepo.wishes["wish-1"].Status; status != tc.wantStatus {
+				t.Fatalf("статус %s, ожидался %s", status, tc.wantStatus)
+			}
+			if tc.wantRelay {
+				if len(relayerUC.relayed) != 1 || relayerUC.relayed[0].Action != entity.RelayActionRemove {
+					t.Fatalf("удаление не поставлено в очередь релеера: %+v", relayerUC.relayed)
+				}
+				if resp.Transaction == nil || resp.Status != tc.status {
+					t.Fatalf("ответ без транзакции релеера: %+v", resp)
+				}
+			} else if len(relayerUC.relayed) != 0 {
+				t.Fatalf("релеер вызван для статуса %s", tc.status)
+			}
+		})
+	}
+
+	s, _ := newTestWishService(&fakeRelayerUC{}, testWish("pending"))
+	if _, err := s.DeleteWish(ctx, "other", "wish-1"); !errors.Is(err, usecase.ErrWishNotFound) {
+		t.Fatalf("чужое желание: %v", err)
+	}
+}
+
+func TestProgressPercent(t *testing.T) {
+	cases := []struct {
+		amount, target entity.Amount
+		want           float64
+	}{
+		{amount: entity.POL(0), target: entity.POL(10), want: 0},
+		{amount: entity.NewAmount(big.NewInt(3)), target: entity.NewAmount(big.NewInt(9)), want: 33.33},
+		{amount: entity.POL(15), target: entity.POL(10), want: 150},
+		{amount: entity.POL(1), target: entity.Amount{}, want: 0},
+		// Цель в 1 wei и донат в 1000 POL: доля в базисных пунктах не помещается в int64
+		{amount: entity.POL(1000), target: entity.NewAmount(big.NewInt(1)), want: 1e23},
+	}
+	for _, tc := range cases {
+		if got := progressPercent(tc.amount, tc.target); got != tc.want {
+			t.Fatalf("%s из %s: получено %v%%, ожидалось %v%%", tc.amount, tc.target, got, tc.want)
+		}
+	}
+}
+
+func TestReorderWishes(t *testing.T) {
+	ctx := context.Background()
+	wish := func(uuid, status string) *entity.Wish {
+		return &entity.Wish{UUID: uuid, StreamerUUID: "streamer", Status: status}
+	}
+	newService := func() (*WishService, *fakeWishRepo) {
+		return newTestWishService(&fakeRelayerUC{},
+			wish("a", "active"), wish("b", "pending"), wish("c", "complete"),
+			&entity.Wish{UUID: "foreign", StreamerUUID: "other", Status: "active"})
+	}
+	reorder := func(s *WishService, uuids ...string) error {
+		return s.ReorderWishes(ctx, entity.ReorderWishesRequest{UserUUID: "streamer", WishUUIDs: uuids})
+	}
+
+	s, wishRepo := newService()
+	if err := reorder(s, "b", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if got := wishRepo.order.WishUUIDs; len(got) != 2 || got[0] != "b" || got[1] != "a" || wishRepo.order.Version != 1 {
+		t.Fatalf("сохранён порядок %v версии %d", got, wishRepo.order.Version)
+	}
+
+	for _, invalid := range [][]string{nil, {"a"}, {"a", "b", "b"}, {"a", "b", "foreign"}, {"a", "b", "missing"}} {
+		s, _ := newService()
+		if err := reorder(s, invalid...); !errors.Is(err, usecase.ErrInvalidWishOrder) {
+			t.Fatalf("порядок %v: %v", invalid, err)
+		}
+	}
+
+	// Завершённое желание можно передать в списке
+	s, _ = newService()
+	if err := reorder(s, "c", "a", "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Стример добавил желание в другой вкладке после проверки списка
+	s, wishRepo = newService()
+	wishRepo.onList = func() {
+		wishRepo.wishes["d"] = wish("d", "pending")
+		wishRepo.order.WishUUIDs = append(wishRepo.order.WishUUIDs, "d")
+		wishRepo.order.Version++
+	}
+	if err := reorder(s, "b", "a"); !errors.Is(err, usecase.ErrWishOrderChanged) {
+		t.Fatalf("порядок сохранён поверх нового желания: %v", err)
+	}
+	if got := wishRepo.order.WishUUIDs; got[len(got)-1] != "d" {
+		t.Fatalf("новое желание потеряно из порядка: %v", got)
+	}
+}
@@ -0,0 +1,22 @@
+package usecase
+
+import (
+	"backend/internal/entity"
+	"context"
+	"errors"
+)
+
+var (
+	ErrInvalidTransactionRequest = errors.New("invalid transaction request")
+	ErrWalletNotSet              = errors.New("polygon wallet not set")
+	ErrTransactionWouldRevert    = errors.New("transaction would revert")
+	ErrContractNotConfigured     = errors.New("contract address not configured")
+)
+
+type TransactionUsecase interface {
+	BuildRegisterUser(ctx context.Context, userUUID string) (*entity.UnsignedTransaction, error)
+	BuildAddWish(ctx context.Context, userUUID, wishUUID string) (*entity.UnsignedTransaction, error)
+	BuildRemoveWish(ctx context.Context, userUUID, wishUUID string) (*entity.UnsignedTransaction, error)
+	BuildDonate(ctx context.Context, req entity.BuildDonateTransactionRequest) (*entity.UnsignedTransaction, error)
+	BuildWithdraw(ctx context.Context, req entity.BuildWithdrawTransactionRequest) (*entity.UnsignedTransaction, error)
+}
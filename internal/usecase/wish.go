@@ -9,10 +9,36 @@ import (
 var (
 	ErrWishNotFound = errors.New("wish not found")
 	ErrInvalidWish  = errors.New("invalid wish")
+	// ErrWishNotMismatched у желания нет расхождения цены с контрактом
+	ErrWishNotMismatched = errors.New("wish price is not mismatched")
+	// ErrUserNotRegistered регистрация стримера в контракте ещё не подтверждена индексатором
+	ErrUserNotRegistered = errors.New("streamer is not registered on-chain")
+	// ErrWishOnRetiredContract желание добавлено в деплой контракта, выведенный из работы
+	ErrWishOnRetiredContract = errors.New("wish belongs to a retired contract")
+	// ErrWishFieldLocked поле нельзя изменить в текущем статусе желания
+	ErrWishFieldLocked = errors.New("wish field cannot be changed in current status")
+	// ErrInvalidWishOrder список порядка желаний содержит чужие или повторяющиеся желания либо пропускает открытые
+	ErrInvalidWishOrder = errors.New("invalid wish order")
+	// ErrWishOrderChanged порядок желаний изменился, пока проверялся новый: стример добавил желание в другой вкладке
+	ErrWishOrderChanged = errors.New("wish order changed")
+	// ErrWishRemovalOnchain желание есть в контракте, а релеер выключен: стример удаляет его своей транзакцией
+	ErrWishRemovalOnchain = errors.New("wish must be removed on-chain")
 )
 
 type WishUsecase interface {
 	AddWish(ctx context.Context, req entity.AddWishRequest) (string, error)
 	UpdateWish(ctx context.Context, req entity.UpdateWishRequest) error
+	// EditWish меняет описание желания с учётом полей, записанных в контракт
+	EditWish(ctx context.Context, req entity.EditWishRequest) error
+	// ReorderWishes сохраняет порядок желаний стримера, заданный перетаскиванием
+	ReorderWishes(ctx context.Context, req entity.ReorderWishesRequest) error
+	// DeleteWish удаляет желание; желание из контракта удаляется через релеер
+	DeleteWish(ctx context.Context, userUUID, wishUUID string) (*entity.DeleteWishResponse, error)
 	GetWishes(ctx context.Context, streamerUUID string) ([]entity.WishResponse, error)
+	// GetOwnerWishes возвращает страницу желаний стримера в любых статусах из statuses (пустой список — все статусы)
+	GetOwnerWishes(ctx context.Context, userUUID string, statuses []string, page, pageSize int) (*entity.GetOwnerWishesResponse, error)
+	// GetPriceMismatches возвращает желания стримера, цена которых в контракте не совпала с целевой суммой
+	GetPriceMismatches(ctx context.Context, userUUID string) ([]entity.PriceMismatchResponse, error)
+	// AcceptOnchainPrice принимает цену из контракта как целевую сумму желания и активирует его
+	AcceptOnchainPrice(ctx context.Context, userUUID, wishUUID string) error
 }
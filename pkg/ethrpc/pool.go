@@ -0,0 +1,386 @@
+package ethrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"golang.org/x/time/rate"
+)
+
+// Client методы RPC клиента, которые используются в приложении. *ethclient.Client и *Pool реализуют этот интерфейс
+type Client interface {
+	bind.ContractBackend
+	bind.DeployBackend
+	ethereum.ChainIDReader
+	ethereum.BlockNumberReader
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	Close()
+}
+
+var (
+	_ Client = (*ethclient.Client)(nil)
+	_ Client = (*Pool)(nil)
+)
+
+// ErrNoEndpoints возвращается, если не удалось подключиться ни к одному RPC
+var ErrNoEndpoints = errors.New("нет доступных RPC эндпоинтов")
+
+// rpcLimitExceeded код JSON-RPC ошибки превышения лимита запросов
+const rpcLimitExceeded = -32005
+
+// Options параметры пула RPC эндпоинтов
+type Options struct {
+	// MaxLag на сколько блоков эндпоинт может отставать от лучшего, прежде чем считаться нездоровым
+	MaxLag uint64
+	// HealthInterval интервал проверки эндпоинтов
+	HealthInterval time.Duration
+	// RequestsPerSecond ограничение запросов в секунду на один эндпоинт, 0 — без ограничения
+	RequestsPerSecond float64
+	// Retries количество повторов запроса на других эндпоинтах
+	Retries int
+	// Backoff начальная пауза между повторами, удваивается с каждой попыткой
+	Backoff time.Duration
+}
+
+type endpoint struct {
+	url     string
+	client  *ethclient.Client
+	limiter *rate.Limiter
+
+	healthy bool
+	head    uint64
+}
+
+// Pool распределяет запросы по нескольким RPC эндпоинтам: запросы идут в текущий эндпоинт,
+// при ошибке или отставании от головы цепочки пул переключается на следующий здоровый
+type Pool struct {
+	endpoints []*endpoint
+	options   Options
+
+	mu      sync.RWMutex
+	current int
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewPool подключается к эндпоинтам в порядке приоритета и запускает фоновую проверку их состояния
+func NewPool(ctx context.Context, urls []string, options Options) (*Pool, error) {
+	if options.MaxLag == 0 {
+		options.MaxLag = 5
+	}
+	if options.HealthInterval <= 0 {
+		options.HealthInterval = 10 * time.Second
+	}
+	if options.Retries <= 0 {
+		options.Retries = 3
+	}
+	if options.Backoff <= 0 {
+		options.Backoff = 500 * time.Millisecond
+	}
+	limit := rate.Inf
+	if options.RequestsPerSecond > 0 {
+		limit = rate.Limit(options.RequestsPerSecond)
+	}
+
+	p := &Pool{
+		options: options,
+		stop:    make(chan struct{}),
+	}
+	for _, url := range urls {
+		client, err := ethclient.DialContext(ctx, url)
+		if err != nil {
+			log.Printf("⚠️ Ошибка подключения к RPC %s: %v", url, err)
+			continue
+		}
+		p.endpoints = append(p.endpoints, &endpoint{
+			url:     url,
+			client:  client,
+			limiter: rate.NewLimiter(limit, 1),
+			healthy: true,
+		})
+	}
+	if len(p.endpoints) == 0 {
+		return nil, ErrNoEndpoints
+	}
+
+	p.checkHealth(ctx)
+	go p.healthLoop()
+	return p, nil
+}
+
+// Close останавливает проверку эндпоинтов и закрывает соединения
+func (p *Pool) Close() {
+	p.once.Do(func() {
+		close(p.stop)
+		for _, ep := range p.endpoints {
+			ep.client.Close()
+		}
+	})
+}
+
+// URL возвращает адрес текущего эндпоинта
+func (p *Pool) URL() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.endpoints[p.current].url
+}
+
+func (p *Pool) healthLoop() {
+	ticker := time.NewTicker(p.options.HealthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), p.options.HealthInterval)
+			p.checkHealth(ctx)
+			cancel()
+		}
+	}
+}
+
+// checkHealth запрашивает номер последнего блока у всех эндпоинтов.
+// Нездоровыми считаются эндпоинты с ошибкой и отстающие от лучшего более чем на MaxLag блоков
+func (p *Pool) checkHealth(ctx context.Context) {
+	heads := make([]uint64, len(p.endpoints))
+	errs := make([]error, len(p.endpoints))
+
+	var wg sync.WaitGroup
+	for i, ep := range p.endpoints {
+		wg.Add(1)
+		go func(i int, ep *endpoint) {
+			defer wg.Done()
+			heads[i], errs[i] = ep.client.BlockNumber(ctx)
+		}(i, ep)
+	}
+	wg.Wait()
+
+	var best uint64
+	for i := range p.endpoints {
+		if errs[i] == nil && heads[i] > best {
+			best = heads[i]
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, ep := range p.endpoints {
+		healthy := errs[i] == nil && heads[i]+p.options.MaxLag >= best
+		if healthy != ep.healthy {
+			if healthy {
+				log.Printf("✅ RPC %s снова доступен (блок %d)", ep.url, heads[i])
+			} else if errs[i] != nil {
+				log.Printf("⚠️ RPC %s недоступен: %v", ep.url, errs[i])
+			} else {
+				log.Printf("⚠️ RPC %s отстаёт от головы цепочки: %d против %d", ep.url, heads[i], best)
+			}
+		}
+		ep.healthy = healthy
+		if errs[i] == nil {
+			ep.head = heads[i]
+		}
+	}
+
+	// Возвращаемся на более приоритетный эндпоинт, как только он снова здоров
+	for i, ep := range p.endpoints {
+		if ep.healthy {
+			if i != p.current {
+				log.Printf("Переключаемся на RPC %s", ep.url)
+				p.current = i
+			}
+			return
+		}
+	}
+}
+
+// pick выбирает эндпоинт для очередной попытки: текущий, затем остальные здоровые по приоритету,
+// затем нездоровые — лучше попробовать их, чем сразу вернуть ошибку
+func (p *Pool) pick(tried map[int]bool) (int, *endpoint) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if !tried[p.current] && p.endpoints[p.current].healthy {
+		return p.current, p.endpoints[p.current]
+	}
+	for i, ep := range p.endpoints {
+		if !tried[i] && ep.healthy {
+			return i, ep
+		}
+	}
+	for i, ep := range p.endpoints {
+		if !tried[i] {
+			return i, ep
+		}
+	}
+	return p.current, p.endpoints[p.current]
+}
+
+// markFailed помечает эндпоинт нездоровым и переключает пул на следующий здоровый
+func (p *Pool) markFailed(index int, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ep := p.endpoints[index]
+	if ep.healthy {
+		log.Printf("⚠️ Ошибка RPC %s: %v", ep.url, err)
+	}
+	ep.healthy = false
+	if p.current != index {
+		return
+	}
+	for i, next := range p.endpoints {
+		if next.healthy {
+			log.Printf("Переключаемся на RPC %s", next.url)
+			p.current = i
+			return
+		}
+	}
+}
+
+// do выполняет запрос с ограничением частоты и повторами на других эндпоинтах
+func do[T any](ctx context.Context, p *Pool, fn func(client *ethclient.Client) (T, error)) (T, error) {
+	var (
+		result  T
+		err     error
+		backoff = p.options.Backoff
+		tried   = make(map[int]bool, len(p.endpoints))
+	)
+	for attempt := 0; attempt <= p.options.Retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return result, ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+		if len(tried) == len(p.endpoints) {
+			tried = make(map[int]bool, len(p.endpoints))
+		}
+
+		index, ep := p.pick(tried)
+		tried[index] = true
+		if err := ep.limiter.Wait(ctx); err != nil {
+			return result, err
+		}
+
+		result, err = fn(ep.client)
+		if err == nil || !retryable(ctx, err) {
+			return result, err
+		}
+		p.markFailed(index, err)
+	}
+	return result, fmt.Errorf("RPC запрос не выполнен после %d попыток: %w", p.options.Retries+1, err)
+}
+
+// retryable сообщает, имеет ли смысл повторять запрос на другом эндпоинте.
+// Ответы узла (revert, nonce too low, not found) не повторяются, ошибки транспорта и лимитов — повторяются
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, ethereum.NotFound) {
+		return false
+	}
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == 429 || httpErr.StatusCode >= 500
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr.ErrorCode() == rpcLimitExceeded
+	}
+	return true
+}
+
+func (p *Pool) ChainID(ctx context.Context) (*big.Int, error) {
+	return do(ctx, p, func(c *ethclient.Client) (*big.Int, error) { return c.ChainID(ctx) })
+}
+
+func (p *Pool) BlockNumber(ctx context.Context) (uint64, error) {
+	return do(ctx, p, func(c *ethclient.Client) (uint64, error) { return c.BlockNumber(ctx) })
+}
+
+func (p *Pool) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return do(ctx, p, func(c *ethclient.Client) (*types.Header, error) { return c.HeaderByNumber(ctx, number) })
+}
+
+func (p *Pool) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	return do(ctx, p, func(c *ethclient.Client) ([]byte, error) { return c.CodeAt(ctx, account, blockNumber) })
+}
+
+func (p *Pool) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	return do(ctx, p, func(c *ethclient.Client) ([]byte, error) { return c.PendingCodeAt(ctx, account) })
+}
+
+func (p *Pool) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	return do(ctx, p, func(c *ethclient.Client) (*big.Int, error) { return c.BalanceAt(ctx, account, blockNumber) })
+}
+
+func (p *Pool) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	return do(ctx, p, func(c *ethclient.Client) (uint64, error) { return c.NonceAt(ctx, account, blockNumber) })
+}
+
+func (p *Pool) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return do(ctx, p, func(c *ethclient.Client) (uint64, error) { return c.PendingNonceAt(ctx, account) })
+}
+
+func (p *Pool) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return do(ctx, p, func(c *ethclient.Client) ([]byte, error) { return c.CallContract(ctx, call, blockNumber) })
+}
+
+func (p *Pool) EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+	return do(ctx, p, func(c *ethclient.Client) (uint64, error) { return c.EstimateGas(ctx, call) })
+}
+
+func (p *Pool) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return do(ctx, p, func(c *ethclient.Client) (*big.Int, error) { return c.SuggestGasPrice(ctx) })
+}
+
+func (p *Pool) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return do(ctx, p, func(c *ethclient.Client) (*big.Int, error) { return c.SuggestGasTipCap(ctx) })
+}
+
+// SendTransaction отправляет подписанную транзакцию. Повторная отправка на другой эндпоинт безопасна:
+// хеш транзакции не меняется, а узел отклонит дубликат
+func (p *Pool) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	_, err := do(ctx, p, func(c *ethclient.Client) (struct{}, error) { return struct{}{}, c.SendTransaction(ctx, tx) })
+	return err
+}
+
+func (p *Pool) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	return do(ctx, p, func(c *ethclient.Client) (*types.Receipt, error) { return c.TransactionReceipt(ctx, txHash) })
+}
+
+func (p *Pool) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	return do(ctx, p, func(c *ethclient.Client) ([]types.Log, error) { return c.FilterLogs(ctx, query) })
+}
+
+// SubscribeFilterLogs подписывается на логи через первый здоровый эндпоинт, поддерживающий подписки (ws:// или wss://)
+func (p *Pool) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	var lastErr error = ErrNoEndpoints
+	tried := make(map[int]bool, len(p.endpoints))
+	for range p.endpoints {
+		index, ep := p.pick(tried)
+		tried[index] = true
+		sub, err := ep.client.SubscribeFilterLogs(ctx, query, ch)
+		if err == nil {
+			return sub, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}